@@ -2,145 +2,687 @@ package ssp
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
 )
 
 // AnalyticsStore handles SSP analytics storage in ClickHouse
 type AnalyticsStore struct {
+	mu   sync.RWMutex // Guards conn and cfg, which Reconnect replaces
 	conn clickhouse.Conn
+	cfg  ClickHouseConfig
+}
+
+// ClickHouseConfig holds ClickHouse connection settings.
+// TLS is enabled when any of the TLS paths is set.
+type ClickHouseConfig struct {
+	Addr        string
+	Username    string
+	Password    string
+	Database    string
+	TLSCertPath string // Client certificate for mutual TLS
+	TLSKeyPath  string // Client private key for mutual TLS
+	TLSCAPath   string // CA bundle used to verify the server
+
+	RetentionDays       int // Days analytics rows are kept; 0 uses DefaultAnalyticsRetentionDays
+	QueryTimeoutSeconds int // Limit on each read query; 0 uses DefaultAnalyticsQueryTimeout
+
+	// Per-table retention days overriding RetentionDays, e.g. set through the admin API
+	RetentionOverrides map[string]int
+
+	MaxOpenConns int           // Connection pool size; 0 uses DefaultClickHouseMaxOpenConns
+	MaxIdleConns int           // Pooled connections kept open when idle; 0 uses DefaultClickHouseMaxIdleConns
+	DialTimeout  time.Duration // Limit on opening a connection; 0 uses the driver default
+
+	// Object storage HMAC keys for ExportToS3; empty uses the ClickHouse server's credentials
+	ExportAccessKeyID     string
+	ExportSecretAccessKey string
+}
+
+// ClickHouse connection pool defaults, sized for a single-node server
+const (
+	DefaultClickHouseMaxOpenConns = 5
+	DefaultClickHouseMaxIdleConns = 3
+)
+
+// DefaultAnalyticsQueryTimeout bounds analytics read queries when
+// ClickHouseConfig.QueryTimeoutSeconds is not set
+const DefaultAnalyticsQueryTimeout = 10 * time.Second
+
+// queryContext bounds a read query by the configured query timeout so slow
+// stats queries cannot hold HTTP handlers indefinitely. A timed-out query
+// fails with context.DeadlineExceeded.
+func (as *AnalyticsStore) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := DefaultAnalyticsQueryTimeout
+	if seconds := as.config().QueryTimeoutSeconds; seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// tlsConfig builds the TLS configuration, or returns nil when TLS is not configured
+func (cfg ClickHouseConfig) tlsConfig() (*tls.Config, error) {
+	if cfg.TLSCertPath == "" && cfg.TLSKeyPath == "" && cfg.TLSCAPath == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.TLSCAPath != "" {
+		caCert, err := os.ReadFile(cfg.TLSCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("no valid certificates in CA file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertPath != "" || cfg.TLSKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
 }
 
 // NewAnalyticsStore creates a new analytics store
-func NewAnalyticsStore(addr string) (*AnalyticsStore, error) {
-	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{addr},
+func NewAnalyticsStore(cfg ClickHouseConfig) (*AnalyticsStore, error) {
+	if cfg.Database == "" {
+		cfg.Database = "default"
+	}
+
+	conn, err := openClickHouse(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &AnalyticsStore{conn: conn, cfg: cfg}
+
+	// Create tables
+	if err := store.createTables(); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	return store, nil
+}
+
+// openClickHouse opens a ClickHouse connection
+func openClickHouse(cfg ClickHouseConfig) (clickhouse.Conn, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse TLS configuration: %w", err)
+	}
+
+	conn, err := clickhouse.Open(cfg.options(tlsConfig))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+
+	return conn, nil
+}
+
+// options builds the driver options, applying the connection pool defaults
+func (cfg ClickHouseConfig) options(tlsConfig *tls.Config) *clickhouse.Options {
+	maxOpen, maxIdle := cfg.MaxOpenConns, cfg.MaxIdleConns
+	if maxOpen <= 0 {
+		maxOpen = DefaultClickHouseMaxOpenConns
+	}
+	if maxIdle <= 0 {
+		maxIdle = DefaultClickHouseMaxIdleConns
+	}
+	// Idle connections beyond the pool size would never be used
+	maxIdle = min(maxIdle, maxOpen)
+
+	return &clickhouse.Options{
+		Addr: []string{cfg.Addr},
 		Auth: clickhouse.Auth{
-			Database: "default",
+			Database: cfg.Database,
+			Username: cfg.Username,
+			Password: cfg.Password,
 		},
+		TLS: tlsConfig,
 		Compression: &clickhouse.Compression{
 			Method: clickhouse.CompressionLZ4,
 		},
-	})
+		MaxOpenConns: maxOpen,
+		MaxIdleConns: maxIdle,
+		DialTimeout:  cfg.DialTimeout,
+	}
+}
+
+// connection returns the current ClickHouse connection
+func (as *AnalyticsStore) connection() clickhouse.Conn {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.conn
+}
+
+// config returns the current connection settings
+func (as *AnalyticsStore) config() ClickHouseConfig {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.cfg
+}
+
+// Ping checks that ClickHouse is reachable
+func (as *AnalyticsStore) Ping(ctx context.Context) error {
+	return as.connection().Ping(ctx)
+}
+
+// Reconnect closes the current connection and opens a new one to addr.
+// An empty addr reuses the configured address. The existing connection is
+// kept if the new one cannot be established.
+func (as *AnalyticsStore) Reconnect(addr string) error {
+	cfg := as.config()
+	if addr != "" {
+		cfg.Addr = addr
+	}
 
+	conn, err := openClickHouse(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+		return err
 	}
 
-	store := &AnalyticsStore{conn: conn}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to ping ClickHouse: %w", err)
+	}
 
-	// Create tables
-	if err := store.createTables(); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	as.mu.Lock()
+	old := as.conn
+	as.conn = conn
+	as.cfg = cfg
+	as.mu.Unlock()
+
+	return old.Close()
+}
+
+// DefaultAnalyticsRetentionDays is how long analytics rows are kept when
+// ClickHouseConfig.RetentionDays is not set
+const DefaultAnalyticsRetentionDays = 90
+
+// analyticsTable is a ClickHouse table definition with the column migrations
+// applied after it is created and the materialized views fed from it
+type analyticsTable struct {
+	name       string
+	schema     string
+	migrations []string
+	views      []analyticsView
+}
+
+// analyticsView is a materialized view holding a copy of its table's rows
+// under a different sort key
+type analyticsView struct {
+	name   string
+	schema string
+}
+
+// bidsViewSchema returns a materialized view of ssp_bids sorted by orderBy.
+//
+// ssp_bids is sorted by (timestamp, publisher_id, partner_id), so queries
+// filtering on placement_id, or on partner_id without a tight time range, scan
+// every granule in the range. Each view trades write amplification (every bid
+// insert is written once more per view) and the storage of a full copy of the
+// rows for reads that only touch the granules of the filtered key.
+//
+// The view only takes bids from its backfill cutoff on, bound to the ?
+// placeholder when it is created; older bids are copied in by BackfillViews,
+// so a bid is never both inserted through the view and backfilled.
+func bidsViewSchema(name, orderBy string, retentionDays int) string {
+	return fmt.Sprintf(`
+			CREATE MATERIALIZED VIEW IF NOT EXISTS %s
+			ENGINE = MergeTree()
+			ORDER BY (%s)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY
+			AS SELECT * FROM ssp_bids
+			WHERE timestamp >= ?;
+			`, name, orderBy, retentionDays)
+}
+
+// viewBackfillsSchema records the views whose rows from before their cutoff
+// have not been copied in yet, so a backfill interrupted by a restart is
+// retried. The latest row per view wins.
+const viewBackfillsSchema = `
+			CREATE TABLE IF NOT EXISTS ssp_view_backfills (
+				view String,
+				source String,
+				cutoff DateTime,
+				completed UInt8,
+				updated_at DateTime
+			) ENGINE = ReplacingMergeTree(updated_at)
+			ORDER BY view;
+			`
+
+// analyticsTables returns the analytics table definitions with rows expiring
+// after retentionDays
+func analyticsTables(retentionDays int) []analyticsTable {
+	if retentionDays <= 0 {
+		retentionDays = DefaultAnalyticsRetentionDays
 	}
 
-	return store, nil
+	return []analyticsTable{
+		// SSP Ad Requests table
+		{
+			name: "ssp_ad_requests",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_ad_requests (
+				request_id String,
+				placement_id String,
+				site_id String,
+				publisher_id String,
+				timestamp DateTime,
+				url String,
+				referer String,
+				user_agent String,
+				ip String,
+				country String,
+				device_type String,
+				width UInt16,
+				height UInt16,
+				ad_type String,
+				bid_floor Float64,
+				no_fill_reason String
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, publisher_id, site_id)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+			// Columns added after the initial schema
+			migrations: []string{
+				`ALTER TABLE ssp_ad_requests ADD COLUMN IF NOT EXISTS no_fill_reason String`,
+			},
+		},
+		// SSP Bids table
+		{
+			name: "ssp_bids",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_bids (
+				bid_id String,
+				request_id String,
+				imp_id String,
+				placement_id String,
+				site_id String,
+				publisher_id String,
+				partner_id String,
+				partner_name String,
+				price Float64,
+				currency String,
+				adomain Array(String),
+				timestamp DateTime,
+				won UInt8,
+				cleared_price Float64
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, publisher_id, partner_id)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+			views: []analyticsView{
+				// Placement stats
+				{name: "ssp_bids_by_placement", schema: bidsViewSchema("ssp_bids_by_placement", "placement_id, timestamp", retentionDays)},
+				// Partner stats
+				{name: "ssp_bids_by_partner_publisher", schema: bidsViewSchema("ssp_bids_by_partner_publisher", "partner_id, publisher_id, timestamp", retentionDays)},
+			},
+		},
+		// SSP Impressions table
+		{
+			name: "ssp_impressions",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_impressions (
+				impression_id String,
+				bid_id String,
+				request_id String,
+				placement_id String,
+				site_id String,
+				publisher_id String,
+				partner_id String,
+				price Float64,
+				publisher_revenue Float64,
+				timestamp DateTime,
+				country String,
+				device_type String,
+				user_id String
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, publisher_id, site_id)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+			// Columns added after the initial schema
+			migrations: []string{
+				`ALTER TABLE ssp_impressions ADD COLUMN IF NOT EXISTS user_id String`,
+				// Clicks are matched back to impressions by impression_id
+				`ALTER TABLE ssp_impressions ADD INDEX IF NOT EXISTS idx_impression_id impression_id TYPE bloom_filter GRANULARITY 4`,
+				`ALTER TABLE ssp_impressions ADD INDEX IF NOT EXISTS idx_placement_id placement_id TYPE bloom_filter GRANULARITY 4`,
+			},
+		},
+		// SSP Clicks table
+		{
+			name: "ssp_clicks",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_clicks (
+				click_id String,
+				impression_id String,
+				bid_id String,
+				placement_id String,
+				site_id String,
+				publisher_id String,
+				timestamp DateTime
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, publisher_id, site_id)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+			migrations: []string{
+				`ALTER TABLE ssp_clicks ADD INDEX IF NOT EXISTS idx_impression_id impression_id TYPE bloom_filter GRANULARITY 4`,
+			},
+		},
+		// SSP Partner No-Fills table
+		{
+			name: "ssp_partner_no_fills",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_partner_no_fills (
+				request_id String,
+				partner_id String,
+				partner_name String,
+				reason String,
+				timestamp DateTime
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, partner_id, reason)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+		},
+		// Creative validation warnings table
+		{
+			name: "ssp_creative_warnings",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_creative_warnings (
+				request_id String,
+				bid_id String,
+				placement_id String,
+				partner_id String,
+				code String,
+				severity String,
+				message String,
+				timestamp DateTime
+			) ENGINE = MergeTree()
+			ORDER BY (timestamp, partner_id, code)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+		},
+		// VAST player errors table
+		{
+			name: "ssp_vast_errors",
+			schema: fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS ssp_vast_errors (
+				bid_id String,
+				placement_id String,
+				error_code UInt16,
+				timestamp DateTime
+			) ENGINE = MergeTree()
+			ORDER BY (placement_id, timestamp)
+			PARTITION BY toYYYYMM(timestamp)
+			TTL timestamp + INTERVAL %d DAY;
+			`, retentionDays),
+		},
+	}
+}
+
+// ErrUnknownAnalyticsTable is returned when setting retention on a table the
+// SSP does not manage
+var ErrUnknownAnalyticsTable = errors.New("unknown analytics table")
+
+// ErrInvalidRetentionDays is returned for a retention period of less than a day
+var ErrInvalidRetentionDays = errors.New("retention_days must be positive")
+
+// AnalyticsRetention is how long rows in an analytics table are kept
+type AnalyticsRetention struct {
+	Table         string    `json:"table"`
+	RetentionDays int       `json:"retention_days"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
+
+// ValidateAnalyticsRetention checks that table is an analytics table and
+// days is a usable TTL
+func ValidateAnalyticsRetention(table string, days int) error {
+	_, err := findAnalyticsTable(table, days)
+	return err
+}
+
+// findAnalyticsTable returns the definition of an analytics table
+func findAnalyticsTable(table string, days int) (analyticsTable, error) {
+	if days <= 0 {
+		return analyticsTable{}, ErrInvalidRetentionDays
+	}
+	for _, t := range analyticsTables(days) {
+		if t.name == table {
+			return t, nil
+		}
+	}
+	return analyticsTable{}, fmt.Errorf("%w: %s", ErrUnknownAnalyticsTable, table)
+}
+
+// SetRetention changes the TTL of an analytics table, and of the materialized
+// views fed from it, so rows expire after days. Existing rows are re-evaluated
+// by ClickHouse in the background. Tables already at days are left alone.
+func (as *AnalyticsStore) SetRetention(ctx context.Context, table string, days int) error {
+	t, err := findAnalyticsTable(table, days)
+	if err != nil {
+		return err
+	}
+	return as.syncRetention(ctx, t, days)
+}
+
+// ttlDaysPattern matches the row TTL of an analytics table as ClickHouse
+// normalizes it in system.tables
+var ttlDaysPattern = regexp.MustCompile(`TTL timestamp \+ toIntervalDay\((\d+)\)`)
+
+// parseTTLDays returns the row TTL in days from a table's engine_full, or 0
+// when it has none
+func parseTTLDays(engineFull string) int {
+	match := ttlDaysPattern.FindStringSubmatch(engineFull)
+	if match == nil {
+		return 0
+	}
+	days, _ := strconv.Atoi(match[1])
+	return days
+}
+
+// ttlDays returns the current row TTL in days of a table, or of the inner
+// table holding a materialized view's rows
+func (as *AnalyticsStore) ttlDays(ctx context.Context, name string) (int, error) {
+	query := `
+		SELECT engine_full
+		FROM system.tables
+		WHERE database = currentDatabase()
+		  AND engine LIKE '%MergeTree'
+		  AND name IN (?, concat('.inner.', ?), (
+			SELECT concat('.inner_id.', toString(uuid))
+			FROM system.tables
+			WHERE database = currentDatabase() AND name = ?
+		  ))
+	`
+
+	var engineFull string
+	if err := as.connection().QueryRow(ctx, query, name, name, name).Scan(&engineFull); err != nil {
+		return 0, fmt.Errorf("failed to read %s TTL: %w", name, err)
+	}
+	return parseTTLDays(engineFull), nil
+}
+
+// syncRetention sets the TTL of a table and its views to days, altering only
+// those whose TTL differs. CREATE TABLE IF NOT EXISTS leaves the TTL of an
+// existing table alone, and each MODIFY TTL rewrites every part, so tables
+// already at the configured retention are not touched.
+func (as *AnalyticsStore) syncRetention(ctx context.Context, t analyticsTable, days int) error {
+	// Names are our own, so they are safe to format into the statement
+	names := []string{t.name}
+	for _, view := range t.views {
+		names = append(names, view.name)
+	}
+	for _, name := range names {
+		current, err := as.ttlDays(ctx, name)
+		if err != nil {
+			return err
+		}
+		if current == days {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s MODIFY TTL timestamp + INTERVAL %d DAY", name, days)
+		if err := as.connection().Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to set %s retention: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// createView creates a materialized view. A new view takes rows from a
+// cutoff of now on, and a pending backfill of the older rows is recorded
+// before the view exists, so no restart can leave it unrecorded. Views are not
+// created with POPULATE, which blocks until every row is copied and misses
+// rows inserted meanwhile.
+func (as *AnalyticsStore) createView(ctx context.Context, table analyticsTable, view analyticsView) error {
+	var exists uint64
+	query := `SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = ?`
+	if err := as.connection().QueryRow(ctx, query, view.name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check %s view: %w", view.name, err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	cutoff := time.Now().UTC().Truncate(time.Second)
+	query = `INSERT INTO ssp_view_backfills (view, source, cutoff, completed, updated_at) VALUES (?, ?, ?, 0, now())`
+	if err := as.connection().Exec(ctx, query, view.name, table.name, cutoff); err != nil {
+		return fmt.Errorf("failed to record %s backfill: %w", view.name, err)
+	}
+
+	if err := as.connection().Exec(ctx, view.schema, cutoff); err != nil {
+		return fmt.Errorf("failed to create %s view: %w", view.name, err)
+	}
+	return nil
+}
+
+// BackfillViews copies the rows from before their cutoff into views whose
+// backfill has not completed, including ones interrupted by a restart. It can
+// take as long as the tables are large, so it is meant to run in the
+// background.
+func (as *AnalyticsStore) BackfillViews(ctx context.Context) error {
+	query := `SELECT view, source, cutoff FROM ssp_view_backfills FINAL WHERE completed = 0`
+	rows, err := as.connection().Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list pending view backfills: %w", err)
+	}
+
+	type backfill struct {
+		view   string
+		source string
+		cutoff time.Time
+	}
+	var pending []backfill
+	for rows.Next() {
+		var b backfill
+		if err := rows.Scan(&b.view, &b.source, &b.cutoff); err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Mutations run synchronously so the insert cannot race the delete
+	syncCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 1,
+	}))
+	for _, b := range pending {
+		// Names are our own, so they are safe to format into the statements.
+		// Rows below the cutoff only come from the backfill, so dropping them
+		// first makes a retry of a partial backfill copy nothing twice.
+		query := fmt.Sprintf("ALTER TABLE %s DELETE WHERE timestamp < ?", b.view)
+		if err := as.connection().Exec(syncCtx, query, b.cutoff); err != nil {
+			return fmt.Errorf("failed to clear partial %s backfill: %w", b.view, err)
+		}
+
+		query = fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE timestamp < ?", b.view, b.source)
+		if err := as.connection().Exec(ctx, query, b.cutoff); err != nil {
+			return fmt.Errorf("failed to backfill %s view: %w", b.view, err)
+		}
+
+		query = `INSERT INTO ssp_view_backfills (view, source, cutoff, completed, updated_at) VALUES (?, ?, ?, 1, now())`
+		if err := as.connection().Exec(ctx, query, b.view, b.source, b.cutoff); err != nil {
+			return fmt.Errorf("failed to record %s backfill: %w", b.view, err)
+		}
+	}
+	return nil
 }
 
-// createTables creates analytics tables in ClickHouse
+// createTables creates analytics tables in ClickHouse and brings existing
+// tables to the configured retention, so startup only alters tables whose
+// retention actually changed
 func (as *AnalyticsStore) createTables() error {
 	ctx := context.Background()
 
-	// SSP Ad Requests table
-	adRequestsSchema := `
-	CREATE TABLE IF NOT EXISTS ssp_ad_requests (
-		request_id String,
-		placement_id String,
-		site_id String,
-		publisher_id String,
-		timestamp DateTime,
-		url String,
-		referer String,
-		user_agent String,
-		ip String,
-		country String,
-		device_type String,
-		width UInt16,
-		height UInt16,
-		ad_type String,
-		bid_floor Float64
-	) ENGINE = MergeTree()
-	ORDER BY (timestamp, publisher_id, site_id)
-	PARTITION BY toYYYYMM(timestamp)
-	TTL timestamp + INTERVAL 90 DAY;
-	`
+	cfg := as.config()
+	retentionDays := cfg.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = DefaultAnalyticsRetentionDays
+	}
 
-	if err := as.conn.Exec(ctx, adRequestsSchema); err != nil {
-		return fmt.Errorf("failed to create ssp_ad_requests table: %w", err)
-	}
-
-	// SSP Bids table
-	bidsSchema := `
-	CREATE TABLE IF NOT EXISTS ssp_bids (
-		bid_id String,
-		request_id String,
-		imp_id String,
-		placement_id String,
-		site_id String,
-		publisher_id String,
-		partner_id String,
-		partner_name String,
-		price Float64,
-		currency String,
-		adomain Array(String),
-		timestamp DateTime,
-		won UInt8,
-		cleared_price Float64
-	) ENGINE = MergeTree()
-	ORDER BY (timestamp, publisher_id, partner_id)
-	PARTITION BY toYYYYMM(timestamp)
-	TTL timestamp + INTERVAL 90 DAY;
-	`
+	if err := as.connection().Exec(ctx, viewBackfillsSchema); err != nil {
+		return fmt.Errorf("failed to create ssp_view_backfills table: %w", err)
+	}
 
-	if err := as.conn.Exec(ctx, bidsSchema); err != nil {
-		return fmt.Errorf("failed to create ssp_bids table: %w", err)
-	}
-
-	// SSP Impressions table
-	impressionsSchema := `
-	CREATE TABLE IF NOT EXISTS ssp_impressions (
-		impression_id String,
-		bid_id String,
-		request_id String,
-		placement_id String,
-		site_id String,
-		publisher_id String,
-		partner_id String,
-		price Float64,
-		publisher_revenue Float64,
-		timestamp DateTime,
-		country String,
-		device_type String
-	) ENGINE = MergeTree()
-	ORDER BY (timestamp, publisher_id, site_id)
-	PARTITION BY toYYYYMM(timestamp)
-	TTL timestamp + INTERVAL 90 DAY;
-	`
+	for _, table := range analyticsTables(retentionDays) {
+		days := retentionDays
+		if override, ok := cfg.RetentionOverrides[table.name]; ok && override > 0 {
+			days = override
+			table, _ = findAnalyticsTable(table.name, days)
+		}
 
-	if err := as.conn.Exec(ctx, impressionsSchema); err != nil {
-		return fmt.Errorf("failed to create ssp_impressions table: %w", err)
-	}
-
-	// SSP Clicks table
-	clicksSchema := `
-	CREATE TABLE IF NOT EXISTS ssp_clicks (
-		click_id String,
-		impression_id String,
-		bid_id String,
-		placement_id String,
-		site_id String,
-		publisher_id String,
-		timestamp DateTime
-	) ENGINE = MergeTree()
-	ORDER BY (timestamp, publisher_id, site_id)
-	PARTITION BY toYYYYMM(timestamp)
-	TTL timestamp + INTERVAL 90 DAY;
-	`
+		if err := as.connection().Exec(ctx, table.schema); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+
+		for _, migration := range table.migrations {
+			if err := as.connection().Exec(ctx, migration); err != nil {
+				return fmt.Errorf("failed to migrate %s table: %w", table.name, err)
+			}
+		}
+
+		// Views are created after their table's migrations so they copy its current columns
+		for _, view := range table.views {
+			if err := as.createView(ctx, table, view); err != nil {
+				return err
+			}
+		}
 
-	if err := as.conn.Exec(ctx, clicksSchema); err != nil {
-		return fmt.Errorf("failed to create ssp_clicks table: %w", err)
+		if err := as.syncRetention(ctx, table, days); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -148,21 +690,22 @@ func (as *AnalyticsStore) createTables() error {
 
 // AdRequestLog represents an ad request log entry
 type AdRequestLog struct {
-	RequestID   string
-	PlacementID string
-	SiteID      string
-	PublisherID string
-	Timestamp   time.Time
-	URL         string
-	Referer     string
-	UserAgent   string
-	IP          string
-	Country     string
-	DeviceType  string
-	Width       int
-	Height      int
-	AdType      string
-	BidFloor    float64
+	RequestID    string
+	PlacementID  string
+	SiteID       string
+	PublisherID  string
+	Timestamp    time.Time
+	URL          string
+	Referer      string
+	UserAgent    string
+	IP           string
+	Country      string
+	DeviceType   string
+	Width        int
+	Height       int
+	AdType       string
+	BidFloor     float64
+	NoFillReason string // Empty when the request was filled
 }
 
 // LogAdRequest logs an ad request
@@ -171,11 +714,11 @@ func (as *AnalyticsStore) LogAdRequest(ctx context.Context, log *AdRequestLog) e
 		INSERT INTO ssp_ad_requests (
 			request_id, placement_id, site_id, publisher_id, timestamp,
 			url, referer, user_agent, ip, country, device_type,
-			width, height, ad_type, bid_floor
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			width, height, ad_type, bid_floor, no_fill_reason
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	return as.conn.Exec(ctx, query,
+	return as.connection().Exec(ctx, query,
 		log.RequestID,
 		log.PlacementID,
 		log.SiteID,
@@ -191,6 +734,7 @@ func (as *AnalyticsStore) LogAdRequest(ctx context.Context, log *AdRequestLog) e
 		log.Height,
 		log.AdType,
 		log.BidFloor,
+		log.NoFillReason,
 	)
 }
 
@@ -227,7 +771,7 @@ func (as *AnalyticsStore) LogBid(ctx context.Context, log *BidLog) error {
 		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	return as.conn.Exec(ctx, query,
+	return as.connection().Exec(ctx, query,
 		log.BidID,
 		log.RequestID,
 		log.ImpID,
@@ -245,6 +789,16 @@ func (as *AnalyticsStore) LogBid(ctx context.Context, log *BidLog) error {
 	)
 }
 
+// impressionIDNamespace derives impression IDs from bid IDs; see ImpressionIDForBid
+var impressionIDNamespace = uuid.MustParse("6f1c0a52-3d7e-4b9a-8e21-5c4d9f0b7a13")
+
+// ImpressionIDForBid returns the impression ID logged for a served bid's
+// impression. It is derived from the bid's ServedID (see BidCacheEntry) so
+// clicks, which only carry that ID, can be matched back to their impression.
+func ImpressionIDForBid(servedID string) string {
+	return uuid.NewSHA1(impressionIDNamespace, []byte(servedID)).String()
+}
+
 // ImpressionLog represents an impression log entry
 type ImpressionLog struct {
 	ImpressionID     string
@@ -259,6 +813,7 @@ type ImpressionLog struct {
 	Timestamp        time.Time
 	Country          string
 	DeviceType       string
+	UserID           string
 }
 
 // LogImpression logs an impression
@@ -266,11 +821,11 @@ func (as *AnalyticsStore) LogImpression(ctx context.Context, log *ImpressionLog)
 	query := `
 		INSERT INTO ssp_impressions (
 			impression_id, bid_id, request_id, placement_id, site_id, publisher_id,
-			partner_id, price, publisher_revenue, timestamp, country, device_type
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			partner_id, price, publisher_revenue, timestamp, country, device_type, user_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	return as.conn.Exec(ctx, query,
+	return as.connection().Exec(ctx, query,
 		log.ImpressionID,
 		log.BidID,
 		log.RequestID,
@@ -283,9 +838,29 @@ func (as *AnalyticsStore) LogImpression(ctx context.Context, log *ImpressionLog)
 		log.Timestamp,
 		log.Country,
 		log.DeviceType,
+		log.UserID,
 	)
 }
 
+// CountUserImpressions counts a user's impressions on a site since a point in time
+func (as *AnalyticsStore) CountUserImpressions(ctx context.Context, userID, siteID string, since time.Time) (int64, error) {
+	query := `
+		SELECT toInt64(count())
+		FROM ssp_impressions
+		WHERE user_id = ? AND site_id = ? AND timestamp >= ?
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	var count int64
+	if err := as.connection().QueryRow(ctx, query, userID, siteID, since).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 // ClickLog represents a click log entry
 type ClickLog struct {
 	ClickID      string
@@ -305,7 +880,7 @@ func (as *AnalyticsStore) LogClick(ctx context.Context, log *ClickLog) error {
 		) VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	return as.conn.Exec(ctx, query,
+	return as.connection().Exec(ctx, query,
 		log.ClickID,
 		log.ImpressionID,
 		log.BidID,
@@ -316,6 +891,88 @@ func (as *AnalyticsStore) LogClick(ctx context.Context, log *ClickLog) error {
 	)
 }
 
+// PartnerNoFillLog represents a partner returning no usable bid for a request
+type PartnerNoFillLog struct {
+	RequestID   string
+	PartnerID   string
+	PartnerName string
+	Reason      string
+	Timestamp   time.Time
+}
+
+// LogPartnerNoFill logs a partner no-fill
+func (as *AnalyticsStore) LogPartnerNoFill(ctx context.Context, log *PartnerNoFillLog) error {
+	query := `
+		INSERT INTO ssp_partner_no_fills (
+			request_id, partner_id, partner_name, reason, timestamp
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	return as.connection().Exec(ctx, query,
+		log.RequestID,
+		log.PartnerID,
+		log.PartnerName,
+		log.Reason,
+		log.Timestamp,
+	)
+}
+
+// CreativeWarningLog represents a creative validation problem in a winning bid
+type CreativeWarningLog struct {
+	RequestID   string
+	BidID       string
+	PlacementID string
+	PartnerID   string
+	Code        string
+	Severity    string
+	Message     string
+	Timestamp   time.Time
+}
+
+// LogCreativeWarning logs a creative validation warning
+func (as *AnalyticsStore) LogCreativeWarning(ctx context.Context, log *CreativeWarningLog) error {
+	query := `
+		INSERT INTO ssp_creative_warnings (
+			request_id, bid_id, placement_id, partner_id, code, severity, message, timestamp
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return as.connection().Exec(ctx, query,
+		log.RequestID,
+		log.BidID,
+		log.PlacementID,
+		log.PartnerID,
+		log.Code,
+		log.Severity,
+		log.Message,
+		log.Timestamp,
+	)
+}
+
+// VastErrorLog represents a VAST error reported by a video player
+type VastErrorLog struct {
+	BidID       string
+	PlacementID string
+	ErrorCode   int // IAB VAST error code, e.g. 303 for no ad
+	Timestamp   time.Time
+}
+
+// LogVASTError logs a VAST player error
+func (as *AnalyticsStore) LogVASTError(ctx context.Context, log *VastErrorLog) error {
+	query := `
+		INSERT INTO ssp_vast_errors (
+			bid_id, placement_id, error_code, timestamp
+		) VALUES (?, ?, ?, ?)
+	`
+
+	return as.connection().Exec(ctx, query,
+		log.BidID,
+		log.PlacementID,
+		uint16(log.ErrorCode),
+		log.Timestamp,
+	)
+}
+
 // GetPublisherStats retrieves publisher statistics
 func (as *AnalyticsStore) GetPublisherStats(ctx context.Context, publisherID string, start, end time.Time) (*SupplyStats, error) {
 	query := `
@@ -336,7 +993,10 @@ func (as *AnalyticsStore) GetPublisherStats(ctx context.Context, publisherID str
 		GROUP BY publisher_id, date
 	`
 
-	rows, err := as.conn.Query(ctx, query, publisherID, start, end)
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, publisherID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -364,49 +1024,142 @@ func (as *AnalyticsStore) GetPublisherStats(ctx context.Context, publisherID str
 	return stats, nil
 }
 
-// GetSiteStats retrieves site statistics
-func (as *AnalyticsStore) GetSiteStats(ctx context.Context, siteID string, start, end time.Time) ([]*SupplyStats, error) {
-	query := `
+// Supply totals breakdown levels
+const (
+	statsLevelPublisher = "publisher"
+	statsLevelSite      = "site"
+	statsLevelPlacement = "placement"
+)
+
+// supplyTotalsColumns maps a breakdown level to its site and placement
+// columns; levels above them report empty IDs
+var supplyTotalsColumns = map[string][2]string{
+	statsLevelPublisher: {"''", "''"},
+	statsLevelSite:      {"site_id", "''"},
+	statsLevelPlacement: {"site_id", "placement_id"},
+}
+
+// getSupplyTotals totals a publisher's stats over a date range, one row per
+// publisher, site or placement depending on level
+func (as *AnalyticsStore) getSupplyTotals(ctx context.Context, publisherID string, start, end time.Time, level string) ([]*SupplyStats, error) {
+	cols := supplyTotalsColumns[level]
+	query := fmt.Sprintf(`
 		SELECT
 			publisher_id,
-			site_id,
-			'' as placement_id,
+			%s as site_id,
+			%s as placement_id,
 			count(*) as requests,
 			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as impressions,
 			sum(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as revenue,
-			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as fills,
-			avg(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as avg_cpm,
-			toDate(timestamp) as date
+			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as fills
 		FROM ssp_bids
-		WHERE site_id = ?
+		WHERE publisher_id = ?
 			AND timestamp >= ?
 			AND timestamp < ?
-		GROUP BY publisher_id, site_id, date
-		ORDER BY date DESC
-	`
+		GROUP BY publisher_id, site_id, placement_id
+		ORDER BY revenue DESC
+	`, cols[0], cols[1])
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
 
-	rows, err := as.conn.Query(ctx, query, siteID, start, end)
+	rows, err := as.connection().Query(ctx, query, publisherID, start, end)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	stats := []*SupplyStats{}
+	totals := []*SupplyStats{}
 	for rows.Next() {
-		stat := &SupplyStats{}
-		var date string
+		stats := &SupplyStats{}
 		if err := rows.Scan(
-			&stat.PublisherID,
-			&stat.SiteID,
-			&stat.PlacementID,
-			&stat.Requests,
-			&stat.Impressions,
-			&stat.Revenue,
-			&stat.Fills,
-			&stat.AvgCPM,
-			&date,
-		); err != nil {
-			return nil, err
+			&stats.PublisherID,
+			&stats.SiteID,
+			&stats.PlacementID,
+			&stats.Requests,
+			&stats.Impressions,
+			&stats.Revenue,
+			&stats.Fills,
+		); err != nil {
+			return nil, err
+		}
+		if stats.Impressions > 0 {
+			stats.AvgCPM = stats.Revenue / float64(stats.Impressions)
+		}
+		totals = append(totals, stats)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetPublisherTotals totals a publisher's stats over a date range
+func (as *AnalyticsStore) GetPublisherTotals(ctx context.Context, publisherID string, start, end time.Time) (*SupplyStats, error) {
+	totals, err := as.getSupplyTotals(ctx, publisherID, start, end, statsLevelPublisher)
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		return &SupplyStats{PublisherID: publisherID}, nil
+	}
+	return totals[0], nil
+}
+
+// GetSiteTotalsByPublisher totals stats over a date range for each of a publisher's sites
+func (as *AnalyticsStore) GetSiteTotalsByPublisher(ctx context.Context, publisherID string, start, end time.Time) ([]*SupplyStats, error) {
+	return as.getSupplyTotals(ctx, publisherID, start, end, statsLevelSite)
+}
+
+// GetPlacementTotalsByPublisher totals stats over a date range for each of a publisher's placements
+func (as *AnalyticsStore) GetPlacementTotalsByPublisher(ctx context.Context, publisherID string, start, end time.Time) ([]*SupplyStats, error) {
+	return as.getSupplyTotals(ctx, publisherID, start, end, statsLevelPlacement)
+}
+
+// GetSiteStats retrieves site statistics
+func (as *AnalyticsStore) GetSiteStats(ctx context.Context, siteID string, start, end time.Time) ([]*SupplyStats, error) {
+	query := `
+		SELECT
+			publisher_id,
+			site_id,
+			'' as placement_id,
+			count(*) as requests,
+			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as impressions,
+			sum(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as revenue,
+			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as fills,
+			avg(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as avg_cpm,
+			toDate(timestamp) as date
+		FROM ssp_bids
+		WHERE site_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY publisher_id, site_id, date
+		ORDER BY date DESC
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, siteID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*SupplyStats{}
+	for rows.Next() {
+		stat := &SupplyStats{}
+		var date string
+		if err := rows.Scan(
+			&stat.PublisherID,
+			&stat.SiteID,
+			&stat.PlacementID,
+			&stat.Requests,
+			&stat.Impressions,
+			&stat.Revenue,
+			&stat.Fills,
+			&stat.AvgCPM,
+			&date,
+		); err != nil {
+			return nil, err
 		}
 		stat.Date = date
 		stats = append(stats, stat)
@@ -427,8 +1180,8 @@ func (as *AnalyticsStore) GetPlacementStats(ctx context.Context, placementID str
 			sum(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as revenue,
 			sum(CASE WHEN won = 1 THEN 1 ELSE 0 END) as fills,
 			avg(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as avg_cpm,
-			toDate(timestamp) as date
-		FROM ssp_bids
+			toString(toDate(timestamp)) as date
+		FROM ssp_bids_by_placement
 		WHERE placement_id = ?
 			AND timestamp >= ?
 			AND timestamp < ?
@@ -436,7 +1189,10 @@ func (as *AnalyticsStore) GetPlacementStats(ctx context.Context, placementID str
 		ORDER BY date DESC
 	`
 
-	rows, err := as.conn.Query(ctx, query, placementID, start, end)
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, placementID, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -462,11 +1218,698 @@ func (as *AnalyticsStore) GetPlacementStats(ctx context.Context, placementID str
 		stat.Date = date
 		stats = append(stats, stat)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	clicks, err := as.getClicksByPlacementDate(ctx, placementID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// CTR is over rendered impressions, the ones clicks are matched to, not auction wins
+	rendered, err := as.getImpressionsByPlacementDate(ctx, placementID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range stats {
+		stat.Clicks = clicks[stat.Date]
+		if impressions := rendered[stat.Date]; impressions > 0 {
+			stat.CTR = float64(stat.Clicks) / float64(impressions)
+		}
+	}
+
+	return stats, nil
+}
+
+// placementClicksFilter selects clicks on a placement's impressions in a time range.
+// Clicks carry no placement ID, so they are matched to impressions by impression_id.
+const placementClicksFilter = `
+		WHERE impression_id IN (
+			SELECT impression_id
+			FROM ssp_impressions
+			WHERE placement_id = ?
+				AND timestamp >= ?
+				AND timestamp < ?
+		)
+			AND timestamp >= ?
+			AND timestamp < ?
+`
+
+// getClicksByPlacementDate counts the clicks on a placement's impressions per
+// day, keyed by date (YYYY-MM-DD) to match GetPlacementStats rows
+func (as *AnalyticsStore) getClicksByPlacementDate(ctx context.Context, placementID string, start, end time.Time) (map[string]int64, error) {
+	query := `
+		SELECT toString(toDate(timestamp)) as date, toInt64(count()) as clicks
+		FROM ssp_clicks
+	` + placementClicksFilter + `
+		GROUP BY date
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, placementID, start, end, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clicks := make(map[string]int64)
+	for rows.Next() {
+		var date string
+		var count int64
+		if err := rows.Scan(&date, &count); err != nil {
+			return nil, err
+		}
+		clicks[date] = count
+	}
+
+	return clicks, rows.Err()
+}
+
+// getImpressionsByPlacementDate counts a placement's rendered impressions per
+// day, keyed by date (YYYY-MM-DD) to match GetPlacementStats rows
+func (as *AnalyticsStore) getImpressionsByPlacementDate(ctx context.Context, placementID string, start, end time.Time) (map[string]int64, error) {
+	query := `
+		SELECT toString(toDate(timestamp)) as date, toInt64(count()) as impressions
+		FROM ssp_impressions
+		WHERE placement_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY date
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, placementID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	impressions := make(map[string]int64)
+	for rows.Next() {
+		var date string
+		var count int64
+		if err := rows.Scan(&date, &count); err != nil {
+			return nil, err
+		}
+		impressions[date] = count
+	}
+
+	return impressions, rows.Err()
+}
+
+// GetNetworkAverages retrieves per-publisher RPM, fill rate, CTR and CPM averaged
+// across all publishers. No publisher identifiers are included in the result.
+func (as *AnalyticsStore) GetNetworkAverages(ctx context.Context, start, end time.Time) (*NetworkAverages, error) {
+	query := `
+		SELECT
+			toFloat64(avg(if(r.requests > 0, i.revenue / r.requests * 1000, 0))) as avg_rpm,
+			toFloat64(avg(if(r.requests > 0, i.impressions / r.requests, 0))) as avg_fill_rate,
+			toFloat64(avg(if(i.impressions > 0, c.clicks / i.impressions, 0))) as avg_ctr,
+			toFloat64(avg(if(i.impressions > 0, i.revenue / i.impressions * 1000, 0))) as avg_cpm
+		FROM (
+			SELECT publisher_id, count(*) as requests
+			FROM ssp_ad_requests
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY publisher_id
+		) AS r
+		LEFT JOIN (
+			SELECT publisher_id, count(*) as impressions, sum(price) / 1000 as revenue
+			FROM ssp_impressions
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY publisher_id
+		) AS i ON r.publisher_id = i.publisher_id
+		LEFT JOIN (
+			SELECT publisher_id, count(*) as clicks
+			FROM ssp_clicks
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY publisher_id
+		) AS c ON r.publisher_id = c.publisher_id
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	stats := &NetworkAverages{}
+	if err := as.connection().QueryRow(ctx, query, start, end, start, end, start, end).Scan(
+		&stats.RPM,
+		&stats.FillRate,
+		&stats.CTR,
+		&stats.AvgCPM,
+	); err != nil {
+		return nil, err
+	}
 
 	return stats, nil
 }
 
+// PeakTrafficHours is the number of hour-of-week buckets GetPeakTrafficHours returns
+const PeakTrafficHours = 10
+
+// MaxPeakTrafficLookbackDays caps the days of ad requests GetPeakTrafficHours scans
+const MaxPeakTrafficLookbackDays = 90
+
+// ErrInvalidLookbackDays is returned for a lookback outside 1..MaxPeakTrafficLookbackDays
+var ErrInvalidLookbackDays = fmt.Errorf("lookback days must be between 1 and %d", MaxPeakTrafficLookbackDays)
+
+// GetPeakTrafficHours retrieves the UTC hours of the week with the most ad
+// requests over the last lookbackDays days, busiest first. Hours without
+// requests do not count toward an hour's average.
+func (as *AnalyticsStore) GetPeakTrafficHours(ctx context.Context, lookbackDays int) ([]HourlyTrafficStats, error) {
+	if lookbackDays <= 0 || lookbackDays > MaxPeakTrafficLookbackDays {
+		return nil, ErrInvalidLookbackDays
+	}
+
+	query := `
+		SELECT
+			toInt32(toDayOfWeek(hour_start)) as day_of_week,
+			toInt32(toHour(hour_start)) as hour,
+			toFloat64(avg(requests)) as avg_requests,
+			toFloat64(quantile(0.95)(requests)) as p95_requests
+		FROM (
+			SELECT toStartOfHour(timestamp, 'UTC') as hour_start, count(*) as requests
+			FROM ssp_ad_requests
+			WHERE timestamp >= ?
+			GROUP BY hour_start
+		)
+		GROUP BY day_of_week, hour
+		ORDER BY avg_requests DESC
+		LIMIT ?
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	since := time.Now().UTC().AddDate(0, 0, -lookbackDays)
+	rows, err := as.connection().Query(ctx, query, since, PeakTrafficHours)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := []HourlyTrafficStats{}
+	for rows.Next() {
+		var dayOfWeek, hour int32
+		var stat HourlyTrafficStats
+		if err := rows.Scan(&dayOfWeek, &hour, &stat.AvgRequests, &stat.P95Requests); err != nil {
+			return nil, err
+		}
+		stat.DayOfWeek, stat.Hour = int(dayOfWeek), int(hour)
+		hours = append(hours, stat)
+	}
+
+	return hours, rows.Err()
+}
+
+// MaxTopPlacements caps the placements returned by GetTopPlacements
+const MaxTopPlacements = 100
+
+// topPlacementsQuery ranks a publisher's placements by revenue. ssp_bids has a
+// row per bid, so requests are distinct request IDs and impressions are the
+// won bids, not row counts.
+const topPlacementsQuery = `
+		SELECT
+			placement_id,
+			toInt64(uniqExact(request_id)) as requests,
+			toInt64(countIf(won = 1)) as impressions,
+			sumIf(cleared_price, won = 1) / 1000 as revenue
+		FROM ssp_bids
+		WHERE publisher_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY placement_id
+		ORDER BY revenue DESC, placement_id
+		LIMIT ?
+	`
+
+// GetTopPlacements retrieves a publisher's placements ranked by revenue
+func (as *AnalyticsStore) GetTopPlacements(ctx context.Context, publisherID string, start, end time.Time, limit int) ([]*PlacementPerf, error) {
+	if limit <= 0 || limit > MaxTopPlacements {
+		limit = MaxTopPlacements
+	}
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, topPlacementsQuery, publisherID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	placements := []*PlacementPerf{}
+	for rows.Next() {
+		perf := &PlacementPerf{}
+		if err := rows.Scan(
+			&perf.PlacementID,
+			&perf.Requests,
+			&perf.Impressions,
+			&perf.Revenue,
+		); err != nil {
+			return nil, err
+		}
+		perf.setRates()
+		placements = append(placements, perf)
+	}
+
+	return placements, rows.Err()
+}
+
+// setRates derives RPM and fill rate from the request, impression and revenue totals
+func (p *PlacementPerf) setRates() {
+	if p.Requests > 0 {
+		p.RPM = p.Revenue / float64(p.Requests) * 1000
+		p.FillRate = float64(p.Impressions) / float64(p.Requests)
+	}
+}
+
+// ErrInvalidGranularity is returned for an unsupported stats time bucket
+var ErrInvalidGranularity = errors.New("granularity must be hour, day, week or month")
+
+// networkStatsBuckets maps a granularity to the ClickHouse expression that
+// truncates timestamp to the start of its UTC bucket
+var networkStatsBuckets = map[string]string{
+	"hour":  "toStartOfHour(timestamp, 'UTC')",
+	"day":   "toDateTime(toStartOfDay(timestamp, 'UTC'), 'UTC')",
+	"week":  "toDateTime(toStartOfWeek(timestamp, 1, 'UTC'), 'UTC')",
+	"month": "toDateTime(toStartOfMonth(timestamp, 'UTC'), 'UTC')",
+}
+
+// networkStatsBucketLabel formats a bucket start for NetworkStats.Date
+func networkStatsBucketLabel(bucket time.Time, granularity string) string {
+	if granularity == "hour" {
+		return bucket.UTC().Format(time.RFC3339)
+	}
+	return bucket.UTC().Format("2006-01-02")
+}
+
+// GetNetworkStats retrieves ad requests, impressions, revenue, fill rate and
+// average CPM across all publishers for each hour, day, week or month bucket
+func (as *AnalyticsStore) GetNetworkStats(ctx context.Context, start, end time.Time, granularity string) ([]*NetworkStats, error) {
+	bucket, ok := networkStatsBuckets[granularity]
+	if !ok {
+		return nil, ErrInvalidGranularity
+	}
+
+	query := `
+		SELECT
+			r.bucket,
+			r.requests,
+			r.publishers,
+			i.impressions,
+			i.revenue
+		FROM (
+			SELECT ` + bucket + ` as bucket, toInt64(count(*)) as requests, toInt64(uniqExact(publisher_id)) as publishers
+			FROM ssp_ad_requests
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY bucket
+		) AS r
+		LEFT JOIN (
+			SELECT ` + bucket + ` as bucket, toInt64(count(*)) as impressions, sum(price) / 1000 as revenue
+			FROM ssp_impressions
+			WHERE timestamp >= ? AND timestamp < ?
+			GROUP BY bucket
+		) AS i ON r.bucket = i.bucket
+		ORDER BY r.bucket
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, start, end, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*NetworkStats{}
+	for rows.Next() {
+		stat := &NetworkStats{}
+		var bucketStart time.Time
+		if err := rows.Scan(
+			&bucketStart,
+			&stat.Requests,
+			&stat.PublisherCount,
+			&stat.Impressions,
+			&stat.Revenue,
+		); err != nil {
+			return nil, err
+		}
+		stat.Date = networkStatsBucketLabel(bucketStart, granularity)
+		stat.Fills = stat.Impressions
+		if stat.Requests > 0 {
+			stat.FillRate = float64(stat.Impressions) / float64(stat.Requests)
+			stat.RPM = stat.Revenue / float64(stat.Requests) * 1000
+		}
+		if stat.Impressions > 0 {
+			stat.AvgCPM = stat.Revenue / float64(stat.Impressions) * 1000
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetImpressionsByHour retrieves a publisher's impressions and revenue for each
+// hour of a UTC date. Hours without impressions are included with zero values.
+func (as *AnalyticsStore) GetImpressionsByHour(ctx context.Context, publisherID string, date time.Time) ([]HourlyImpression, error) {
+	query := `
+		SELECT
+			toInt32(toHour(timestamp, 'UTC')) as hour,
+			toInt64(count(*)) as impressions,
+			sum(publisher_revenue) / 1000 as revenue
+		FROM ssp_impressions
+		WHERE publisher_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY hour
+	`
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, publisherID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := make([]HourlyImpression, 24)
+	for i := range hours {
+		hours[i].Hour = i
+	}
+
+	for rows.Next() {
+		var hour int32
+		var impressions int64
+		var revenue float64
+		if err := rows.Scan(&hour, &impressions, &revenue); err != nil {
+			return nil, err
+		}
+		if hour >= 0 && hour < 24 {
+			hours[hour].Impressions = impressions
+			hours[hour].Revenue = revenue
+		}
+	}
+
+	return hours, nil
+}
+
+// GetImpressionsByDay retrieves a publisher's impressions and gross revenue for
+// each UTC day in [start, end). Days without impressions are omitted.
+func (as *AnalyticsStore) GetImpressionsByDay(ctx context.Context, publisherID string, start, end time.Time) ([]DailyImpression, error) {
+	query := `
+		SELECT
+			toDate(timestamp, 'UTC') as day,
+			toInt64(count(*)) as impressions,
+			sum(price) / 1000 as revenue
+		FROM ssp_impressions
+		WHERE publisher_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY day
+		ORDER BY day
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, publisherID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []DailyImpression{}
+	for rows.Next() {
+		var day DailyImpression
+		if err := rows.Scan(&day.Date, &day.Impressions, &day.Revenue); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// GetNoFillReasons retrieves no-fill request counts for a placement grouped by reason
+func (as *AnalyticsStore) GetNoFillReasons(ctx context.Context, placementID string, start, end time.Time) (map[string]int64, error) {
+	query := `
+		SELECT
+			no_fill_reason,
+			toInt64(count(*)) as requests
+		FROM ssp_ad_requests
+		WHERE placement_id = ?
+			AND no_fill_reason != ''
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY no_fill_reason
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, placementID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := make(map[string]int64)
+	for rows.Next() {
+		var reason string
+		var count int64
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		reasons[reason] = count
+	}
+
+	return reasons, nil
+}
+
+// GetPartnerNoFills retrieves no-fill counts for a partner grouped by reason
+func (as *AnalyticsStore) GetPartnerNoFills(ctx context.Context, partnerID string, start, end time.Time) (map[string]int64, error) {
+	query := `
+		SELECT
+			reason,
+			toInt64(count(*)) as no_fills
+		FROM ssp_partner_no_fills
+		WHERE partner_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY reason
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, partnerID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reasons := make(map[string]int64)
+	for rows.Next() {
+		var reason string
+		var count int64
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, err
+		}
+		reasons[reason] = count
+	}
+
+	return reasons, nil
+}
+
+// GetVASTErrors retrieves a placement's VAST error counts by error code
+func (as *AnalyticsStore) GetVASTErrors(ctx context.Context, placementID string, start, end time.Time) (map[int]int64, error) {
+	query := `
+		SELECT
+			toInt32(error_code) as code,
+			toInt64(count(*)) as errors
+		FROM ssp_vast_errors
+		WHERE placement_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY code
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, placementID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	errorCodes := make(map[int]int64)
+	for rows.Next() {
+		var code int32
+		var count int64
+		if err := rows.Scan(&code, &count); err != nil {
+			return nil, err
+		}
+		errorCodes[int(code)] = count
+	}
+
+	return errorCodes, nil
+}
+
+// GetPartnerStats retrieves a partner's bids, wins and spend per publisher,
+// highest spend first
+func (as *AnalyticsStore) GetPartnerStats(ctx context.Context, partnerID string, start, end time.Time) ([]*PartnerStats, error) {
+	query := `
+		SELECT
+			partner_id,
+			publisher_id,
+			toInt64(count(*)) as bids,
+			toInt64(sum(won)) as wins,
+			avg(price) as avg_bid,
+			sum(CASE WHEN won = 1 THEN cleared_price ELSE 0 END) as spend
+		FROM ssp_bids_by_partner_publisher
+		WHERE partner_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY partner_id, publisher_id
+		ORDER BY spend DESC
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, partnerID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*PartnerStats{}
+	for rows.Next() {
+		stat := &PartnerStats{}
+		if err := rows.Scan(
+			&stat.PartnerID,
+			&stat.PublisherID,
+			&stat.Bids,
+			&stat.Wins,
+			&stat.AvgBid,
+			&stat.Spend,
+		); err != nil {
+			return nil, err
+		}
+		if stat.Bids > 0 {
+			stat.WinRate = float64(stat.Wins) / float64(stat.Bids)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetPartnerContribution retrieves each demand partner's revenue on a
+// publisher's inventory, highest revenue first
+func (as *AnalyticsStore) GetPartnerContribution(ctx context.Context, publisherID string, start, end time.Time) ([]*PartnerContribution, error) {
+	query := `
+		SELECT
+			partner_id,
+			anyLast(partner_name) as partner_name,
+			sumIf(cleared_price, won = 1) / 1000 as revenue,
+			toInt64(sum(won)) as impressions
+		FROM ssp_bids
+		WHERE publisher_id = ?
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY partner_id
+		ORDER BY revenue DESC, partner_id
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, publisherID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contributions := []*PartnerContribution{}
+	for rows.Next() {
+		contribution := &PartnerContribution{}
+		if err := rows.Scan(
+			&contribution.PartnerID,
+			&contribution.PartnerName,
+			&contribution.Revenue,
+			&contribution.Impressions,
+		); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, contribution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	setContributionShares(contributions)
+	return contributions, nil
+}
+
+// setContributionShares sets each partner's SharePct of the total revenue
+func setContributionShares(contributions []*PartnerContribution) {
+	total := 0.0
+	for _, c := range contributions {
+		total += c.Revenue
+	}
+	if total <= 0 {
+		return
+	}
+
+	for _, c := range contributions {
+		c.SharePct = c.Revenue / total * 100
+	}
+}
+
+// GetRevenueByPublisher retrieves total cleared revenue per publisher
+func (as *AnalyticsStore) GetRevenueByPublisher(ctx context.Context, start, end time.Time) (map[string]float64, error) {
+	query := `
+		SELECT
+			publisher_id,
+			sum(cleared_price) as revenue
+		FROM ssp_bids
+		WHERE won = 1
+			AND timestamp >= ?
+			AND timestamp < ?
+		GROUP BY publisher_id
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revenue := make(map[string]float64)
+	for rows.Next() {
+		var publisherID string
+		var total float64
+		if err := rows.Scan(&publisherID, &total); err != nil {
+			return nil, err
+		}
+		revenue[publisherID] = total
+	}
+
+	return revenue, nil
+}
+
 // Close closes the ClickHouse connection
 func (as *AnalyticsStore) Close() error {
-	return as.conn.Close()
+	return as.connection().Close()
 }
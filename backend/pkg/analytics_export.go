@@ -0,0 +1,106 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// Analytics export statuses
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// ExportDateLayout is the format of AnalyticsExport.Date
+const ExportDateLayout = "2006-01-02"
+
+// AnalyticsExport is a copy of one day of an analytics table to object storage
+type AnalyticsExport struct {
+	ID          string     `json:"id"`
+	Table       string     `json:"table"`
+	Date        string     `json:"date"`   // YYYY-MM-DD
+	Bucket      string     `json:"bucket"` // s3://, gs:// or https:// bucket URL
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ExportObjectURL returns the HTTPS URL of the Parquet file a day of table is
+// exported to under bucketURL. s3:// and gs:// URLs are mapped to their
+// public endpoints; https:// URLs are used as given.
+func ExportObjectURL(bucketURL, table string, date time.Time) (string, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("bucket must be an s3://, gs:// or https:// URL")
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	var base string
+	switch u.Scheme {
+	case "s3":
+		base = "https://" + u.Host + ".s3.amazonaws.com"
+	case "gs":
+		base = "https://storage.googleapis.com/" + u.Host
+	case "https":
+		base = "https://" + u.Host
+	default:
+		return "", fmt.Errorf("bucket must be an s3://, gs:// or https:// URL")
+	}
+	if prefix != "" {
+		base += "/" + prefix
+	}
+
+	return fmt.Sprintf("%s/%s/%s.parquet", base, table, date.Format(ExportDateLayout)), nil
+}
+
+// ExportToS3 copies one day of an analytics table to object storage as
+// Parquet through ClickHouse's s3 table function. Re-exporting a day
+// overwrites the previous file.
+func (as *AnalyticsStore) ExportToS3(ctx context.Context, table string, date time.Time, bucketURL string) error {
+	if err := ValidateExportTable(table); err != nil {
+		return err
+	}
+
+	objectURL, err := ExportObjectURL(bucketURL, table, date)
+	if err != nil {
+		return err
+	}
+
+	// Without keys ClickHouse falls back to its own configured credentials
+	source := "s3(?, 'Parquet')"
+	args := []interface{}{objectURL}
+	if cfg := as.config(); cfg.ExportAccessKeyID != "" {
+		source = "s3(?, ?, ?, 'Parquet')"
+		args = append(args, cfg.ExportAccessKeyID, cfg.ExportSecretAccessKey)
+	}
+	args = append(args, date.Format(ExportDateLayout))
+
+	// The table name is our own, so it is safe to format into the statement
+	query := fmt.Sprintf("INSERT INTO FUNCTION %s SELECT * FROM %s WHERE toDate(timestamp) = toDate(?)", source, table)
+
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"s3_truncate_on_insert": 1,
+	}))
+	if err := as.connection().Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to export %s for %s: %w", table, date.Format(ExportDateLayout), err)
+	}
+	return nil
+}
+
+// ValidateExportTable checks that table is one of the SSP's analytics tables
+func ValidateExportTable(table string) error {
+	for _, t := range analyticsTables(DefaultAnalyticsRetentionDays) {
+		if t.name == table {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownAnalyticsTable, table)
+}
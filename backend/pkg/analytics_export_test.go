@@ -0,0 +1,47 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExportObjectURL(t *testing.T) {
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		"s3://analytics-archive":           "https://analytics-archive.s3.amazonaws.com/ssp_bids/2024-01-15.parquet",
+		"s3://analytics-archive/ssp/":      "https://analytics-archive.s3.amazonaws.com/ssp/ssp_bids/2024-01-15.parquet",
+		"gs://analytics-archive/ssp":       "https://storage.googleapis.com/analytics-archive/ssp/ssp_bids/2024-01-15.parquet",
+		"https://minio.example.com/bucket": "https://minio.example.com/bucket/ssp_bids/2024-01-15.parquet",
+	}
+	for bucket, expected := range tests {
+		got, err := ExportObjectURL(bucket, "ssp_bids", date)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", bucket, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, bucket, got)
+		}
+	}
+
+	for _, bucket := range []string{"", "analytics-archive", "http://minio.example.com/bucket", "file:///tmp/export"} {
+		if _, err := ExportObjectURL(bucket, "ssp_bids", date); err == nil {
+			t.Errorf("Expected bucket %q to be rejected", bucket)
+		}
+	}
+}
+
+func TestExportToS3RejectsUnknownTable(t *testing.T) {
+	as := &AnalyticsStore{}
+	err := as.ExportToS3(context.Background(), "system.users", time.Now(), "s3://analytics-archive")
+	if !errors.Is(err, ErrUnknownAnalyticsTable) {
+		t.Errorf("Expected ErrUnknownAnalyticsTable, got %v", err)
+	}
+
+	if err := ValidateExportTable("ssp_bids"); err != nil {
+		t.Errorf("Expected ssp_bids to be exportable, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Analytics worker pool defaults
+const (
+	DefaultAnalyticsQueueSize = 10000
+	DefaultAnalyticsWorkers   = 4
+)
+
+// analyticsWriteTimeout bounds a single analytics write
+const analyticsWriteTimeout = 5 * time.Second
+
+// AnalyticsEvent is a log entry that can be written to the analytics store
+type AnalyticsEvent interface {
+	write(ctx context.Context, as *AnalyticsStore) error
+}
+
+func (log *AdRequestLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogAdRequest(ctx, log)
+}
+
+func (log *BidLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogBid(ctx, log)
+}
+
+func (log *ImpressionLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogImpression(ctx, log)
+}
+
+func (log *ClickLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogClick(ctx, log)
+}
+
+func (log *PartnerNoFillLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogPartnerNoFill(ctx, log)
+}
+
+func (log *CreativeWarningLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogCreativeWarning(ctx, log)
+}
+
+func (log *VastErrorLog) write(ctx context.Context, as *AnalyticsStore) error {
+	return as.LogVASTError(ctx, log)
+}
+
+// AnalyticsWorkerPool writes analytics events in the background through a
+// bounded queue, so load spikes cannot spawn unbounded goroutines. Events are
+// dropped when the queue is full.
+type AnalyticsWorkerPool struct {
+	store  *AnalyticsStore
+	events chan AnalyticsEvent
+	onDrop func() // Called for each dropped event, e.g. to count drops
+	logger *slog.Logger
+	wg     sync.WaitGroup
+}
+
+// NewAnalyticsWorkerPool starts workers draining a queue of queueSize events
+func NewAnalyticsWorkerPool(store *AnalyticsStore, queueSize, workers int, onDrop func(), logger *slog.Logger) *AnalyticsWorkerPool {
+	if queueSize <= 0 {
+		queueSize = DefaultAnalyticsQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultAnalyticsWorkers
+	}
+
+	pool := &AnalyticsWorkerPool{
+		store:  store,
+		events: make(chan AnalyticsEvent, queueSize),
+		onDrop: onDrop,
+		logger: logger,
+	}
+
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Enqueue queues an event without blocking. It reports false when the event
+// was dropped because the queue is full. A nil pool discards events.
+func (p *AnalyticsWorkerPool) Enqueue(event AnalyticsEvent) bool {
+	if p == nil {
+		return false
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	default:
+		if p.onDrop != nil {
+			p.onDrop()
+		}
+		return false
+	}
+}
+
+// Close stops accepting events and waits for queued events to be written
+func (p *AnalyticsWorkerPool) Close() {
+	close(p.events)
+	p.wg.Wait()
+}
+
+func (p *AnalyticsWorkerPool) work() {
+	defer p.wg.Done()
+
+	for event := range p.events {
+		ctx, cancel := context.WithTimeout(context.Background(), analyticsWriteTimeout)
+		if err := event.write(ctx, p.store); err != nil {
+			p.logger.Error("Failed to write analytics event", "event", fmt.Sprintf("%T", event), "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,60 @@
+package ssp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+)
+
+type countingEvent struct {
+	written *atomic.Int64
+}
+
+func (e countingEvent) write(ctx context.Context, as *AnalyticsStore) error {
+	e.written.Add(1)
+	return nil
+}
+
+func TestAnalyticsWorkerPoolWritesEvents(t *testing.T) {
+	var written atomic.Int64
+	pool := NewAnalyticsWorkerPool(nil, 100, 2, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := 0; i < 50; i++ {
+		if !pool.Enqueue(countingEvent{written: &written}) {
+			t.Fatalf("event %d unexpectedly dropped", i)
+		}
+	}
+	pool.Close()
+
+	if got := written.Load(); got != 50 {
+		t.Errorf("expected 50 events written, got %d", got)
+	}
+}
+
+func TestAnalyticsWorkerPoolDropsWhenFull(t *testing.T) {
+	var written atomic.Int64
+	drops := 0
+
+	// No workers are started, so the queue fills up
+	pool := &AnalyticsWorkerPool{
+		events: make(chan AnalyticsEvent, 2),
+		onDrop: func() { drops++ },
+	}
+
+	for i := 0; i < 5; i++ {
+		pool.Enqueue(countingEvent{written: &written})
+	}
+
+	if drops != 3 {
+		t.Errorf("expected 3 dropped events, got %d", drops)
+	}
+}
+
+func TestAnalyticsWorkerPoolNil(t *testing.T) {
+	var pool *AnalyticsWorkerPool
+	if pool.Enqueue(&ClickLog{}) {
+		t.Error("expected nil pool to discard events")
+	}
+}
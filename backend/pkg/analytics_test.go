@@ -0,0 +1,230 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNetworkStatsBucketLabel(t *testing.T) {
+	bucket := time.Date(2024, 3, 4, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		granularity string
+		expected    string
+	}{
+		{"hour", "2024-03-04T13:00:00Z"},
+		{"day", "2024-03-04"},
+		{"week", "2024-03-04"},
+		{"month", "2024-03-04"},
+	}
+
+	for _, tt := range tests {
+		if _, ok := networkStatsBuckets[tt.granularity]; !ok {
+			t.Errorf("Expected bucket expression for %s", tt.granularity)
+		}
+		if got := networkStatsBucketLabel(bucket, tt.granularity); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.granularity, tt.expected, got)
+		}
+	}
+}
+
+func TestGetNetworkStatsInvalidGranularity(t *testing.T) {
+	as := &AnalyticsStore{}
+	_, err := as.GetNetworkStats(context.Background(), time.Now().AddDate(0, 0, -1), time.Now(), "minute")
+	if !errors.Is(err, ErrInvalidGranularity) {
+		t.Errorf("Expected ErrInvalidGranularity, got %v", err)
+	}
+}
+
+func TestGetPeakTrafficHoursInvalidLookback(t *testing.T) {
+	as := &AnalyticsStore{}
+	for _, days := range []int{0, -1, MaxPeakTrafficLookbackDays + 1} {
+		if _, err := as.GetPeakTrafficHours(context.Background(), days); !errors.Is(err, ErrInvalidLookbackDays) {
+			t.Errorf("Expected ErrInvalidLookbackDays for %d days, got %v", days, err)
+		}
+	}
+}
+
+func TestAnalyticsTablesRetention(t *testing.T) {
+	for _, table := range analyticsTables(30) {
+		if !strings.Contains(table.schema, "TTL timestamp + INTERVAL 30 DAY") {
+			t.Errorf("Expected %s to expire rows after 30 days, got:\n%s", table.name, table.schema)
+		}
+	}
+
+	for _, table := range analyticsTables(0) {
+		if !strings.Contains(table.schema, "TTL timestamp + INTERVAL 90 DAY") {
+			t.Errorf("Expected %s to default to 90 day retention, got:\n%s", table.name, table.schema)
+		}
+	}
+}
+
+func TestParseTTLDays(t *testing.T) {
+	engineFull := "MergeTree PARTITION BY toYYYYMM(timestamp) ORDER BY (timestamp, publisher_id, partner_id) TTL timestamp + toIntervalDay(30) SETTINGS index_granularity = 8192"
+	if days := parseTTLDays(engineFull); days != 30 {
+		t.Errorf("Expected 30 day TTL, got %d", days)
+	}
+	if days := parseTTLDays("MergeTree ORDER BY timestamp SETTINGS index_granularity = 8192"); days != 0 {
+		t.Errorf("Expected no TTL, got %d", days)
+	}
+}
+
+func TestValidateAnalyticsRetention(t *testing.T) {
+	if err := ValidateAnalyticsRetention("ssp_bids", 180); err != nil {
+		t.Errorf("Expected ssp_bids retention to be valid, got %v", err)
+	}
+	if err := ValidateAnalyticsRetention("ssp_bids", 0); !errors.Is(err, ErrInvalidRetentionDays) {
+		t.Errorf("Expected ErrInvalidRetentionDays, got %v", err)
+	}
+	if err := ValidateAnalyticsRetention("ssp_bids; DROP TABLE ssp_bids", 30); !errors.Is(err, ErrUnknownAnalyticsTable) {
+		t.Errorf("Expected ErrUnknownAnalyticsTable, got %v", err)
+	}
+}
+
+func TestSetRetentionRejectsUnknownTable(t *testing.T) {
+	as := &AnalyticsStore{}
+	if err := as.SetRetention(context.Background(), "users", 30); !errors.Is(err, ErrUnknownAnalyticsTable) {
+		t.Errorf("Expected ErrUnknownAnalyticsTable, got %v", err)
+	}
+}
+
+func TestAnalyticsQueryContext(t *testing.T) {
+	tests := []struct {
+		seconds  int
+		expected time.Duration
+	}{
+		{0, DefaultAnalyticsQueryTimeout},
+		{3, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		as := &AnalyticsStore{cfg: ClickHouseConfig{QueryTimeoutSeconds: tt.seconds}}
+		ctx, cancel := as.queryContext(context.Background())
+		deadline, ok := ctx.Deadline()
+		cancel()
+		if !ok {
+			t.Fatalf("Expected query context to have a deadline")
+		}
+		if remaining := time.Until(deadline); remaining > tt.expected || remaining < tt.expected-time.Second {
+			t.Errorf("QueryTimeoutSeconds=%d: expected deadline in %v, got %v", tt.seconds, tt.expected, remaining)
+		}
+	}
+}
+
+func TestAnalyticsBidsViews(t *testing.T) {
+	var bids analyticsTable
+	for _, table := range analyticsTables(30) {
+		if table.name == "ssp_bids" {
+			bids = table
+		}
+	}
+
+	expected := map[string]string{
+		"ssp_bids_by_placement":         "ORDER BY (placement_id, timestamp)",
+		"ssp_bids_by_partner_publisher": "ORDER BY (partner_id, publisher_id, timestamp)",
+	}
+	if len(bids.views) != len(expected) {
+		t.Fatalf("Expected %d ssp_bids views, got %d", len(expected), len(bids.views))
+	}
+	for _, view := range bids.views {
+		orderBy, ok := expected[view.name]
+		if !ok {
+			t.Errorf("Unexpected view %s", view.name)
+			continue
+		}
+		for _, want := range []string{"CREATE MATERIALIZED VIEW IF NOT EXISTS " + view.name, orderBy, "TTL timestamp + INTERVAL 30 DAY", "AS SELECT * FROM ssp_bids", "WHERE timestamp >= ?"} {
+			if !strings.Contains(view.schema, want) {
+				t.Errorf("Expected %s schema to contain %q, got:\n%s", view.name, want, view.schema)
+			}
+		}
+		if strings.Contains(view.schema, "POPULATE") {
+			t.Errorf("Expected %s to be backfilled rather than populated at creation", view.name)
+		}
+	}
+}
+
+func TestTopPlacementsCountsWonImpressions(t *testing.T) {
+	for _, want := range []string{"uniqExact(request_id)) as requests", "countIf(won = 1)) as impressions", "sumIf(cleared_price, won = 1)"} {
+		if !strings.Contains(topPlacementsQuery, want) {
+			t.Errorf("Expected top placements query to contain %q, got:\n%s", want, topPlacementsQuery)
+		}
+	}
+	if strings.Contains(topPlacementsQuery, "count(*)") {
+		t.Error("Expected bid rows not to be counted as requests or impressions")
+	}
+
+	// 200 requests, 50 won impressions, $1.00 revenue
+	perf := &PlacementPerf{PlacementID: "placement-1", Requests: 200, Impressions: 50, Revenue: 1.0}
+	perf.setRates()
+	if math.Abs(perf.RPM-5.0) > 1e-9 {
+		t.Errorf("Expected RPM 5.0, got %f", perf.RPM)
+	}
+	if math.Abs(perf.FillRate-0.25) > 1e-9 {
+		t.Errorf("Expected fill rate 0.25, got %f", perf.FillRate)
+	}
+
+	empty := &PlacementPerf{PlacementID: "placement-2"}
+	empty.setRates()
+	if empty.RPM != 0 || empty.FillRate != 0 {
+		t.Errorf("Expected zero rates without requests, got %+v", empty)
+	}
+}
+
+func TestImpressionIDForBid(t *testing.T) {
+	id := ImpressionIDForBid("bid-1")
+	if id != ImpressionIDForBid("bid-1") {
+		t.Error("Expected the same impression ID for the same bid")
+	}
+	if id == ImpressionIDForBid("bid-2") {
+		t.Error("Expected different impression IDs for different bids")
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		t.Errorf("Expected a UUID impression ID, got %q", id)
+	}
+}
+
+func TestClickHouseConfigPoolOptions(t *testing.T) {
+	opts := ClickHouseConfig{Addr: "localhost:9000"}.options(nil)
+	if opts.MaxOpenConns != DefaultClickHouseMaxOpenConns || opts.MaxIdleConns != DefaultClickHouseMaxIdleConns {
+		t.Errorf("Expected default pool %d/%d, got %d/%d", DefaultClickHouseMaxOpenConns, DefaultClickHouseMaxIdleConns, opts.MaxOpenConns, opts.MaxIdleConns)
+	}
+	if opts.DialTimeout != 0 {
+		t.Errorf("Expected driver default dial timeout, got %v", opts.DialTimeout)
+	}
+
+	opts = ClickHouseConfig{MaxOpenConns: 2, MaxIdleConns: 10, DialTimeout: 500 * time.Millisecond}.options(nil)
+	if opts.MaxOpenConns != 2 || opts.MaxIdleConns != 2 {
+		t.Errorf("Expected idle connections capped at pool size 2, got %d/%d", opts.MaxOpenConns, opts.MaxIdleConns)
+	}
+	if opts.DialTimeout != 500*time.Millisecond {
+		t.Errorf("Expected dial timeout 500ms, got %v", opts.DialTimeout)
+	}
+}
+
+func TestSetContributionShares(t *testing.T) {
+	contributions := []*PartnerContribution{
+		{PartnerID: "dsp-1", Revenue: 3},
+		{PartnerID: "dsp-2", Revenue: 1},
+		{PartnerID: "dsp-3", Revenue: 0},
+	}
+	setContributionShares(contributions)
+
+	expected := []float64{75, 25, 0}
+	for i, c := range contributions {
+		if math.Abs(c.SharePct-expected[i]) > 1e-9 {
+			t.Errorf("Expected %s share %.2f, got %.2f", c.PartnerID, expected[i], c.SharePct)
+		}
+	}
+
+	none := []*PartnerContribution{{PartnerID: "dsp-1"}}
+	setContributionShares(none)
+	if none[0].SharePct != 0 {
+		t.Errorf("Expected zero share without revenue, got %.2f", none[0].SharePct)
+	}
+}
@@ -0,0 +1,30 @@
+package ssp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// APIKeyGracePeriod is how long a rotated-out API key stays valid
+const APIKeyGracePeriod = 24 * time.Hour
+
+// apiKeyPrefix identifies AdNexus publisher API keys
+const apiKeyPrefix = "anx_"
+
+// GenerateAPIKey returns a new random publisher API key
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hex SHA-256 hash under which an API key is stored
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
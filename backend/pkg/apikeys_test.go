@@ -0,0 +1,28 @@
+package ssp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key1, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey failed: %v", err)
+	}
+	key2, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey failed: %v", err)
+	}
+
+	if !strings.HasPrefix(key1, apiKeyPrefix) {
+		t.Errorf("expected key prefix %s, got %s", apiKeyPrefix, key1)
+	}
+	if key1 == key2 {
+		t.Error("expected unique keys")
+	}
+
+	if hash := HashAPIKey(key1); len(hash) != 64 || hash == HashAPIKey(key2) {
+		t.Errorf("unexpected key hash %s", hash)
+	}
+}
@@ -0,0 +1,58 @@
+package ssp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// appStoreHosts are the app store domains accepted in App.StoreURL
+var appStoreHosts = map[string]bool{
+	"apps.apple.com":   true,
+	"itunes.apple.com": true,
+	"play.google.com":  true,
+}
+
+// androidBundlePattern matches reverse-DNS package names such as
+// "com.example.app", which Android apps use as their bundle
+var androidBundlePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*(\.[a-zA-Z][a-zA-Z0-9_]*)+$`)
+
+// ValidateStoreURL checks that an app store URL points to the Apple App Store
+// or Google Play
+func ValidateStoreURL(storeURL string) error {
+	u, err := url.Parse(storeURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("storeurl must be an http(s) URL")
+	}
+
+	if !appStoreHosts[strings.ToLower(u.Hostname())] {
+		return fmt.Errorf("storeurl must be an Apple App Store or Google Play URL")
+	}
+
+	return nil
+}
+
+// ValidateAppBundle checks that a bundle is an iOS App Store ID or an Android
+// package name
+func ValidateAppBundle(bundle string) error {
+	if !IsIOSBundle(bundle) && !androidBundlePattern.MatchString(bundle) {
+		return fmt.Errorf("bundle must be an App Store ID or a package name such as com.example.app")
+	}
+	return nil
+}
+
+// ValidateApp checks the app settings of a mobile app placement
+func ValidateApp(app *App) error {
+	if err := ValidateAppBundle(app.Bundle); err != nil {
+		return err
+	}
+
+	if app.StoreURL != "" {
+		if err := ValidateStoreURL(app.StoreURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
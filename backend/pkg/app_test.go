@@ -0,0 +1,50 @@
+package ssp
+
+import "testing"
+
+func TestValidateStoreURL(t *testing.T) {
+	valid := []string{
+		"https://apps.apple.com/us/app/example/id1234567890",
+		"https://itunes.apple.com/app/id1234567890",
+		"https://play.google.com/store/apps/details?id=com.example.app",
+	}
+	for _, storeURL := range valid {
+		if err := ValidateStoreURL(storeURL); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", storeURL, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"play.google.com/store/apps/details?id=com.example.app",
+		"ftp://play.google.com/store",
+		"https://example.com/app",
+		"https://play.google.com.example.com/store",
+	}
+	for _, storeURL := range invalid {
+		if err := ValidateStoreURL(storeURL); err == nil {
+			t.Errorf("Expected %q to be rejected", storeURL)
+		}
+	}
+}
+
+func TestValidateAppBundle(t *testing.T) {
+	for _, bundle := range []string{"1234567890", "id1234567890", "com.example.app", "com.example_co.game2"} {
+		if err := ValidateAppBundle(bundle); err != nil {
+			t.Errorf("Expected bundle %q to be valid, got %v", bundle, err)
+		}
+	}
+
+	for _, bundle := range []string{"", "example", "com..example", "com.example.", "1com.example", "https://example.com"} {
+		if err := ValidateAppBundle(bundle); err == nil {
+			t.Errorf("Expected bundle %q to be rejected", bundle)
+		}
+	}
+
+	if err := ValidateApp(&App{Bundle: "com.example.app", StoreURL: "https://example.com"}); err == nil {
+		t.Error("Expected app with an arbitrary store URL to be rejected")
+	}
+	if err := ValidateApp(&App{Bundle: "com.example.app"}); err != nil {
+		t.Errorf("Expected app without a store URL to be valid, got %v", err)
+	}
+}
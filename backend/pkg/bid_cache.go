@@ -0,0 +1,218 @@
+package ssp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBidExp is how long a won bid stays valid when it does not set exp
+const DefaultBidExp = 300 * time.Second
+
+// Impression pixel timestamp bounds
+const (
+	MaxImpressionAge       = 5 * time.Minute  // Older pixels are treated as replays
+	MaxImpressionClockSkew = 30 * time.Second // Allowance for client clocks running ahead
+)
+
+// ErrImpressionReplay is returned for impression timestamps outside the accepted window
+var ErrImpressionReplay = errors.New("impression timestamp outside accepted window")
+
+// CheckImpressionTimestamp validates an impression pixel timestamp in Unix
+// milliseconds. Pixels older than MaxImpressionAge, or further in the future
+// than MaxImpressionClockSkew, are rejected with ErrImpressionReplay.
+func CheckImpressionTimestamp(tsMillis int64, now time.Time) error {
+	ts := time.UnixMilli(tsMillis)
+	if now.Sub(ts) > MaxImpressionAge || ts.Sub(now) > MaxImpressionClockSkew {
+		return ErrImpressionReplay
+	}
+	return nil
+}
+
+// ImpressionSigner signs the served ID and ts of impression pixels, so a
+// client cannot refresh the timestamp of a captured pixel
+type ImpressionSigner struct {
+	key []byte
+}
+
+// NewImpressionSigner creates a signer keyed with key, or with a random key
+// when key is empty. A random key is enough while the bid cache, which holds
+// the bids pixels are verified against, is per replica.
+func NewImpressionSigner(key []byte) (*ImpressionSigner, error) {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &ImpressionSigner{key: key}, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of the served ID and ts (Unix milliseconds).
+// A nil signer returns "".
+func (s *ImpressionSigner) Sign(servedID string, tsMillis int64) string {
+	if s == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(servedID + "." + strconv.FormatInt(tsMillis, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether sig is the signature of the served ID and ts
+func (s *ImpressionSigner) Verify(servedID string, tsMillis int64, sig string) bool {
+	return sig != "" && hmac.Equal([]byte(sig), []byte(s.Sign(servedID, tsMillis)))
+}
+
+// bidCachePurgeInterval is how often Put sweeps expired entries from the cache
+const bidCachePurgeInterval = time.Minute
+
+// BidCacheEntry is a won bid awaiting its impression
+type BidCacheEntry struct {
+	Bid         *Bid
+	PlacementID string
+	ExpiresAt   time.Time
+
+	// ServedID keys the entry and is carried by the bid's pixels in place of
+	// the DSP's bid ID, which is not unique across partners or auctions
+	ServedID string
+
+	// Auction context logged with the bid's impression
+	RequestID        string
+	SiteID           string
+	PublisherID      string
+	PartnerID        string
+	UserID           string
+	ClearedPrice     float64 // CPM
+	PublisherRevenue float64 // CPM
+
+	// The site's impression cap when the bid won, so impressions are capped
+	// without loading the site
+	ImpressionCap *FrequencyCap
+
+	impressed bool // The bid's impression was counted
+	rewarded  bool // The bid's rewarded video completion was counted
+}
+
+// Expired reports whether the impression window for the bid has passed
+func (e *BidCacheEntry) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// BidCache holds won bids in memory until they expire, so their impression and
+// rewarded completion pixels can be verified
+type BidCache struct {
+	mu        sync.Mutex
+	entries   map[string]*BidCacheEntry
+	lastPurge time.Time
+}
+
+// NewBidCache creates an empty bid cache
+func NewBidCache() *BidCache {
+	return &BidCache{
+		entries:   make(map[string]*BidCacheEntry),
+		lastPurge: time.Now(),
+	}
+}
+
+// Put caches a won bid. The entry expires after bid.Exp seconds, or
+// DefaultBidExp when the bid does not set it.
+func (c *BidCache) Put(bid *Bid, placementID string) *BidCacheEntry {
+	return c.PutEntry(&BidCacheEntry{Bid: bid, PlacementID: placementID})
+}
+
+// PutEntry caches a won bid with its auction context, setting the entry's
+// expiry as Put does. A ServedID is generated when the entry has none.
+func (c *BidCache) PutEntry(entry *BidCacheEntry) *BidCacheEntry {
+	now := time.Now()
+	exp := DefaultBidExp
+	if entry.Bid.Exp > 0 {
+		exp = time.Duration(entry.Bid.Exp) * time.Second
+	}
+	entry.ExpiresAt = now.Add(exp)
+	if entry.ServedID == "" {
+		entry.ServedID = uuid.New().String()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[entry.ServedID] = entry
+	if now.Sub(c.lastPurge) >= bidCachePurgeInterval {
+		c.purge(now)
+	}
+
+	return entry
+}
+
+// Get returns the cached entry for a served ID, including expired entries that
+// have not been swept yet
+func (c *BidCache) Get(servedID string) (*BidCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[servedID]
+	return entry, ok
+}
+
+// ClaimImpression marks the bid's impression as counted and returns its
+// entry. It reports false for unknown bids and bids whose impression was
+// already counted. The entry stays cached until it expires so a rewarded
+// video completion can still be verified.
+func (c *BidCache) ClaimImpression(servedID string) (*BidCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[servedID]
+	if !ok || entry.impressed {
+		return nil, false
+	}
+	entry.impressed = true
+	return entry, true
+}
+
+// ClaimReward marks the bid's rewarded video completion as counted and returns
+// its entry. It reports false for unknown bids, bids served on another
+// placement and bids already rewarded.
+func (c *BidCache) ClaimReward(servedID, placementID string) (*BidCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[servedID]
+	if !ok || entry.PlacementID != placementID || entry.rewarded {
+		return nil, false
+	}
+	entry.rewarded = true
+	return entry, true
+}
+
+// Invalidate drops the cached entry for a served ID
+func (c *BidCache) Invalidate(servedID string) {
+	c.mu.Lock()
+	delete(c.entries, servedID)
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached entries
+func (c *BidCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// purge removes expired entries; the caller must hold c.mu
+func (c *BidCache) purge(now time.Time) {
+	for id, entry := range c.entries {
+		if entry.Expired(now) {
+			delete(c.entries, id)
+		}
+	}
+	c.lastPurge = now
+}
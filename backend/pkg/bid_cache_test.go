@@ -0,0 +1,155 @@
+package ssp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBidCacheExpiry(t *testing.T) {
+	cache := NewBidCache()
+
+	entry := cache.Put(&Bid{ID: "bid-1"}, "placement-1")
+	if got := time.Until(entry.ExpiresAt); got <= DefaultBidExp-time.Second || got > DefaultBidExp {
+		t.Errorf("Expected default expiry of %v, got %v", DefaultBidExp, got)
+	}
+
+	entry = cache.Put(&Bid{ID: "bid-2", Exp: 30}, "placement-1")
+	if got := time.Until(entry.ExpiresAt); got <= 29*time.Second || got > 30*time.Second {
+		t.Errorf("Expected expiry of 30s, got %v", got)
+	}
+
+	if entry.Expired(time.Now()) {
+		t.Error("Expected fresh entry not to be expired")
+	}
+	if !entry.Expired(time.Now().Add(31 * time.Second)) {
+		t.Error("Expected entry to be expired after exp")
+	}
+
+	if _, ok := cache.Get(entry.ServedID); !ok {
+		t.Fatal("Expected bid-2 to be cached")
+	}
+
+	cache.Invalidate(entry.ServedID)
+	if _, ok := cache.Get(entry.ServedID); ok {
+		t.Error("Expected bid-2 to be invalidated")
+	}
+}
+
+func TestBidCacheKeyedByServedID(t *testing.T) {
+	cache := NewBidCache()
+
+	// DSP bid IDs are only unique per DSP, so the same ID can win twice
+	first := cache.Put(&Bid{ID: "1"}, "placement-1")
+	second := cache.Put(&Bid{ID: "1"}, "placement-2")
+	if first.ServedID == "" || first.ServedID == second.ServedID {
+		t.Fatalf("Expected distinct served IDs, got %q and %q", first.ServedID, second.ServedID)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected both bids cached, got %d", cache.Len())
+	}
+	if _, ok := cache.ClaimImpression("1"); ok {
+		t.Error("Expected the DSP bid ID not to claim an impression")
+	}
+
+	entry := cache.PutEntry(&BidCacheEntry{Bid: &Bid{ID: "2"}, ServedID: "served-1"})
+	if entry.ServedID != "served-1" {
+		t.Errorf("Expected a given served ID to be kept, got %s", entry.ServedID)
+	}
+}
+
+func TestBidCacheClaims(t *testing.T) {
+	cache := NewBidCache()
+	id := cache.Put(&Bid{ID: "bid-1"}, "placement-1").ServedID
+
+	if _, ok := cache.ClaimImpression(id); !ok {
+		t.Fatal("Expected first impression to be claimed")
+	}
+	if _, ok := cache.ClaimImpression(id); ok {
+		t.Error("Expected repeated impression to be rejected")
+	}
+	if _, ok := cache.ClaimImpression("bid-unknown"); ok {
+		t.Error("Expected impression for unknown bid to be rejected")
+	}
+
+	if _, ok := cache.ClaimReward(id, "placement-2"); ok {
+		t.Error("Expected reward on another placement to be rejected")
+	}
+	if _, ok := cache.ClaimReward(id, "placement-1"); !ok {
+		t.Fatal("Expected reward to be claimed after the impression")
+	}
+	if _, ok := cache.ClaimReward(id, "placement-1"); ok {
+		t.Error("Expected repeated reward to be rejected")
+	}
+}
+
+func TestBidCachePurge(t *testing.T) {
+	cache := NewBidCache()
+	expiring := cache.Put(&Bid{ID: "bid-1", Exp: 1}, "placement-1")
+	cache.Put(&Bid{ID: "bid-2"}, "placement-1")
+
+	cache.mu.Lock()
+	cache.purge(time.Now().Add(2 * time.Second))
+	cache.mu.Unlock()
+
+	if _, ok := cache.Get(expiring.ServedID); ok {
+		t.Error("Expected expired bid-1 to be purged")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cached entry, got %d", cache.Len())
+	}
+}
+
+func TestCheckImpressionTimestamp(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		ts    time.Time
+		valid bool
+	}{
+		{"fresh", now.Add(-10 * time.Second), true},
+		{"slightly in the future", now.Add(10 * time.Second), true},
+		{"older than five minutes", now.Add(-6 * time.Minute), false},
+		{"too far in the future", now.Add(time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		err := CheckImpressionTimestamp(tt.ts.UnixMilli(), now)
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid timestamp, got %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrImpressionReplay) {
+			t.Errorf("%s: expected ErrImpressionReplay, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestImpressionSigner(t *testing.T) {
+	signer, err := NewImpressionSigner([]byte("pixel-key"))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	sig := signer.Sign("served-1", 1700000000000)
+	if !signer.Verify("served-1", 1700000000000, sig) {
+		t.Error("Expected own signature to verify")
+	}
+	if signer.Verify("served-1", 1700000060000, sig) {
+		t.Error("Expected signature not to verify with a refreshed ts")
+	}
+	if signer.Verify("served-2", 1700000000000, sig) {
+		t.Error("Expected signature not to verify for another bid")
+	}
+	if signer.Verify("served-1", 1700000000000, "") {
+		t.Error("Expected a missing signature to be rejected")
+	}
+
+	other, err := NewImpressionSigner(nil)
+	if err != nil {
+		t.Fatalf("Failed to create signer with a random key: %v", err)
+	}
+	if other.Verify("served-1", 1700000000000, sig) {
+		t.Error("Expected signatures to depend on the key")
+	}
+}
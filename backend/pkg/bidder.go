@@ -4,9 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,14 +29,17 @@ type Bidder struct {
 // NewBidder creates a new bidder instance
 func NewBidder(sspID string, timeout time.Duration) *Bidder {
 	return &Bidder{
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:  &http.Client{},
 		sspID:   sspID,
 		timeout: timeout,
 	}
 }
 
+// Timeout returns the default partner bid timeout
+func (b *Bidder) Timeout() time.Duration {
+	return b.timeout
+}
+
 // DemandPartner represents a demand-side partner (DSP, ADX, exchange)
 type DemandPartner struct {
 	ID       string
@@ -39,10 +49,21 @@ type DemandPartner struct {
 	Active   bool
 	QPS      int     // Queries per second limit
 	RevShare float64 // SSP revenue share (0.0-1.0)
+	Priority int     // Auction tie-breaker: lower value wins ties
+
+	// Authenticate adds the partner's credentials to a request; nil sends it unauthenticated
+	Authenticate func(req *http.Request, body []byte)
 }
 
-// SendBidRequest sends a bid request to a demand partner
+// SendBidRequest sends a bid request to a demand partner. The request is
+// bounded by the context deadline, or by the bidder timeout if there is none.
 func (b *Bidder) SendBidRequest(ctx context.Context, bidReq *BidRequest, partner *DemandPartner) (*BidResponse, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+
 	// Marshal bid request
 	reqBody, err := json.Marshal(bidReq)
 	if err != nil {
@@ -57,6 +78,9 @@ func (b *Bidder) SendBidRequest(ctx context.Context, bidReq *BidRequest, partner
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-OpenRTB-Version", "2.5")
+	if partner.Authenticate != nil {
+		partner.Authenticate(req, reqBody)
+	}
 
 	// Send request
 	resp, err := b.client.Do(req)
@@ -83,15 +107,60 @@ func (b *Bidder) SendBidRequest(ctx context.Context, bidReq *BidRequest, partner
 
 	var bidResp BidResponse
 	if err := json.Unmarshal(respBody, &bidResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal bid response: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBidResponse, err)
 	}
 
 	return &bidResp, nil
 }
 
+// userIDProviders maps AdRequest.UserIDs keys to their EID source and agent type
+var userIDProviders = map[string]struct {
+	source string
+	atype  int
+}{
+	"uid2":     {source: "uidapi.com", atype: 3},
+	"liveramp": {source: "liveramp.com", atype: 3},
+	"id5":      {source: "id5-sync.com", atype: 1},
+}
+
+// buildEIDs converts user IDs into OpenRTB extended identifiers, ordered by
+// provider key. Unknown providers and empty IDs are skipped.
+func buildEIDs(userIDs map[string]string) []EID {
+	keys := make([]string, 0, len(userIDs))
+	for key := range userIDs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	eids := []EID{}
+	for _, key := range keys {
+		provider, ok := userIDProviders[key]
+		if !ok || userIDs[key] == "" {
+			continue
+		}
+		eids = append(eids, EID{
+			Source: provider.source,
+			UIDs:   []UID{{ID: userIDs[key], AType: provider.atype}},
+		})
+	}
+
+	return eids
+}
+
+// contentRatingBlockedAttrs maps a site content rating to the OpenRTB creative
+// attributes (list 5.3) blocked on its banners
+var contentRatingBlockedAttrs = map[string][]int{
+	ContentRatingG:    {8, 9, 10}, // Pop, provocative or suggestive imagery, flashing animation
+	ContentRatingPG:   {9, 10},    // Provocative or suggestive imagery, flashing animation
+	ContentRatingPG13: {9},        // Provocative or suggestive imagery
+}
+
 // BidRequestBuilder builds OpenRTB 2.5 bid requests from placements
 type BidRequestBuilder struct {
 	sspID string
+
+	SKAdNetworkIDs []string            // SKAdNetwork IDs supported for iOS app attribution
+	SupplyChain    *SupplyChainBuilder // Optional; adds source.ext.schain and source.pchain
 }
 
 // NewBidRequestBuilder creates a new bid request builder
@@ -103,19 +172,150 @@ func NewBidRequestBuilder(sspID string) *BidRequestBuilder {
 
 // AdRequest represents an incoming ad request from publisher
 type AdRequest struct {
-	PlacementID string
-	URL         string
-	Referer     string
-	UserAgent   string
-	IP          string
-	Width       int
-	Height      int
+	PlacementID   string
+	URL           string
+	Referer       string
+	UserAgent     string
+	IP            string
+	Width         int
+	Height        int
+	Geo           *Geo              // IP-derived location, set by geo enrichment
+	UserIDs       map[string]string // Third-party user IDs keyed by provider, e.g. "uid2", "liveramp"
+	GDPRApplies   int               // 1 when GDPR applies to the user
+	GDPRConsent   string            // TCF v2 consent string, checked when GDPR applies
+	USPrivacy     string            // CCPA US Privacy string, e.g. "1YNN"
+	COPPA         int               // 1 when the request is subject to COPPA
+	Proto         string            // Scheme the ad request arrived on (X-Forwarded-Proto)
+	UserID        string            // SSP user ID from the first-party cookie
+	BuyerUIDs     map[string]string // Synced partner user IDs keyed by partner ID; see WithBuyerUID
+	BCat          []string          // Categories blocked by the publisher, site and placement; see GetEffectiveBlockedCategories
+	RequestOrigin string            // RequestOriginServer or RequestOriginHeaderBidding; empty is treated as server
 	// Additional params
 	Params map[string]interface{}
 }
 
-// BuildBidRequest builds an OpenRTB 2.5 bid request from ad request and placement
-func (b *BidRequestBuilder) BuildBidRequest(adReq *AdRequest, placement *Placement, site *Site, pub *Publisher) (*BidRequest, error) {
+// UserIDsAllowed reports whether user identifiers (user.id, buyeruid and
+// EIDs) may be sent to bidders: never under COPPA, and otherwise only with
+// the consent user sync requires
+func (r *AdRequest) UserIDsAllowed() bool {
+	return r.COPPA != 1 && SyncConsented(r.GDPRApplies == 1, r.GDPRConsent, r.USPrivacy)
+}
+
+// SetPrivacyFrom takes the COPPA, GDPR and CCPA signals of an inbound OpenRTB
+// request, so user identifiers are gated on what the caller sent. The consent
+// string is read from user.consent or, as Prebid sends it, user.ext.consent.
+func (r *AdRequest) SetPrivacyFrom(bidReq *BidRequest) {
+	if bidReq.Regs != nil {
+		r.COPPA = bidReq.Regs.Coppa
+		var ext regsExt
+		if data, err := json.Marshal(bidReq.Regs.Ext); err == nil && json.Unmarshal(data, &ext) == nil {
+			r.GDPRApplies = ext.GDPR
+			r.USPrivacy = ext.USPrivacy
+		}
+	}
+	if bidReq.User != nil {
+		r.GDPRConsent = bidReq.User.Consent
+		var ext struct {
+			Consent string `json:"consent"`
+		}
+		if data, err := json.Marshal(bidReq.User.Ext); r.GDPRConsent == "" && err == nil && json.Unmarshal(data, &ext) == nil {
+			r.GDPRConsent = ext.Consent
+		}
+	}
+}
+
+// Ad request origins
+const (
+	RequestOriginServer        = "server"         // Direct ad request; the SSP is the final destination
+	RequestOriginHeaderBidding = "header_bidding" // Prebid call; the browser picks the final winner
+)
+
+// SourceFD returns the OpenRTB source.fd flag for the request origin. Header
+// bidding responses go back to Prebid, so the SSP is not the final destination.
+func (r *AdRequest) SourceFD() int {
+	if r.RequestOrigin == RequestOriginHeaderBidding {
+		return 0
+	}
+	return 1
+}
+
+// IsSecure reports whether the ad will render on an HTTPS page. The page URL
+// scheme decides; without one, X-Forwarded-Proto does. Unknown is treated as
+// secure since most publisher pages are served over HTTPS.
+func (r *AdRequest) IsSecure() bool {
+	switch {
+	case strings.HasPrefix(r.URL, "https://"):
+		return true
+	case strings.HasPrefix(r.URL, "http://"):
+		return false
+	}
+	return !strings.EqualFold(r.Proto, "http")
+}
+
+// regsExt is the regs.ext object carrying GDPR and CCPA signals
+type regsExt struct {
+	GDPR      int    `json:"gdpr,omitempty"`
+	USPrivacy string `json:"us_privacy,omitempty"`
+}
+
+// buildRegsExt returns the regs.ext object for the request's privacy signals,
+// or nil when neither GDPR nor CCPA applies
+func buildRegsExt(adReq *AdRequest) json.RawMessage {
+	ext := regsExt{USPrivacy: adReq.USPrivacy}
+	if adReq.GDPRApplies == 1 {
+		ext.GDPR = 1
+	}
+	if ext == (regsExt{}) {
+		return nil
+	}
+
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Bid request tmax bounds in milliseconds
+const (
+	defaultTmax = 120 // Used when the request context has no deadline
+	minTmax     = 50  // Floor for tmax derived from a nearly expired deadline
+)
+
+// tmaxFromContext returns the bid request tmax in milliseconds. With a context
+// deadline, tmax is the time remaining (at least minTmax) so DSPs are never
+// given longer to respond than the caller will wait.
+func tmaxFromContext(ctx context.Context) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return defaultTmax
+	}
+	return max(int(time.Until(deadline).Milliseconds()), minTmax)
+}
+
+// deviceGeo returns the device location sent to DSPs for geo targeting, from
+// the request's IP-derived location
+func deviceGeo(geo *Geo) *Geo {
+	if geo == nil {
+		return nil
+	}
+
+	return &Geo{
+		Type:    2, // IP address
+		Country: geo.Country,
+		Region:  geo.Region,
+		City:    geo.City,
+		ZIP:     geo.ZIP,
+	}
+}
+
+// ErrNoEligibleFormats is returned when every banner format of a placement is
+// below its minimum size, leaving nothing to offer bidders
+var ErrNoEligibleFormats = errors.New("no banner format meets the placement minimum size")
+
+// BuildBidRequest builds an OpenRTB 2.5 bid request from ad request and placement.
+// tmax follows the remaining ctx deadline when there is one.
+func (b *BidRequestBuilder) BuildBidRequest(ctx context.Context, adReq *AdRequest, placement *Placement, site *Site, pub *Publisher) (*BidRequest, error) {
 	// Generate request ID
 	reqID := uuid.New().String()
 
@@ -125,7 +325,9 @@ func (b *BidRequestBuilder) BuildBidRequest(adReq *AdRequest, placement *Placeme
 		TagID:       placement.ID,
 		BidFloor:    placement.MinBidFloor,
 		BidFloorCur: "USD",
-		Secure:      1, // Assume HTTPS
+	}
+	if adReq.IsSecure() {
+		imp.Secure = 1
 	}
 
 	// Add impression type based on ad type
@@ -134,15 +336,55 @@ func (b *BidRequestBuilder) BuildBidRequest(adReq *AdRequest, placement *Placeme
 		imp.Banner = b.buildBanner(placement)
 	case "video":
 		imp.Video = b.buildVideo(placement)
+	case AdTypeRewardedVideo:
+		// Rewarded video must be watched in full and plays before content
+		imp.Video = b.buildVideo(placement)
+		notSkippable := 0
+		imp.Video.Skip = &notSkippable
+		imp.Video.StartDelay = 0
+		imp.Ext = map[string]interface{}{"rewarded": 1}
 	case "native":
 		imp.Native = &Native{
 			Request: `{"ver":"1.2"}`, // Placeholder
 			Ver:     "1.2",
 		}
+	case AdTypeDOOH:
+		// Out-of-home screens render static display creatives
+		imp.Banner = b.buildBanner(placement)
+		if placement.ScreenMetadata != nil {
+			imp.Ext = map[string]interface{}{"dooh": placement.ScreenMetadata}
+		}
 	default:
 		return nil, fmt.Errorf("unsupported ad type: %s", placement.AdType)
 	}
 
+	if imp.Banner != nil && len(imp.Banner.Format) == 0 && len(placement.Formats) > 0 {
+		return nil, ErrNoEligibleFormats
+	}
+
+	if placement.Interstitial {
+		imp.Instl = 1
+	}
+
+	// Brand safety: block creative attributes unsuitable for the site's rating
+	if imp.Banner != nil {
+		if attrs := contentRatingBlockedAttrs[site.ContentRating]; len(attrs) > 0 {
+			imp.Banner.BAttr = append([]int{}, attrs...)
+		}
+	}
+
+	// Attach placement deals; DealType and pacing are SSP-internal and stripped
+	if len(placement.Deals) > 0 {
+		deals := make([]Deal, len(placement.Deals))
+		for i, deal := range placement.Deals {
+			deal.DealType = ""
+			deal.DailyBudget = 0
+			deal.DeliveryPacing = 0
+			deals[i] = deal
+		}
+		imp.PMP = &PMP{Deals: deals}
+	}
+
 	// Build site object
 	siteInfo := &SiteInfo{
 		ID:     site.ID,
@@ -158,10 +400,15 @@ func (b *BidRequestBuilder) BuildBidRequest(adReq *AdRequest, placement *Placeme
 		},
 	}
 
+	if site.ContentRating != "" {
+		siteInfo.Content = &Content{ContentRating: site.ContentRating}
+	}
+
 	// Build device object
 	device := &Device{
-		UA: adReq.UserAgent,
-		IP: adReq.IP,
+		UA:  adReq.UserAgent,
+		IP:  adReq.IP,
+		Geo: deviceGeo(adReq.Geo),
 	}
 
 	// Build bid request
@@ -170,27 +417,119 @@ func (b *BidRequestBuilder) BuildBidRequest(adReq *AdRequest, placement *Placeme
 		Imp:    []Impression{imp},
 		Site:   siteInfo,
 		Device: device,
-		At:     2,   // Second price auction
-		Tmax:   120, // 120ms timeout
+		At:     2, // Second price auction unless the placement overrides it
+		Tmax:   tmaxFromContext(ctx),
 		Cur:    []string{"USD"},
 		Source: &Source{
-			FD:  1, // Final destination
+			FD:  adReq.SourceFD(),
 			TID: uuid.New().String(),
 		},
 	}
 
+	// Buyer UIDs differ per partner and are set by WithBuyerUID when sending
+	if adReq.UserIDsAllowed() {
+		if eids := buildEIDs(adReq.UserIDs); len(eids) > 0 {
+			bidReq.User = &User{Ext: &UserExt{EIDs: eids}}
+		}
+		if adReq.UserID != "" {
+			if bidReq.User == nil {
+				bidReq.User = &User{}
+			}
+			bidReq.User.ID = adReq.UserID
+		}
+	}
+
+	if placement.AuctionType != 0 {
+		bidReq.At = placement.AuctionType
+	}
+
+	// COPPA is a top-level regs flag; GDPR and CCPA travel in regs.ext
+	if ext := buildRegsExt(adReq); adReq.COPPA == 1 || ext != nil {
+		bidReq.Regs = &Regs{Coppa: adReq.COPPA}
+		if ext != nil {
+			bidReq.Regs.Ext = ext
+		}
+	}
+
+	if site.Language != "" {
+		bidReq.WLang = []string{site.Language}
+	}
+
+	if len(placement.BlockedDomains) > 0 {
+		bidReq.BAdv = append([]string{}, placement.BlockedDomains...)
+	}
+
+	if len(adReq.BCat) > 0 {
+		bidReq.BCat = append([]string{}, adReq.BCat...)
+	}
+
+	if b.SupplyChain != nil {
+		schain, err := b.SupplyChain.BuildForPublisher(pub.ID, pub.Domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build supply chain: %w", err)
+		}
+		if err := AddToSource(bidReq.Source, schain); err != nil {
+			return nil, err
+		}
+		bidReq.Source.PChain = b.SupplyChain.ToPChainString(schain)
+	}
+
+	// App inventory is sold as app in place of site, under the same publisher
+	if placement.App != nil && placement.AdType != AdTypeDOOH {
+		app := *placement.App
+		app.Publisher = siteInfo.Publisher
+		if len(app.Cat) == 0 {
+			app.Cat = site.Cat
+		}
+		bidReq.App = &app
+		bidReq.Site = nil
+	}
+
+	// A DOOH screen is not a website; OpenRTB 2.6 sends dooh in place of site
+	if placement.AdType == AdTypeDOOH {
+		bidReq.DOOH = buildDOOH(placement, site, pub)
+		bidReq.Site = nil
+	}
+
+	b.ApplySKAdNetwork(bidReq)
+
 	return bidReq, nil
 }
 
+// buildDOOH builds the OpenRTB 2.6 dooh object from placement venue metadata
+func buildDOOH(placement *Placement, site *Site, pub *Publisher) *DOOHInfo {
+	dooh := &DOOHInfo{
+		ID:     placement.ID,
+		Name:   placement.Name,
+		Domain: site.Domain,
+		Publisher: &Publisher2{
+			ID:     pub.ID,
+			Name:   pub.Name,
+			Domain: pub.Domain,
+		},
+	}
+
+	if placement.DOOH != nil {
+		dooh.VenueType = placement.DOOH.VenueType
+		dooh.VenueTypeTax = placement.DOOH.VenueTypeTax
+	}
+
+	return dooh
+}
+
 func (b *BidRequestBuilder) buildBanner(placement *Placement) *Banner {
 	banner := &Banner{
 		ID:  placement.ID,
 		Pos: 1, // Above the fold
 	}
 
-	// Add formats
+	// Add formats, leaving out sizes below the placement minimum
 	if len(placement.Formats) > 0 {
-		banner.Format = placement.Formats
+		for _, format := range placement.Formats {
+			if format.W >= placement.MinWidth && format.H >= placement.MinHeight {
+				banner.Format = append(banner.Format, format)
+			}
+		}
 	} else if placement.Width > 0 && placement.Height > 0 {
 		banner.W = placement.Width
 		banner.H = placement.Height
@@ -204,9 +543,10 @@ func (b *BidRequestBuilder) buildBanner(placement *Placement) *Banner {
 
 func (b *BidRequestBuilder) buildVideo(placement *Placement) *Video {
 	video := &Video{
-		W:   placement.Width,
-		H:   placement.Height,
-		Pos: 1,
+		W:         placement.Width,
+		H:         placement.Height,
+		Pos:       1,
+		Placement: videoPlacementTypes[placement.PlacementType],
 	}
 
 	// Add video settings if available
@@ -219,6 +559,9 @@ func (b *BidRequestBuilder) buildVideo(placement *Placement) *Video {
 		video.StartDelay = placement.Video.StartDelay
 		video.PlaybackMethod = placement.Video.PlaybackMethod
 		video.API = placement.Video.API
+		for _, companion := range placement.Video.CompanionAds {
+			video.CompanionAd = append(video.CompanionAd, Banner{W: companion.W, H: companion.H})
+		}
 	} else {
 		// Default video settings
 		video.Mimes = []string{"video/mp4", "video/webm"}
@@ -234,13 +577,99 @@ func (b *BidRequestBuilder) buildVideo(placement *Placement) *Video {
 // AuctionEngine handles auction logic for bid responses
 type AuctionEngine struct {
 	minBidFloor float64
+
+	AuctionType       int     // 1=first price, 2=second price
+	BidShadingEnabled bool    // Shade bids in first-price auctions
+	BidShadingFactor  float64 // Multiplier applied to bid prices when shading (e.g. 0.85)
+
+	// FloorRelaxation is how far (as a fraction) the highest bid may fall short
+	// of the floor and still win on a placement below its MinFillRate
+	FloorRelaxation float64
+	// OnFloorRelaxed is called whenever an auction clears on a relaxed floor
+	OnFloorRelaxed func()
+	// DealPacer paces guaranteed deals with a DailyBudget; nil disables pacing
+	DealPacer *DealPacer
+	// OnDealPacingSkip is called for each guaranteed deal bid skipped by pacing
+	OnDealPacingSkip func(dealID string)
+	// CurrencyWhitelist lists the response currencies bids may be priced in;
+	// empty accepts any currency that has an exchange rate
+	CurrencyWhitelist []string
+	// ExchangeRates converts bids to DefaultCurrency before the auction, in
+	// units of DefaultCurrency per unit of the keyed (upper-case) currency.
+	// Bids in other currencies without a rate are rejected.
+	ExchangeRates map[string]float64
+	// OnBidRejected is called for each bid rejected before the auction, with
+	// the metric reason (e.g. "currency")
+	OnBidRejected func(reason string)
+	// OnBidsBelowFloor is called with the number of open-auction bids under the
+	// placement floor, for every auction that had any
+	OnBidsBelowFloor func(count int)
+	// Logger receives debug logs for bids filtered out of the auction; nil disables them
+	Logger *slog.Logger
+
+	fillMu    sync.Mutex
+	fillStats map[string]*placementFill
 }
 
-// NewAuctionEngine creates a new auction engine
+// FillRateHalfLife is how long it takes an auction's weight in a placement's
+// observed fill rate to halve, so the rate follows recent demand rather than
+// the placement's whole history
+const FillRateHalfLife = time.Hour
+
+// placementFill tracks exponentially decayed auction outcomes for a placement
+// with a minimum fill rate
+type placementFill struct {
+	auctions  float64
+	fills     float64
+	updatedAt time.Time
+}
+
+// relaxedFloorFactor sets the effective floor relative to the highest bid when relaxing
+const relaxedFloorFactor = 0.9
+
+// DefaultCurrency is the currency bid requests are priced in, and the
+// OpenRTB default for responses that omit cur
+const DefaultCurrency = "USD"
+
+// NewAuctionEngine creates a new auction engine running second-price auctions
+// that accepts bids in DefaultCurrency
 func NewAuctionEngine(minBidFloor float64) *AuctionEngine {
 	return &AuctionEngine{
-		minBidFloor: minBidFloor,
+		minBidFloor:       minBidFloor,
+		AuctionType:       2,
+		FloorRelaxation:   0.10,
+		CurrencyWhitelist: []string{DefaultCurrency},
+		fillStats:         make(map[string]*placementFill),
+	}
+}
+
+// exchangeRate returns the rate converting bids priced in a response currency
+// to DefaultCurrency. It reports false when the currency is not whitelisted
+// or has no exchange rate, so the bids may not enter the auction.
+func (ae *AuctionEngine) exchangeRate(cur string) (float64, bool) {
+	if cur == "" {
+		cur = DefaultCurrency
+	}
+	if len(ae.CurrencyWhitelist) > 0 && !slices.ContainsFunc(ae.CurrencyWhitelist, func(allowed string) bool {
+		return strings.EqualFold(allowed, cur)
+	}) {
+		return 0, false
 	}
+	if strings.EqualFold(cur, DefaultCurrency) {
+		return 1, true
+	}
+	rate, ok := ae.ExchangeRates[strings.ToUpper(cur)]
+	return rate, ok && rate > 0
+}
+
+// shadingActive reports whether bid shading applies to this engine's auctions
+func (ae *AuctionEngine) shadingActive() bool {
+	return ae.BidShadingEnabled && ae.AuctionType == 1 && ae.BidShadingFactor > 0
+}
+
+// MinBidFloor returns the network-wide minimum bid floor
+func (ae *AuctionEngine) MinBidFloor() float64 {
+	return ae.minBidFloor
 }
 
 // AuctionResult represents the result of an auction
@@ -250,71 +679,349 @@ type AuctionResult struct {
 	AllBids        []BidWithPartner
 	AuctionType    int // 1=first price, 2=second price
 	ClearedPrice   float64
+	OriginalPrice  float64 // Winning bid price as submitted
+	ShadedPrice    float64 // Winning bid price after shading (equals OriginalPrice when not shaded)
+	IsPG           bool    // Won by a programmatic guaranteed deal outside the auction
+	FloorRelaxed   bool    // Cleared on a floor lowered to meet the placement's MinFillRate
+	FloorStats     FloorPriceStats
+}
+
+// FloorPriceStats counts the open-auction bids that met or missed the
+// placement floor, so publishers can see what their floor is costing them
+type FloorPriceStats struct {
+	TotalBids      int
+	BidsAboveFloor int     // Bids at or above the floor
+	BidsBelowFloor int     // Bids filtered for being under the floor
+	MaxBidBelow    float64 // Highest bid price under the floor (0 when none)
 }
 
 // BidWithPartner combines a bid with its partner info
 type BidWithPartner struct {
 	Bid     *Bid
 	Partner *DemandPartner
+	Price   float64 // Price used for ranking, after any bid shading
 }
 
-// RunAuction runs a second-price auction on bid responses
+// RunAuction runs a first- or second-price auction on all bids in the responses
 func (ae *AuctionEngine) RunAuction(responses map[*DemandPartner]*BidResponse, placement *Placement) (*AuctionResult, error) {
+	return ae.RunAuctionForImpression(responses, placement, "")
+}
+
+// RunAuctionForImpression runs a first- or second-price auction on the bids for
+// one impression. Bids whose ImpID does not match impID are dropped so a bid for
+// another impression in the request cannot win; an empty impID accepts all bids.
+func (ae *AuctionEngine) RunAuctionForImpression(responses map[*DemandPartner]*BidResponse, placement *Placement, impID string) (*AuctionResult, error) {
 	allBids := []BidWithPartner{}
 
+	// Programmatic guaranteed deals bypass the auction entirely
+	pgDeals := make(map[string]Deal)
+	for _, deal := range placement.Deals {
+		if deal.DealType == DealTypeGuaranteed {
+			pgDeals[deal.ID] = deal
+			if ae.DealPacer != nil {
+				ae.DealPacer.SetBudget(deal.ID, deal.DailyBudget, deal.DeliveryPacing)
+			}
+		}
+	}
+	pgBids := []BidWithPartner{}
+
+	// Highest open-auction bid that did not meet the placement floor
+	var highestBelowFloor float64
+	var belowFloor []BidWithPartner
+
+	blocked := domainSet(placement.BlockedDomains)
+
 	// Collect all bids
 	for partner, response := range responses {
 		if response == nil {
 			continue
 		}
+		rate, currencyOK := ae.exchangeRate(response.Cur)
 
 		for _, seatBid := range response.SeatBid {
 			for _, bid := range seatBid.Bid {
-				// Filter by bid floor
-				if bid.Price >= placement.MinBidFloor {
-					allBids = append(allBids, BidWithPartner{
+				if !currencyOK {
+					ae.logFilteredBid(&bid, partner, placement, "unsupported_currency", "cur", response.Cur)
+					if ae.OnBidRejected != nil {
+						ae.OnBidRejected("currency")
+					}
+					continue
+				}
+				// bid is a copy, so the response keeps the DSP's original price
+				bid.Price *= rate
+
+				if impID != "" && bid.ImpID != impID {
+					ae.logFilteredBid(&bid, partner, placement, "imp_mismatch", "imp_id", bid.ImpID)
+					continue
+				}
+
+				if domain, ok := matchBlockedDomain(bid.ADomain, blocked); ok {
+					ae.logFilteredBid(&bid, partner, placement, "blocked_domain", "adomain", domain)
+					continue
+				}
+
+				if deal, ok := pgDeals[bid.DealID]; bid.DealID != "" && ok {
+					if reason, rejected := dealBidRejection(deal, seatBid.Seat, &bid); rejected {
+						ae.logFilteredBid(&bid, partner, placement, reason, "deal_id", deal.ID)
+						continue
+					}
+					pgBids = append(pgBids, BidWithPartner{
 						Bid:     &bid,
 						Partner: partner,
+						Price:   bid.Price,
 					})
+					continue
+				}
+
+				price := bid.Price
+				if ae.shadingActive() {
+					price *= ae.BidShadingFactor
+				}
+				candidate := BidWithPartner{
+					Bid:     &bid,
+					Partner: partner,
+					Price:   price,
+				}
+
+				// Filter by bid floor
+				if bid.Price >= placement.MinBidFloor {
+					allBids = append(allBids, candidate)
+				} else {
+					belowFloor = append(belowFloor, candidate)
+					highestBelowFloor = max(highestBelowFloor, bid.Price)
 				}
 			}
 		}
 	}
 
-	// No bids
-	if len(allBids) == 0 {
-		return nil, nil
+	floorStats := FloorPriceStats{
+		TotalBids:      len(allBids) + len(belowFloor),
+		BidsAboveFloor: len(allBids),
+		BidsBelowFloor: len(belowFloor),
+		MaxBidBelow:    highestBelowFloor,
+	}
+	if floorStats.BidsBelowFloor > 0 && ae.OnBidsBelowFloor != nil {
+		ae.OnBidsBelowFloor(floorStats.BidsBelowFloor)
+	}
+
+	sortBids(pgBids)
+	if winner, ok := ae.selectDealWinner(pgBids, placement); ok {
+		return &AuctionResult{
+			WinningBid:     winner.Bid,
+			WinningPartner: winner.Partner,
+			AllBids:        pgBids,
+			AuctionType:    ae.AuctionType,
+			ClearedPrice:   dealClearingPrice(pgDeals[winner.Bid.DealID], winner.Bid.Price),
+			OriginalPrice:  winner.Bid.Price,
+			ShadedPrice:    winner.Bid.Price,
+			IsPG:           true,
+			FloorStats:     floorStats,
+		}, nil
 	}
 
-	// Sort bids by price (descending)
-	for i := 0; i < len(allBids)-1; i++ {
-		for j := i + 1; j < len(allBids); j++ {
-			if allBids[j].Bid.Price > allBids[i].Bid.Price {
-				allBids[i], allBids[j] = allBids[j], allBids[i]
+	floor := placement.MinBidFloor
+	floorRelaxed := false
+	if len(allBids) == 0 && len(belowFloor) > 0 && ae.shouldRelaxFloor(placement, highestBelowFloor) {
+		// Relaxed floors still respect the network minimum
+		floor = max(highestBelowFloor*relaxedFloorFactor, ae.minBidFloor)
+		floorRelaxed = true
+		for _, candidate := range belowFloor {
+			if candidate.Bid.Price >= floor {
+				allBids = append(allBids, candidate)
 			}
 		}
+		if ae.OnFloorRelaxed != nil {
+			ae.OnFloorRelaxed()
+		}
+	}
+
+	if placement.MinFillRate > 0 {
+		ae.recordFill(placement.ID, len(allBids) > 0, time.Now())
 	}
 
+	// No bids
+	if len(allBids) == 0 {
+		return nil, nil
+	}
+
+	// Sort bids by price (descending), ties broken by partner priority
+	sortBids(allBids)
+
 	// Winner is highest bid
 	winner := allBids[0]
 
-	// Calculate cleared price (second price auction)
-	clearedPrice := placement.MinBidFloor
-	if len(allBids) > 1 {
-		// Second highest bid
-		clearedPrice = allBids[1].Bid.Price
+	clearedPrice := floor
+	if ae.AuctionType == 1 {
+		// First price: winner pays its own (possibly shaded) bid
+		clearedPrice = winner.Price
+	} else if len(allBids) > 1 {
+		// Second price: winner pays the second highest bid
+		clearedPrice = allBids[1].Price
 	}
 
 	// But never less than bid floor
-	if clearedPrice < placement.MinBidFloor {
-		clearedPrice = placement.MinBidFloor
+	if clearedPrice < floor {
+		clearedPrice = floor
 	}
 
 	return &AuctionResult{
 		WinningBid:     winner.Bid,
 		WinningPartner: winner.Partner,
 		AllBids:        allBids,
-		AuctionType:    2, // Second price
+		AuctionType:    ae.AuctionType,
 		ClearedPrice:   clearedPrice,
+		OriginalPrice:  winner.Bid.Price,
+		ShadedPrice:    winner.Price,
+		FloorRelaxed:   floorRelaxed,
+		FloorStats:     floorStats,
 	}, nil
 }
+
+// selectDealWinner returns the highest guaranteed deal bid whose deal is
+// within its pacing quota. Deal bids over quota are skipped.
+func (ae *AuctionEngine) selectDealWinner(pgBids []BidWithPartner, placement *Placement) (BidWithPartner, bool) {
+	for _, candidate := range pgBids {
+		dealID := candidate.Bid.DealID
+		if ae.DealPacer == nil {
+			return candidate, true
+		}
+		// The delivery is recorded when the impression is confirmed
+		if ae.DealPacer.ShouldDeliver(dealID) {
+			return candidate, true
+		}
+
+		ae.logFilteredBid(candidate.Bid, candidate.Partner, placement, "deal_pacing", "deal_id", dealID)
+		if ae.OnDealPacingSkip != nil {
+			ae.OnDealPacingSkip(dealID)
+		}
+	}
+	return BidWithPartner{}, false
+}
+
+// dealBidRejection reports why a bid may not take a deal: below the deal
+// floor, from a seat outside WSeat, or for an advertiser outside WADomain
+func dealBidRejection(deal Deal, seat string, bid *Bid) (string, bool) {
+	if bid.Price < deal.BidFloor {
+		return "deal_below_floor", true
+	}
+	if len(deal.WSeat) > 0 && !slices.Contains(deal.WSeat, seat) {
+		return "deal_seat", true
+	}
+	if len(deal.WADomain) > 0 {
+		allowed := domainSet(deal.WADomain)
+		if len(bid.ADomain) == 0 {
+			return "deal_adomain", true
+		}
+		for _, domain := range bid.ADomain {
+			if !allowed[strings.ToLower(strings.TrimSpace(domain))] {
+				return "deal_adomain", true
+			}
+		}
+	}
+	return "", false
+}
+
+// dealClearingPrice is the deal price, never more than the bid itself.
+// Deals without a floor clear at the bid price.
+func dealClearingPrice(deal Deal, bidPrice float64) float64 {
+	if deal.BidFloor <= 0 {
+		return bidPrice
+	}
+	return min(bidPrice, deal.BidFloor)
+}
+
+// domainSet normalizes advertiser domains, e.g. a placement's blocked domains, for lookup
+func domainSet(domains []string) map[string]bool {
+	if len(domains) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		set[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	return set
+}
+
+// matchBlockedDomain returns the first bid advertiser domain that is blocked
+func matchBlockedDomain(adomains []string, blocked map[string]bool) (string, bool) {
+	for _, domain := range adomains {
+		if blocked[strings.ToLower(strings.TrimSpace(domain))] {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// logFilteredBid logs a bid dropped from the auction and the reason it was dropped
+func (ae *AuctionEngine) logFilteredBid(bid *Bid, partner *DemandPartner, placement *Placement, reason string, attrs ...any) {
+	if ae.Logger == nil {
+		return
+	}
+	args := []any{"reason", reason, "bid_id", bid.ID, "placement_id", placement.ID}
+	if partner != nil {
+		args = append(args, "partner_id", partner.ID)
+	}
+	ae.Logger.Debug("Bid filtered from auction", append(args, attrs...)...)
+}
+
+// shouldRelaxFloor reports whether a placement running below its minimum fill
+// rate may clear on a lower floor. The highest bid must come within
+// FloorRelaxation of the placement floor.
+func (ae *AuctionEngine) shouldRelaxFloor(placement *Placement, highestBid float64) bool {
+	if placement.MinFillRate <= 0 || highestBid <= 0 {
+		return false
+	}
+	if highestBid*(1+ae.FloorRelaxation) < placement.MinBidFloor {
+		return false
+	}
+	return ae.FillRate(placement.ID) < placement.MinFillRate
+}
+
+// recordFill records at now whether an auction for a placement produced a
+// winner, first decaying the earlier outcomes by FillRateHalfLife
+func (ae *AuctionEngine) recordFill(placementID string, filled bool, now time.Time) {
+	ae.fillMu.Lock()
+	defer ae.fillMu.Unlock()
+
+	stats, ok := ae.fillStats[placementID]
+	if !ok {
+		stats = &placementFill{updatedAt: now}
+		ae.fillStats[placementID] = stats
+	}
+
+	if elapsed := now.Sub(stats.updatedAt); elapsed > 0 {
+		decay := math.Pow(0.5, float64(elapsed)/float64(FillRateHalfLife))
+		stats.auctions *= decay
+		stats.fills *= decay
+		stats.updatedAt = now
+	}
+	stats.auctions++
+	if filled {
+		stats.fills++
+	}
+}
+
+// FillRate returns the observed fill rate for a placement with a minimum fill
+// rate, weighted toward recent auctions, or 0 before any auctions have run.
+// Both counts decay alike, so the rate needs no decay when read.
+func (ae *AuctionEngine) FillRate(placementID string) float64 {
+	ae.fillMu.Lock()
+	defer ae.fillMu.Unlock()
+
+	stats, ok := ae.fillStats[placementID]
+	if !ok || stats.auctions == 0 {
+		return 0
+	}
+	return stats.fills / stats.auctions
+}
+
+// sortBids orders bids by price descending. Equal prices are ordered by
+// partner priority (lower first) so ties do not depend on map iteration order.
+func sortBids(bids []BidWithPartner) {
+	sort.SliceStable(bids, func(i, j int) bool {
+		if bids[i].Price != bids[j].Price {
+			return bids[i].Price > bids[j].Price
+		}
+		return bids[i].Partner.Priority < bids[j].Partner.Priority
+	})
+}
@@ -1,6 +1,11 @@
 package ssp
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -43,7 +48,7 @@ func TestBidRequestBuilder(t *testing.T) {
 		Height:      250,
 	}
 
-	bidReq, err := builder.BuildBidRequest(adReq, placement, site, publisher)
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
 	if err != nil {
 		t.Fatalf("Failed to build bid request: %v", err)
 	}
@@ -90,6 +95,266 @@ func TestBidRequestBuilder(t *testing.T) {
 	}
 }
 
+func TestBidRequestBuilderContentRating(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1", Name: "Test Publisher"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	site := &Site{ID: "site-1", Domain: "kids.example.com", ContentRating: ContentRatingG}
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	if bidReq.Site.Content == nil || bidReq.Site.Content.ContentRating != "G" {
+		t.Errorf("Expected content rating G, got %+v", bidReq.Site.Content)
+	}
+
+	blocked := map[int]bool{}
+	for _, attr := range bidReq.Imp[0].Banner.BAttr {
+		blocked[attr] = true
+	}
+	if !blocked[9] {
+		t.Errorf("Expected suggestive imagery (9) blocked for G rating, got %v", bidReq.Imp[0].Banner.BAttr)
+	}
+
+	site.ContentRating = ContentRatingR
+	bidReq, err = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	if len(bidReq.Imp[0].Banner.BAttr) != 0 {
+		t.Errorf("Expected no blocked attributes for R rating, got %v", bidReq.Imp[0].Banner.BAttr)
+	}
+}
+
+func TestBidRequestBuilderSourceFD(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1", Name: "Test Publisher"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	for origin, want := range map[string]int{"": 1, RequestOriginServer: 1, RequestOriginHeaderBidding: 0} {
+		adReq := &AdRequest{PlacementID: "placement-1", RequestOrigin: origin}
+		bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+		if err != nil {
+			t.Fatalf("Failed to build bid request: %v", err)
+		}
+		if bidReq.Source.FD != want {
+			t.Errorf("Expected source.fd %d for origin %q, got %d", want, origin, bidReq.Source.FD)
+		}
+	}
+}
+
+func TestBidRequestBuilderApp(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1", Name: "Test Publisher"}
+	site := &Site{ID: "site-1", Domain: "testsite.com", Cat: []string{"IAB9"}}
+	placement := &Placement{
+		ID:     "placement-1",
+		AdType: "banner",
+		Width:  320,
+		Height: 50,
+		App:    &App{Bundle: "com.example.app", StoreURL: "https://play.google.com/store/apps/details?id=com.example.app"},
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), &AdRequest{PlacementID: "placement-1"}, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	if bidReq.Site != nil {
+		t.Error("Expected no site for app inventory")
+	}
+	if bidReq.App == nil || bidReq.App.Bundle != "com.example.app" {
+		t.Fatalf("Expected app with the placement bundle, got %+v", bidReq.App)
+	}
+	if bidReq.App.Publisher == nil || bidReq.App.Publisher.ID != "pub-1" {
+		t.Errorf("Expected app publisher pub-1, got %+v", bidReq.App.Publisher)
+	}
+	if len(bidReq.App.Cat) != 1 || bidReq.App.Cat[0] != "IAB9" {
+		t.Errorf("Expected app to inherit site categories, got %v", bidReq.App.Cat)
+	}
+	if placement.App.Publisher != nil {
+		t.Error("Expected the placement app to be left unchanged")
+	}
+}
+
+func TestBidRequestBuilderDeviceGeo(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), &AdRequest{PlacementID: "placement-1"}, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.Device.Geo != nil {
+		t.Errorf("Expected no device geo without enrichment, got %+v", bidReq.Device.Geo)
+	}
+
+	adReq := &AdRequest{
+		PlacementID: "placement-1",
+		IP:          "203.0.113.7",
+		Geo:         &Geo{Country: "USA", Region: "CA", City: "San Francisco", ZIP: "94105"},
+	}
+	bidReq, err = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	expected := Geo{Type: 2, Country: "USA", Region: "CA", City: "San Francisco", ZIP: "94105"}
+	if bidReq.Device.Geo == nil || *bidReq.Device.Geo != expected {
+		t.Errorf("Expected device geo %+v, got %+v", expected, bidReq.Device.Geo)
+	}
+}
+
+func TestBidRequestBuilderLanguage(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	site := &Site{ID: "site-1", Language: "es"}
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if len(bidReq.WLang) != 1 || bidReq.WLang[0] != "es" {
+		t.Errorf("Expected wlang [es], got %v", bidReq.WLang)
+	}
+
+	site.Language = ""
+	bidReq, err = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.WLang != nil {
+		t.Errorf("Expected no wlang without a site language, got %v", bidReq.WLang)
+	}
+}
+
+func TestBidRequestBuilderBlockedCategories(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{PlacementID: "placement-1", BCat: []string{"IAB25", "IAB26"}}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if len(bidReq.BCat) != 2 || bidReq.BCat[0] != "IAB25" || bidReq.BCat[1] != "IAB26" {
+		t.Errorf("Expected bcat [IAB25 IAB26], got %v", bidReq.BCat)
+	}
+
+	bidReq.BCat[0] = "IAB1"
+	if adReq.BCat[0] != "IAB25" {
+		t.Error("Expected bcat to be copied from the ad request")
+	}
+}
+
+func TestValidLanguageCode(t *testing.T) {
+	for _, code := range []string{"", "en", "es", "zh"} {
+		if !ValidLanguageCode(code) {
+			t.Errorf("Expected %q to be valid", code)
+		}
+	}
+	for _, code := range []string{"EN", "eng", "xx", "en-US"} {
+		if ValidLanguageCode(code) {
+			t.Errorf("Expected %q to be invalid", code)
+		}
+	}
+}
+
+func TestBidRequestBuilderSecure(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	tests := []struct {
+		name  string
+		url   string
+		proto string
+		want  int
+	}{
+		{"https page", "https://example.com/article", "", 1},
+		{"http page", "http://example.com/article", "", 0},
+		{"page scheme wins over proto", "http://example.com/article", "https", 0},
+		{"http proto without page", "", "http", 0},
+		{"https proto without page", "", "HTTPS", 1},
+		{"unknown defaults to secure", "", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adReq := &AdRequest{PlacementID: "placement-1", URL: tt.url, Proto: tt.proto}
+			bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+			if err != nil {
+				t.Fatalf("Failed to build bid request: %v", err)
+			}
+			if bidReq.Imp[0].Secure != tt.want {
+				t.Errorf("Expected secure %d, got %d", tt.want, bidReq.Imp[0].Secure)
+			}
+		})
+	}
+}
+
+func TestBidRequestBuilderUserIDs(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{
+		PlacementID: "placement-1",
+		UserIDs: map[string]string{
+			"uid2":     "uid2-token",
+			"liveramp": "ramp-id",
+			"unknown":  "ignored",
+		},
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	data, err := json.Marshal(bidReq.User)
+	if err != nil {
+		t.Fatalf("Failed to marshal user: %v", err)
+	}
+
+	expected := `{"ext":{"eids":[` +
+		`{"source":"liveramp.com","uids":[{"id":"ramp-id","atype":3}]},` +
+		`{"source":"uidapi.com","uids":[{"id":"uid2-token","atype":3}]}]}}`
+	if string(data) != expected {
+		t.Errorf("Unexpected user ext:\n got: %s\nwant: %s", data, expected)
+	}
+
+	// No user object without IDs
+	adReq.UserIDs = nil
+	bidReq, err = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.User != nil {
+		t.Errorf("Expected nil user, got %+v", bidReq.User)
+	}
+}
+
 func TestBidRequestBuilderVideo(t *testing.T) {
 	builder := NewBidRequestBuilder("test-ssp")
 
@@ -132,175 +397,1044 @@ func TestBidRequestBuilderVideo(t *testing.T) {
 		IP:          "192.168.1.1",
 	}
 
-	bidReq, err := builder.BuildBidRequest(adReq, placement, site, publisher)
-	if err != nil {
-		t.Fatalf("Failed to build video bid request: %v", err)
-	}
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build video bid request: %v", err)
+	}
+
+	if len(bidReq.Imp) != 1 {
+		t.Fatalf("Expected 1 impression, got %d", len(bidReq.Imp))
+	}
+
+	imp := bidReq.Imp[0]
+	if imp.Video == nil {
+		t.Fatal("Video is nil")
+	}
+
+	if imp.Video.W != 640 || imp.Video.H != 480 {
+		t.Errorf("Expected video size 640x480, got %dx%d", imp.Video.W, imp.Video.H)
+	}
+
+	if imp.Video.MaxDuration != 30 {
+		t.Errorf("Expected max duration 30, got %d", imp.Video.MaxDuration)
+	}
+
+	if len(imp.Video.Mimes) != 1 || imp.Video.Mimes[0] != "video/mp4" {
+		t.Errorf("Expected mime type video/mp4, got %v", imp.Video.Mimes)
+	}
+}
+
+func TestBidRequestBuilderVideoPlacementType(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	tests := map[string]int{
+		"":                     0,
+		PlacementTypeInStream:  1,
+		PlacementTypeInBanner:  2,
+		PlacementTypeInArticle: 3,
+		PlacementTypeInFeed:    4,
+	}
+
+	for placementType, expected := range tests {
+		placement := &Placement{ID: "placement-1", AdType: "video", Width: 640, Height: 360, PlacementType: placementType}
+		bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+		if err != nil {
+			t.Fatalf("Failed to build bid request: %v", err)
+		}
+
+		if got := bidReq.Imp[0].Video.Placement; got != expected {
+			t.Errorf("Placement type %q: expected video placement %d, got %d", placementType, expected, got)
+		}
+	}
+}
+
+func TestBidRequestBuilderInterstitial(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	for _, interstitial := range []bool{false, true} {
+		placement := &Placement{ID: "placement-1", AdType: "banner", Width: 320, Height: 480, Interstitial: interstitial}
+		bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+		if err != nil {
+			t.Fatalf("Failed to build bid request: %v", err)
+		}
+
+		expected := 0
+		if interstitial {
+			expected = 1
+		}
+		if bidReq.Imp[0].Instl != expected {
+			t.Errorf("Interstitial %v: expected instl=%d, got %d", interstitial, expected, bidReq.Imp[0].Instl)
+		}
+	}
+}
+
+func TestBidRequestBuilderMinSize(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+	placement := &Placement{
+		ID:        "placement-1",
+		AdType:    "banner",
+		Formats:   []Format{{W: 300, H: 250}, {W: 100, H: 100}, {W: 336, H: 280}},
+		MinWidth:  300,
+		MinHeight: 250,
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	formats := bidReq.Imp[0].Banner.Format
+	if len(formats) != 2 || formats[0].W != 300 || formats[1].W != 336 {
+		t.Errorf("Expected undersized format to be dropped, got %+v", formats)
+	}
+
+	// An impression with every format filtered out is not sent at all
+	placement.Formats = []Format{{W: 100, H: 100}}
+	if _, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher); !errors.Is(err, ErrNoEligibleFormats) {
+		t.Errorf("Expected ErrNoEligibleFormats, got %v", err)
+	}
+
+	if placement.MeetsMinSize(100, 100) {
+		t.Error("Expected 100x100 creative to be rejected")
+	}
+	if !placement.MeetsMinSize(300, 250) || !placement.MeetsMinSize(0, 0) {
+		t.Error("Expected 300x250 and unsized creatives to be accepted")
+	}
+}
+
+func TestValidatePlacementFormats(t *testing.T) {
+	if err := ValidatePlacementFormats([]Format{{W: 300, H: 250}, {W: 3840, H: 2160}, {W: 1, H: 1}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := []Format{{W: 0, H: 0}, {W: 300, H: 0}, {W: 99999, H: 99999}, {W: 3841, H: 250}, {W: 300, H: 2161}}
+	for _, f := range invalid {
+		err := ValidatePlacementFormats([]Format{{W: 728, H: 90}, f})
+		if err == nil || !strings.HasPrefix(err.Error(), "format 1:") {
+			t.Errorf("Expected error for format 1 (%dx%d), got %v", f.W, f.H, err)
+		}
+	}
+}
+
+func TestBidRequestBuilderTmax(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.Tmax != defaultTmax {
+		t.Errorf("Expected default tmax %d without a deadline, got %d", defaultTmax, bidReq.Tmax)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	bidReq, err = builder.BuildBidRequest(ctx, adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.Tmax > 200 || bidReq.Tmax < 150 {
+		t.Errorf("Expected tmax near the 200ms deadline, got %d", bidReq.Tmax)
+	}
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelExpired()
+	time.Sleep(5 * time.Millisecond)
+	bidReq, err = builder.BuildBidRequest(expired, adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.Tmax != minTmax {
+		t.Errorf("Expected tmax floored at %d, got %d", minTmax, bidReq.Tmax)
+	}
+}
+
+func TestBidRequestBuilderRegs(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	tests := []struct {
+		name      string
+		gdpr      int
+		usPrivacy string
+		coppa     int
+		wantRegs  bool
+		wantCoppa int
+		wantExt   string
+	}{
+		{"no signals", 0, "", 0, false, 0, ""},
+		{"gdpr", 1, "", 0, true, 0, `{"gdpr":1}`},
+		{"ccpa", 0, "1YNN", 0, true, 0, `{"us_privacy":"1YNN"}`},
+		{"gdpr and ccpa", 1, "1YNN", 0, true, 0, `{"gdpr":1,"us_privacy":"1YNN"}`},
+		{"coppa", 0, "", 1, true, 1, ""},
+		{"coppa and gdpr", 1, "", 1, true, 1, `{"gdpr":1}`},
+		{"coppa and ccpa", 0, "1YYN", 1, true, 1, `{"us_privacy":"1YYN"}`},
+		{"all", 1, "1YNN", 1, true, 1, `{"gdpr":1,"us_privacy":"1YNN"}`},
+	}
+
+	for _, tt := range tests {
+		adReq := &AdRequest{PlacementID: "placement-1", GDPRApplies: tt.gdpr, USPrivacy: tt.usPrivacy, COPPA: tt.coppa}
+		bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+		if err != nil {
+			t.Fatalf("%s: failed to build bid request: %v", tt.name, err)
+		}
+
+		if (bidReq.Regs != nil) != tt.wantRegs {
+			t.Errorf("%s: expected regs present=%v, got %+v", tt.name, tt.wantRegs, bidReq.Regs)
+			continue
+		}
+		if bidReq.Regs == nil {
+			continue
+		}
+
+		if bidReq.Regs.Coppa != tt.wantCoppa {
+			t.Errorf("%s: expected coppa=%d, got %d", tt.name, tt.wantCoppa, bidReq.Regs.Coppa)
+		}
+
+		var ext string
+		if bidReq.Regs.Ext != nil {
+			data, err := json.Marshal(bidReq.Regs.Ext)
+			if err != nil {
+				t.Fatalf("%s: failed to marshal regs.ext: %v", tt.name, err)
+			}
+			ext = string(data)
+		}
+		if ext != tt.wantExt {
+			t.Errorf("%s: expected regs.ext %s, got %s", tt.name, tt.wantExt, ext)
+		}
+	}
+}
+
+func TestBidRequestBuilderSupplyChain(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+	builder.SupplyChain = NewSupplyChainBuilder("ad.nexus", "test-ssp", "AdNexus", "ad.nexus")
+
+	publisher := &Publisher{ID: "pub-1", Domain: "testpub.com"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	if bidReq.Source.PChain != "ad.nexus:pub-1:0" {
+		t.Errorf("Expected pchain ad.nexus:pub-1:0, got %q", bidReq.Source.PChain)
+	}
+
+	schain, err := ExtractFromSource(bidReq.Source)
+	if err != nil {
+		t.Fatalf("Expected schain in source.ext: %v", err)
+	}
+	if len(schain.Nodes) != 1 || schain.Nodes[0].SID != "pub-1" {
+		t.Errorf("Unexpected schain nodes: %+v", schain.Nodes)
+	}
+}
+
+func TestBidRequestBuilderPlacementAuctionType(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+
+	tests := map[int]int{0: 2, 1: 1, 2: 2}
+	for auctionType, expected := range tests {
+		placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250, AuctionType: auctionType}
+		bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+		if err != nil {
+			t.Fatalf("Failed to build bid request: %v", err)
+		}
+
+		if bidReq.At != expected {
+			t.Errorf("Placement auction type %d: expected at=%d, got %d", auctionType, expected, bidReq.At)
+		}
+	}
+}
+
+func TestBidRequestBuilderRewardedVideo(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1", Domain: "testsite.com"}
+	adReq := &AdRequest{PlacementID: "placement-1"}
+	placement := &Placement{
+		ID:     "placement-1",
+		AdType: AdTypeRewardedVideo,
+		Width:  640,
+		Height: 360,
+		Video:  &VideoSettings{Mimes: []string{"video/mp4"}, StartDelay: 5},
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	video := bidReq.Imp[0].Video
+	if video == nil {
+		t.Fatal("Expected video object for rewarded placement")
+	}
+	if video.Skip == nil || *video.Skip != 0 {
+		t.Errorf("Expected skip=0, got %v", video.Skip)
+	}
+	if video.StartDelay != 0 {
+		t.Errorf("Expected pre-roll start delay 0, got %d", video.StartDelay)
+	}
+
+	data, err := json.Marshal(bidReq.Imp[0])
+	if err != nil {
+		t.Fatalf("Failed to marshal impression: %v", err)
+	}
+	var imp struct {
+		Video struct {
+			Skip *int `json:"skip"`
+		} `json:"video"`
+		Ext struct {
+			Rewarded int `json:"rewarded"`
+		} `json:"ext"`
+	}
+	if err := json.Unmarshal(data, &imp); err != nil {
+		t.Fatalf("Failed to unmarshal impression: %v", err)
+	}
+	if imp.Video.Skip == nil {
+		t.Error("Expected skip to be serialized for rewarded video")
+	}
+	if imp.Ext.Rewarded != 1 {
+		t.Errorf("Expected imp.ext.rewarded=1, got %d", imp.Ext.Rewarded)
+	}
+}
+
+func TestBidRequestBuilderDOOH(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1", Name: "Outdoor Media"}
+	site := &Site{ID: "site-1", Domain: "outdoor.example.com"}
+	adReq := &AdRequest{PlacementID: "screen-1"}
+	placement := &Placement{
+		ID:     "screen-1",
+		Name:   "Airport Gate B12",
+		AdType: AdTypeDOOH,
+		Width:  1920,
+		Height: 1080,
+		DOOH:   &DOOHSettings{VenueType: []int{10201}, VenueTypeTax: 1},
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	if bidReq.Site != nil {
+		t.Error("Expected no site object for DOOH request")
+	}
+
+	if bidReq.DOOH == nil {
+		t.Fatal("DOOH is nil")
+	}
+
+	if bidReq.DOOH.ID != "screen-1" || bidReq.DOOH.Name != "Airport Gate B12" {
+		t.Errorf("Unexpected DOOH identity: %+v", bidReq.DOOH)
+	}
+
+	if len(bidReq.DOOH.VenueType) != 1 || bidReq.DOOH.VenueType[0] != 10201 || bidReq.DOOH.VenueTypeTax != 1 {
+		t.Errorf("Unexpected DOOH venue: %v (tax %d)", bidReq.DOOH.VenueType, bidReq.DOOH.VenueTypeTax)
+	}
+
+	if bidReq.DOOH.Publisher == nil || bidReq.DOOH.Publisher.ID != "pub-1" {
+		t.Errorf("Expected DOOH publisher pub-1, got %+v", bidReq.DOOH.Publisher)
+	}
+
+	if bidReq.Imp[0].Banner == nil || bidReq.Imp[0].Banner.W != 1920 {
+		t.Errorf("Expected 1920x1080 banner impression, got %+v", bidReq.Imp[0].Banner)
+	}
+
+	if bidReq.Imp[0].Ext != nil {
+		t.Errorf("Expected no imp.ext without screen metadata, got %v", bidReq.Imp[0].Ext)
+	}
+
+	placement.ScreenMetadata = &ScreenMetadata{VenueID: "jfk-b12", VenueType: 10201, DMACode: "501", AudienceCount: 40}
+	bidReq, err = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+
+	data, err := json.Marshal(bidReq.Imp[0].Ext)
+	if err != nil {
+		t.Fatalf("Failed to marshal imp.ext: %v", err)
+	}
+	if string(data) != `{"dooh":{"venueid":"jfk-b12","venuetype":10201,"dma":"501","audiencecount":40}}` {
+		t.Errorf("Unexpected imp.ext: %s", data)
+	}
+}
+
+func TestAuctionEngine(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	partner1 := &DemandPartner{
+		ID:   "partner-1",
+		Name: "Partner 1",
+	}
+
+	partner2 := &DemandPartner{
+		ID:   "partner-2",
+		Name: "Partner 2",
+	}
+
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 0.10,
+	}
+
+	// Create bid responses
+	response1 := &BidResponse{
+		ID: "resp-1",
+		SeatBid: []SeatBid{
+			{
+				Bid: []Bid{
+					{
+						ID:    "bid-1",
+						ImpID: "imp-1",
+						Price: 1.50,
+						ADM:   "<ad>markup1</ad>",
+					},
+				},
+			},
+		},
+	}
+
+	response2 := &BidResponse{
+		ID: "resp-2",
+		SeatBid: []SeatBid{
+			{
+				Bid: []Bid{
+					{
+						ID:    "bid-2",
+						ImpID: "imp-1",
+						Price: 2.00,
+					},
+				},
+			},
+		},
+	}
+
+	responses := map[*DemandPartner]*BidResponse{
+		partner1: response1,
+		partner2: response2,
+	}
+
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Auction result is nil")
+	}
+
+	// Winner should be highest bidder (partner2 with 2.00)
+	if result.WinningBid.Price != 2.00 {
+		t.Errorf("Expected winning price 2.00, got %f", result.WinningBid.Price)
+	}
+
+	if result.WinningPartner.ID != "partner-2" {
+		t.Errorf("Expected winner partner-2, got %s", result.WinningPartner.ID)
+	}
+
+	// Cleared price should be second highest (1.50)
+	if result.ClearedPrice != 1.50 {
+		t.Errorf("Expected cleared price 1.50, got %f", result.ClearedPrice)
+	}
+
+	if result.AuctionType != 2 {
+		t.Errorf("Expected auction type 2 (second price), got %d", result.AuctionType)
+	}
+}
+
+func TestAuctionEngineNoBids(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 0.10,
+	}
+
+	responses := map[*DemandPartner]*BidResponse{}
+
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected nil result for no bids")
+	}
+}
+
+func TestAuctionEngineBelowFloor(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	partner := &DemandPartner{
+		ID:   "partner-1",
+		Name: "Partner 1",
+	}
+
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 1.00,
+	}
+
+	response := &BidResponse{
+		ID: "resp-1",
+		SeatBid: []SeatBid{
+			{
+				Bid: []Bid{
+					{
+						ID:    "bid-1",
+						ImpID: "imp-1",
+						Price: 0.50, // Below floor
+					},
+				},
+			},
+		},
+	}
+
+	responses := map[*DemandPartner]*BidResponse{
+		partner: response,
+	}
+
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected nil result for bids below floor")
+	}
+}
+
+func TestAuctionEngineCurrencyWhitelist(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+	var rejected []string
+	engine.OnBidRejected = func(reason string) {
+		rejected = append(rejected, reason)
+	}
+
+	usd := &DemandPartner{ID: "partner-usd"}
+	eur := &DemandPartner{ID: "partner-eur"}
+	unset := &DemandPartner{ID: "partner-unset"}
+	placement := &Placement{ID: "placement-1", MinBidFloor: 1.00}
 
-	if len(bidReq.Imp) != 1 {
-		t.Fatalf("Expected 1 impression, got %d", len(bidReq.Imp))
+	responses := map[*DemandPartner]*BidResponse{
+		usd:   {ID: "resp-usd", Cur: "usd", SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-usd", Price: 2.00}}}}},
+		eur:   {ID: "resp-eur", Cur: "EUR", SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-eur", Price: 5.00}}}}},
+		unset: {ID: "resp-unset", SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-unset", Price: 1.50}}}}},
 	}
 
-	imp := bidReq.Imp[0]
-	if imp.Video == nil {
-		t.Fatal("Video is nil")
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+	if result == nil || result.WinningBid.ID != "bid-usd" {
+		t.Fatalf("Expected bid-usd to win, got %+v", result)
+	}
+	if len(result.AllBids) != 2 {
+		t.Errorf("Expected USD and default-currency bids in the auction, got %d", len(result.AllBids))
+	}
+	if len(rejected) != 1 || rejected[0] != "currency" {
+		t.Errorf("Expected one currency rejection, got %v", rejected)
 	}
 
-	if imp.Video.W != 640 || imp.Video.H != 480 {
-		t.Errorf("Expected video size 640x480, got %dx%d", imp.Video.W, imp.Video.H)
+	// Whitelisted EUR still needs an exchange rate to compete
+	engine.CurrencyWhitelist = append(engine.CurrencyWhitelist, "EUR")
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+	if result == nil || result.WinningBid.ID != "bid-usd" {
+		t.Errorf("Expected bid-eur to be rejected without an exchange rate, got %+v", result)
 	}
 
-	if imp.Video.MaxDuration != 30 {
-		t.Errorf("Expected max duration 30, got %d", imp.Video.MaxDuration)
+	// EUR bids compete at their USD value
+	engine.ExchangeRates = map[string]float64{"EUR": 0.30}
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+	if result == nil || result.WinningBid.ID != "bid-usd" || len(result.AllBids) != 3 {
+		t.Errorf("Expected 5 EUR (1.50 USD) to lose to 2 USD, got %+v", result)
 	}
 
-	if len(imp.Video.Mimes) != 1 || imp.Video.Mimes[0] != "video/mp4" {
-		t.Errorf("Expected mime type video/mp4, got %v", imp.Video.Mimes)
+	engine.ExchangeRates["EUR"] = 1.10
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+	if result == nil || result.WinningBid.ID != "bid-eur" || result.WinningBid.Price != 5.50 {
+		t.Errorf("Expected bid-eur to win at 5.50 USD, got %+v", result)
+	}
+	if responses[eur].SeatBid[0].Bid[0].Price != 5.00 {
+		t.Error("Expected the DSP response to keep its original price")
 	}
 }
 
-func TestAuctionEngine(t *testing.T) {
+func TestAuctionEngineBlockedDomains(t *testing.T) {
 	engine := NewAuctionEngine(0.10)
 
-	partner1 := &DemandPartner{
+	partner := &DemandPartner{
 		ID:   "partner-1",
 		Name: "Partner 1",
 	}
 
-	partner2 := &DemandPartner{
-		ID:   "partner-2",
-		Name: "Partner 2",
-	}
-
 	placement := &Placement{
-		ID:          "placement-1",
-		MinBidFloor: 0.10,
+		ID:             "placement-1",
+		MinBidFloor:    1.00,
+		BlockedDomains: []string{"blocked.example.com"},
 	}
 
-	// Create bid responses
-	response1 := &BidResponse{
+	response := &BidResponse{
 		ID: "resp-1",
 		SeatBid: []SeatBid{
 			{
 				Bid: []Bid{
-					{
-						ID:    "bid-1",
-						ImpID: "imp-1",
-						Price: 1.50,
-						ADM:   "<ad>markup1</ad>",
-					},
+					{ID: "bid-1", ImpID: "imp-1", Price: 3.00, ADomain: []string{"other.example.com", "Blocked.example.com"}},
+					{ID: "bid-2", ImpID: "imp-1", Price: 2.00, ADomain: []string{"allowed.example.com"}},
 				},
 			},
 		},
 	}
 
-	response2 := &BidResponse{
-		ID: "resp-2",
+	result, err := engine.RunAuction(map[*DemandPartner]*BidResponse{partner: response}, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected auction result")
+	}
+
+	if result.WinningBid.ID != "bid-2" {
+		t.Errorf("Expected bid-2 to win, got %s", result.WinningBid.ID)
+	}
+
+	if len(result.AllBids) != 1 {
+		t.Errorf("Expected blocked bid to be filtered, got %d bids", len(result.AllBids))
+	}
+}
+
+func TestAuctionEngineImpressionMatching(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	partner := &DemandPartner{
+		ID:   "partner-1",
+		Name: "Partner 1",
+	}
+
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 1.00,
+	}
+
+	response := &BidResponse{
+		ID: "resp-1",
 		SeatBid: []SeatBid{
 			{
 				Bid: []Bid{
-					{
-						ID:    "bid-2",
-						ImpID: "imp-1",
-						Price: 2.00,
-					},
+					{ID: "bid-1", ImpID: "imp-2", Price: 5.00},
+					{ID: "bid-2", ImpID: "imp-1", Price: 2.00},
 				},
 			},
 		},
 	}
+	responses := map[*DemandPartner]*BidResponse{partner: response}
+
+	result, err := engine.RunAuctionForImpression(responses, placement, "imp-1")
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result == nil || result.WinningBid.ID != "bid-2" {
+		t.Fatalf("Expected bid-2 for imp-1 to win, got %+v", result)
+	}
+
+	if len(result.AllBids) != 1 {
+		t.Errorf("Expected bid for imp-2 to be filtered, got %d bids", len(result.AllBids))
+	}
+
+	result, err = engine.RunAuctionForImpression(responses, placement, "imp-3")
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected no winner for imp-3, got %s", result.WinningBid.ID)
+	}
+}
+
+func TestAuctionEngineFillRateDecays(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	// A long history of filled auctions
+	for i := 0; i < 10000; i++ {
+		engine.recordFill("placement-1", true, start)
+	}
+
+	// A day later, recent unfilled auctions dominate the rate
+	later := start.Add(24 * time.Hour)
+	for i := 0; i < 100; i++ {
+		engine.recordFill("placement-1", false, later)
+	}
+	if rate := engine.FillRate("placement-1"); rate > 0.05 {
+		t.Errorf("Expected the fill rate to follow recent auctions, got %f", rate)
+	}
+
+	// Without elapsed time the rate is the plain ratio
+	engine.recordFill("placement-2", true, start)
+	engine.recordFill("placement-2", false, start)
+	if rate := engine.FillRate("placement-2"); math.Abs(rate-0.5) > 1e-9 {
+		t.Errorf("Expected fill rate 0.5, got %f", rate)
+	}
+}
+
+func TestAuctionEngineMinFillRate(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	relaxations := 0
+	engine.OnFloorRelaxed = func() { relaxations++ }
+
+	partner1 := &DemandPartner{ID: "partner-1"}
+	partner2 := &DemandPartner{ID: "partner-2"}
+	placement := &Placement{ID: "placement-1", MinBidFloor: 1.00, MinFillRate: 0.05}
 
 	responses := map[*DemandPartner]*BidResponse{
-		partner1: response1,
-		partner2: response2,
+		partner1: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-1", ImpID: "imp-1", Price: 0.95}}}}},
+		partner2: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-2", ImpID: "imp-1", Price: 0.50}}}}},
 	}
 
 	result, err := engine.RunAuction(responses, placement)
+	if err != nil || result == nil {
+		t.Fatalf("Expected relaxed floor to produce a winner: %v", err)
+	}
+
+	if !result.FloorRelaxed {
+		t.Error("Expected FloorRelaxed")
+	}
+
+	if result.WinningPartner.ID != "partner-1" {
+		t.Errorf("Expected winner partner-1, got %s", result.WinningPartner.ID)
+	}
+
+	// Effective floor is 0.95 * 0.9; the 0.50 bid does not clear it
+	if math.Abs(result.ClearedPrice-0.855) > 1e-9 {
+		t.Errorf("Expected cleared price 0.855, got %f", result.ClearedPrice)
+	}
+
+	if len(result.AllBids) != 1 {
+		t.Errorf("Expected 1 bid above relaxed floor, got %d", len(result.AllBids))
+	}
+
+	if relaxations != 1 {
+		t.Errorf("Expected 1 floor relaxation, got %d", relaxations)
+	}
+
+	// Fill rate now meets the target, so the floor is enforced again
+	result, err = engine.RunAuction(responses, placement)
 	if err != nil {
 		t.Fatalf("Auction failed: %v", err)
 	}
 
-	if result == nil {
-		t.Fatal("Auction result is nil")
+	if result != nil {
+		t.Error("Expected nil result once fill rate is above the minimum")
 	}
 
-	// Winner should be highest bidder (partner2 with 2.00)
-	if result.WinningBid.Price != 2.00 {
-		t.Errorf("Expected winning price 2.00, got %f", result.WinningBid.Price)
+	// Bids too far below the floor are never relaxed
+	engine = NewAuctionEngine(0.10)
+	responses = map[*DemandPartner]*BidResponse{
+		partner1: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-1", ImpID: "imp-1", Price: 0.80}}}}},
+	}
+
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result != nil {
+		t.Error("Expected nil result for bid outside the floor relaxation")
+	}
+}
+
+func TestAuctionEngineTieBreakByPriority(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 0.10,
+	}
+
+	openExchange := &DemandPartner{ID: "open-exchange", Priority: 10}
+	headerBidding := &DemandPartner{ID: "header-bidding", Priority: 1}
+	lowBidder := &DemandPartner{ID: "low-bidder", Priority: 0}
+
+	responses := map[*DemandPartner]*BidResponse{
+		openExchange: {
+			SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-open", ImpID: "imp-1", Price: 2.00}}}},
+		},
+		headerBidding: {
+			SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-hb", ImpID: "imp-1", Price: 2.00}}}},
+		},
+		lowBidder: {
+			SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-low", ImpID: "imp-1", Price: 1.00}}}},
+		},
+	}
+
+	// Map iteration order is random; run several times to catch non-determinism
+	for i := 0; i < 20; i++ {
+		result, err := engine.RunAuction(responses, placement)
+		if err != nil {
+			t.Fatalf("Auction failed: %v", err)
+		}
+
+		if result.WinningPartner.ID != "header-bidding" {
+			t.Fatalf("Expected header-bidding to win the tie, got %s", result.WinningPartner.ID)
+		}
+
+		if result.ClearedPrice != 2.00 {
+			t.Errorf("Expected cleared price 2.00, got %f", result.ClearedPrice)
+		}
+	}
+}
+
+func TestAuctionEngineBidShading(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+	engine.AuctionType = 1
+	engine.BidShadingEnabled = true
+	engine.BidShadingFactor = 0.85
+
+	partner1 := &DemandPartner{ID: "partner-1"}
+	partner2 := &DemandPartner{ID: "partner-2"}
+	placement := &Placement{ID: "placement-1", MinBidFloor: 0.10}
+
+	responses := map[*DemandPartner]*BidResponse{
+		partner1: {ID: "resp-1", SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-1", ImpID: "imp-1", Price: 1.50}}}}},
+		partner2: {ID: "resp-2", SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-2", ImpID: "imp-1", Price: 2.00}}}}},
+	}
+
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil || result == nil {
+		t.Fatalf("Auction failed: %v", err)
 	}
 
 	if result.WinningPartner.ID != "partner-2" {
 		t.Errorf("Expected winner partner-2, got %s", result.WinningPartner.ID)
 	}
 
-	// Cleared price should be second highest (1.50)
-	if result.ClearedPrice != 1.50 {
-		t.Errorf("Expected cleared price 1.50, got %f", result.ClearedPrice)
+	if result.AuctionType != 1 {
+		t.Errorf("Expected auction type 1 (first price), got %d", result.AuctionType)
 	}
 
-	if result.AuctionType != 2 {
-		t.Errorf("Expected auction type 2 (second price), got %d", result.AuctionType)
+	if result.OriginalPrice != 2.00 {
+		t.Errorf("Expected original price 2.00, got %f", result.OriginalPrice)
+	}
+
+	if math.Abs(result.ShadedPrice-1.70) > 1e-9 {
+		t.Errorf("Expected shaded price 1.70, got %f", result.ShadedPrice)
+	}
+
+	if math.Abs(result.ClearedPrice-1.70) > 1e-9 {
+		t.Errorf("Expected cleared price 1.70, got %f", result.ClearedPrice)
+	}
+
+	// Shading only applies to first-price auctions
+	engine.AuctionType = 2
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil || result == nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result.ShadedPrice != result.OriginalPrice {
+		t.Errorf("Expected no shading in second-price auction, got %f vs %f", result.ShadedPrice, result.OriginalPrice)
+	}
+
+	if result.ClearedPrice != 1.50 {
+		t.Errorf("Expected cleared price 1.50, got %f", result.ClearedPrice)
 	}
 }
 
-func TestAuctionEngineNoBids(t *testing.T) {
+func TestAuctionEngineProgrammaticGuaranteed(t *testing.T) {
 	engine := NewAuctionEngine(0.10)
 
 	placement := &Placement{
 		ID:          "placement-1",
 		MinBidFloor: 0.10,
+		Deals: []Deal{
+			{ID: "pg-deal", BidFloor: 5.00, DealType: DealTypeGuaranteed},
+			{ID: "pmp-deal", BidFloor: 1.00},
+		},
 	}
 
-	responses := map[*DemandPartner]*BidResponse{}
+	openBidder := &DemandPartner{ID: "open-bidder"}
+	pmpBidder := &DemandPartner{ID: "pmp-bidder"}
+	pgBuyer := &DemandPartner{ID: "pg-buyer"}
+
+	responses := map[*DemandPartner]*BidResponse{
+		openBidder: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-open", ImpID: "imp-1", Price: 9.00}}}}},
+		pmpBidder:  {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-pmp", ImpID: "imp-1", Price: 8.00, DealID: "pmp-deal"}}}}},
+		pgBuyer:    {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-pg", ImpID: "imp-1", Price: 5.50, DealID: "pg-deal"}}}}},
+	}
 
 	result, err := engine.RunAuction(responses, placement)
-	if err != nil {
+	if err != nil || result == nil {
 		t.Fatalf("Auction failed: %v", err)
 	}
 
-	if result != nil {
-		t.Error("Expected nil result for no bids")
+	if !result.IsPG {
+		t.Error("Expected PG result")
+	}
+
+	if result.WinningPartner.ID != "pg-buyer" {
+		t.Errorf("Expected winner pg-buyer, got %s", result.WinningPartner.ID)
+	}
+
+	if result.ClearedPrice != 5.00 {
+		t.Errorf("Expected cleared price at deal price 5.00, got %f", result.ClearedPrice)
+	}
+
+	if len(result.AllBids) != 1 {
+		t.Errorf("Expected open auction bids to be skipped, got %d bids", len(result.AllBids))
+	}
+
+	// Without a PG bid the regular auction runs
+	delete(responses, pgBuyer)
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil || result == nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	if result.IsPG || result.WinningPartner.ID != "open-bidder" {
+		t.Errorf("Expected open auction win by open-bidder, got %s (PG=%v)", result.WinningPartner.ID, result.IsPG)
 	}
 }
 
-func TestAuctionEngineBelowFloor(t *testing.T) {
+func TestAuctionEngineProgrammaticGuaranteedDealTerms(t *testing.T) {
 	engine := NewAuctionEngine(0.10)
 
-	partner := &DemandPartner{
-		ID:   "partner-1",
-		Name: "Partner 1",
+	placement := &Placement{
+		ID:          "placement-1",
+		MinBidFloor: 0.10,
+		Deals: []Deal{{
+			ID:       "pg-deal",
+			BidFloor: 5.00,
+			DealType: DealTypeGuaranteed,
+			WSeat:    []string{"seat-1"},
+			WADomain: []string{"brand.example.com"},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		seat    string
+		bid     Bid
+		wantPG  bool
+		cleared float64
+	}{
+		{"Meets deal terms", "seat-1", Bid{Price: 6.00, ADomain: []string{"brand.example.com"}}, true, 5.00},
+		{"Below deal floor", "seat-1", Bid{Price: 4.00, ADomain: []string{"brand.example.com"}}, false, 0},
+		{"Seat not allowed", "seat-2", Bid{Price: 6.00, ADomain: []string{"brand.example.com"}}, false, 0},
+		{"Advertiser not allowed", "seat-1", Bid{Price: 6.00, ADomain: []string{"other.example.com"}}, false, 0},
+		{"No advertiser domain", "seat-1", Bid{Price: 6.00}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bid := tt.bid
+			bid.ID, bid.ImpID, bid.DealID = "bid-pg", "imp-1", "pg-deal"
+			responses := map[*DemandPartner]*BidResponse{
+				{ID: "pg-buyer"}: {SeatBid: []SeatBid{{Seat: tt.seat, Bid: []Bid{bid}}}},
+			}
+
+			result, err := engine.RunAuction(responses, placement)
+			if err != nil {
+				t.Fatalf("Auction failed: %v", err)
+			}
+			if !tt.wantPG {
+				if result != nil && result.IsPG {
+					t.Error("Expected the bid to be rejected from the PG deal")
+				}
+				return
+			}
+			if result == nil || !result.IsPG {
+				t.Fatalf("Expected PG result, got %+v", result)
+			}
+			if result.ClearedPrice != tt.cleared {
+				t.Errorf("Expected cleared price %.2f, got %f", tt.cleared, result.ClearedPrice)
+			}
+		})
+	}
+
+	if price := dealClearingPrice(Deal{}, 3.00); price != 3.00 {
+		t.Errorf("Expected a deal without a floor to clear at the bid price, got %f", price)
 	}
+}
+
+func TestAuctionEngineDealPacing(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+	engine.DealPacer = NewDealPacer()
+	var skipped []string
+	engine.OnDealPacingSkip = func(dealID string) { skipped = append(skipped, dealID) }
 
 	placement := &Placement{
 		ID:          "placement-1",
-		MinBidFloor: 1.00,
+		MinBidFloor: 0.10,
+		Deals:       []Deal{{ID: "pg-deal", BidFloor: 5.00, DealType: DealTypeGuaranteed, DailyBudget: 24}},
 	}
 
-	response := &BidResponse{
-		ID: "resp-1",
-		SeatBid: []SeatBid{
-			{
-				Bid: []Bid{
-					{
-						ID:    "bid-1",
-						ImpID: "imp-1",
-						Price: 0.50, // Below floor
-					},
-				},
-			},
-		},
+	openBidder := &DemandPartner{ID: "open-bidder"}
+	pgBuyer := &DemandPartner{ID: "pg-buyer"}
+	responses := map[*DemandPartner]*BidResponse{
+		openBidder: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-open", ImpID: "imp-1", Price: 2.00}}}}},
+		pgBuyer:    {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-pg", ImpID: "imp-1", Price: 5.50, DealID: "pg-deal"}}}}},
 	}
 
-	responses := map[*DemandPartner]*BidResponse{
-		partner: response,
+	// A budget of 24 a day allows one delivery an hour. Wins only count
+	// against it once their impression is recorded.
+	for i := 0; i < 2; i++ {
+		result, err := engine.RunAuction(responses, placement)
+		if err != nil || result == nil || !result.IsPG {
+			t.Fatalf("Expected auction %d to deliver the PG deal, got %+v (err %v)", i+1, result, err)
+		}
 	}
+	engine.DealPacer.RecordDelivery("pg-deal")
 
 	result, err := engine.RunAuction(responses, placement)
-	if err != nil {
+	if err != nil || result == nil {
 		t.Fatalf("Auction failed: %v", err)
 	}
-
-	if result != nil {
-		t.Error("Expected nil result for bids below floor")
+	if result.IsPG || result.WinningPartner.ID != "open-bidder" {
+		t.Errorf("Expected paced-out deal to fall back to the open auction, got %s (PG=%v)", result.WinningPartner.ID, result.IsPG)
+	}
+	if len(skipped) != 1 || skipped[0] != "pg-deal" {
+		t.Errorf("Expected one pacing skip for pg-deal, got %v", skipped)
 	}
 }
 
@@ -400,7 +1534,7 @@ func BenchmarkBidRequestBuilder(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = builder.BuildBidRequest(adReq, placement, site, publisher)
+		_, _ = builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
 	}
 }
 
@@ -439,3 +1573,40 @@ func BenchmarkAuctionEngine(b *testing.B) {
 		_, _ = engine.RunAuction(responses, placement)
 	}
 }
+
+func TestAuctionEngineFloorPriceStats(t *testing.T) {
+	engine := NewAuctionEngine(0.10)
+	var belowFloor int
+	engine.OnBidsBelowFloor = func(count int) { belowFloor += count }
+
+	placement := &Placement{ID: "placement-1", MinBidFloor: 2.00}
+	partner1 := &DemandPartner{ID: "partner-1"}
+	partner2 := &DemandPartner{ID: "partner-2"}
+	responses := map[*DemandPartner]*BidResponse{
+		partner1: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-1", Price: 3.00}, {ID: "bid-2", Price: 1.50}}}}},
+		partner2: {SeatBid: []SeatBid{{Bid: []Bid{{ID: "bid-3", Price: 1.80}}}}},
+	}
+
+	result, err := engine.RunAuction(responses, placement)
+	if err != nil || result == nil {
+		t.Fatalf("Auction failed: %v", err)
+	}
+
+	want := FloorPriceStats{TotalBids: 3, BidsAboveFloor: 1, BidsBelowFloor: 2, MaxBidBelow: 1.80}
+	if result.FloorStats != want {
+		t.Errorf("Expected floor stats %+v, got %+v", want, result.FloorStats)
+	}
+	if belowFloor != 2 {
+		t.Errorf("Expected 2 bids reported below floor, got %d", belowFloor)
+	}
+
+	// Auctions with every bid under the floor still report them
+	delete(responses, partner1)
+	result, err = engine.RunAuction(responses, placement)
+	if err != nil || result != nil {
+		t.Fatalf("Expected no winner, got %+v (err %v)", result, err)
+	}
+	if belowFloor != 3 {
+		t.Errorf("Expected 3 bids reported below floor, got %d", belowFloor)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	openrtb2 "github.com/prebid/openrtb/v20/openrtb2"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // BidsCubePartner handles integration with BidsCube White Label
@@ -18,6 +21,56 @@ type BidsCubePartner struct {
 	apiKey   string
 	client   *http.Client
 	revShare float64 // Revenue share for SSP
+
+	// DSARequired requests EU Digital Services Act transparency data and drops
+	// bids that do not return it
+	DSARequired bool
+	Logger      *slog.Logger // Optional; receives warnings for filtered bids
+
+	latency      prometheus.Histogram // Optional; observes each HTTP round-trip
+	responseTime prometheus.Gauge     // Optional; last observed round-trip
+	lastLatency  atomic.Int64         // Nanoseconds
+}
+
+// DSA signals sent in regs.ext.dsa when DSARequired is set (IAB DSA Transparency extension)
+const (
+	dsaRequiredOnlinePlatform = 3 // Required; the publisher is an online platform
+	dsaPubRenderMayRender     = 1 // Publisher may render the DSA transparency info
+	dsaDataToPubRequired      = 2 // Buyer must return the DSA transparency info
+)
+
+// bidDSA is the bid.ext.dsa object a buyer returns when DSA info is required
+type bidDSA struct {
+	Behalf string `json:"behalf"` // Advertiser on whose behalf the ad is shown
+	Paid   string `json:"paid"`   // Who paid for the ad
+}
+
+// BidsCubeMetrics holds the Prometheus collectors shared by BidsCube partner clients
+type BidsCubeMetrics struct {
+	Latency      prometheus.Histogram
+	ResponseTime prometheus.Gauge
+}
+
+// NewBidsCubeMetrics creates BidsCube latency collectors. Register them with
+// Collectors before use.
+func NewBidsCubeMetrics() *BidsCubeMetrics {
+	return &BidsCubeMetrics{
+		Latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ssp_bidscube_response_seconds",
+			Help:    "BidsCube bid request round-trip latency in seconds",
+			Buckets: []float64{.01, .025, .05, .075, .1, .15, .2, .3, .5},
+		}),
+		ResponseTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "ssp_partner_response_time_seconds",
+			Help:        "Last observed partner bid request round-trip latency in seconds",
+			ConstLabels: prometheus.Labels{"partner": "bidscube"},
+		}),
+	}
+}
+
+// Collectors returns the collectors to register with Prometheus
+func (m *BidsCubeMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.Latency, m.ResponseTime}
 }
 
 // NewBidsCubePartner creates a new BidsCube partner integration
@@ -32,6 +85,25 @@ func NewBidsCubePartner(endpoint, apiKey string, revShare float64) *BidsCubePart
 	}
 }
 
+// WithMetrics records request latency in h and the last observed latency in g.
+// Either may be nil.
+func (b *BidsCubePartner) WithMetrics(h prometheus.Histogram, g prometheus.Gauge) *BidsCubePartner {
+	b.latency = h
+	b.responseTime = g
+	return b
+}
+
+// observeLatency records a completed HTTP round-trip
+func (b *BidsCubePartner) observeLatency(d time.Duration) {
+	b.lastLatency.Store(int64(d))
+	if b.latency != nil {
+		b.latency.Observe(d.Seconds())
+	}
+	if b.responseTime != nil {
+		b.responseTime.Set(d.Seconds())
+	}
+}
+
 // SendBidRequest sends OpenRTB request to BidsCube
 func (b *BidsCubePartner) SendBidRequest(ctx context.Context, req *openrtb2.BidRequest) (*openrtb2.BidResponse, error) {
 	// Add BidsCube specific extensions
@@ -55,7 +127,9 @@ func (b *BidsCubePartner) SendBidRequest(ctx context.Context, req *openrtb2.BidR
 	httpReq.Header.Set("X-OpenRTB-Version", "2.5")
 	
 	// Send request
+	start := time.Now()
 	resp, err := b.client.Do(httpReq)
+	b.observeLatency(time.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -74,9 +148,13 @@ func (b *BidsCubePartner) SendBidRequest(ctx context.Context, req *openrtb2.BidR
 	// Parse response
 	var bidResp openrtb2.BidResponse
 	if err := json.NewDecoder(resp.Body).Decode(&bidResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBidResponse, err)
 	}
 	
+	if b.DSARequired {
+		b.filterDSABids(&bidResp)
+	}
+
 	// Apply revenue share to bids
 	b.applyRevShare(&bidResp)
 	
@@ -112,10 +190,82 @@ func (b *BidsCubePartner) transformRequest(req *openrtb2.BidRequest) *openrtb2.B
 	if extBytes, err := json.Marshal(extData); err == nil {
 		transformed.Ext = extBytes
 	}
+
+	if b.DSARequired {
+		transformed.Regs = withDSARegs(transformed.Regs)
+	}
 	
 	return &transformed
 }
 
+// withDSARegs returns a copy of regs with ext.dsa set, keeping other regs.ext fields
+func withDSARegs(regs *openrtb2.Regs) *openrtb2.Regs {
+	updated := openrtb2.Regs{}
+	if regs != nil {
+		updated = *regs
+	}
+
+	ext := map[string]interface{}{}
+	if len(updated.Ext) > 0 {
+		if err := json.Unmarshal(updated.Ext, &ext); err != nil || ext == nil {
+			ext = map[string]interface{}{}
+		}
+	}
+	ext["dsa"] = map[string]int{
+		"dsarequired": dsaRequiredOnlinePlatform,
+		"pubrender":   dsaPubRenderMayRender,
+		"datatopub":   dsaDataToPubRequired,
+	}
+
+	if extBytes, err := json.Marshal(ext); err == nil {
+		updated.Ext = extBytes
+	}
+	return &updated
+}
+
+// filterDSABids drops bids without DSA transparency info in bid.ext.dsa
+func (b *BidsCubePartner) filterDSABids(resp *openrtb2.BidResponse) {
+	seatBids := resp.SeatBid[:0]
+	for _, seatBid := range resp.SeatBid {
+		bids := seatBid.Bid[:0]
+		for _, bid := range seatBid.Bid {
+			if err := validateBidDSA(bid.Ext); err != nil {
+				if b.Logger != nil {
+					b.Logger.Warn("Dropping bid without DSA transparency info", "bid_id", bid.ID, "seat", seatBid.Seat, "error", err)
+				}
+				continue
+			}
+			bids = append(bids, bid)
+		}
+		if len(bids) > 0 {
+			seatBid.Bid = bids
+			seatBids = append(seatBids, seatBid)
+		}
+	}
+	resp.SeatBid = seatBids
+}
+
+// validateBidDSA checks that a bid's ext carries a dsa object with behalf and paid set
+func validateBidDSA(ext json.RawMessage) error {
+	if len(ext) == 0 {
+		return fmt.Errorf("missing bid.ext.dsa")
+	}
+
+	var parsed struct {
+		DSA *bidDSA `json:"dsa"`
+	}
+	if err := json.Unmarshal(ext, &parsed); err != nil {
+		return fmt.Errorf("invalid bid.ext: %w", err)
+	}
+	if parsed.DSA == nil {
+		return fmt.Errorf("missing bid.ext.dsa")
+	}
+	if parsed.DSA.Behalf == "" || parsed.DSA.Paid == "" {
+		return fmt.Errorf("bid.ext.dsa requires behalf and paid")
+	}
+	return nil
+}
+
 // applyRevShare adjusts bid prices based on revenue share
 func (b *BidsCubePartner) applyRevShare(resp *openrtb2.BidResponse) {
 	if resp == nil || len(resp.SeatBid) == 0 {
@@ -137,5 +287,6 @@ func (b *BidsCubePartner) GetMetrics() map[string]interface{} {
 		"endpoint": b.endpoint,
 		"rev_share": b.revShare,
 		"timeout_ms": b.client.Timeout.Milliseconds(),
+		"last_latency_seconds": time.Duration(b.lastLatency.Load()).Seconds(),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,94 @@
+package ssp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBidsCubePartnerMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	metrics := NewBidsCubeMetrics()
+	partner := NewBidsCubePartner(server.URL, "key", 0.3).WithMetrics(metrics.Latency, metrics.ResponseTime)
+
+	for i := 0; i < 3; i++ {
+		if _, err := partner.SendBidRequest(context.Background(), &openrtb2.BidRequest{ID: "req"}); err != nil {
+			t.Fatalf("SendBidRequest failed: %v", err)
+		}
+	}
+
+	if got := testutil.CollectAndCount(metrics.Latency); got != 1 {
+		t.Errorf("expected latency histogram to be collected, got %d metrics", got)
+	}
+	if testutil.ToFloat64(metrics.ResponseTime) <= 0 {
+		t.Error("expected last response time to be recorded")
+	}
+	if latency, _ := partner.GetMetrics()["last_latency_seconds"].(float64); latency <= 0 {
+		t.Error("expected last latency in GetMetrics")
+	}
+}
+
+func TestBidsCubePartnerDSA(t *testing.T) {
+	var received openrtb2.BidRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"req","seatbid":[
+			{"seat":"compliant","bid":[
+				{"id":"ok","impid":"1","price":2,"ext":{"dsa":{"behalf":"Advertiser","paid":"Advertiser"}}},
+				{"id":"no-paid","impid":"1","price":3,"ext":{"dsa":{"behalf":"Advertiser"}}}
+			]},
+			{"seat":"missing","bid":[{"id":"no-ext","impid":"1","price":4}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	partner := NewBidsCubePartner(server.URL, "key", 0)
+	partner.DSARequired = true
+
+	gdpr := int8(1)
+	req := &openrtb2.BidRequest{ID: "req", Regs: &openrtb2.Regs{GDPR: &gdpr, Ext: json.RawMessage(`{"gpp_applies":1}`)}}
+	resp, err := partner.SendBidRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SendBidRequest failed: %v", err)
+	}
+
+	var regsExt struct {
+		GPPApplies int            `json:"gpp_applies"`
+		DSA        map[string]int `json:"dsa"`
+	}
+	if received.Regs == nil || json.Unmarshal(received.Regs.Ext, &regsExt) != nil {
+		t.Fatalf("Expected regs.ext in request, got %+v", received.Regs)
+	}
+	if regsExt.DSA["dsarequired"] != 3 || regsExt.DSA["pubrender"] != 1 || regsExt.DSA["datatopub"] != 2 {
+		t.Errorf("Unexpected regs.ext.dsa: %v", regsExt.DSA)
+	}
+	if regsExt.GPPApplies != 1 || received.Regs.GDPR == nil || *received.Regs.GDPR != 1 {
+		t.Error("Expected existing regs fields to be kept")
+	}
+	if len(req.Regs.Ext) == 0 || string(req.Regs.Ext) != `{"gpp_applies":1}` {
+		t.Error("Expected the original request to be left unchanged")
+	}
+
+	if len(resp.SeatBid) != 1 || len(resp.SeatBid[0].Bid) != 1 || resp.SeatBid[0].Bid[0].ID != "ok" {
+		t.Errorf("Expected only the compliant bid to remain, got %+v", resp.SeatBid)
+	}
+}
+
+func TestBidsCubePartnerWithoutDSA(t *testing.T) {
+	partner := NewBidsCubePartner("https://bidscube.example.com", "key", 0)
+	if transformed := partner.transformRequest(&openrtb2.BidRequest{ID: "req"}); transformed.Regs != nil {
+		t.Errorf("Expected no regs without DSARequired, got %+v", transformed.Regs)
+	}
+}
@@ -0,0 +1,95 @@
+package ssp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBlockedCategoryTTL is how long effective category blocks are cached
+const DefaultBlockedCategoryTTL = 5 * time.Minute
+
+// MergeBlockedCategories unions category blocks from several levels, keeping
+// the first occurrence of each category and dropping blanks
+func MergeBlockedCategories(levels ...[]string) []string {
+	var merged []string
+	for _, level := range levels {
+		for _, cat := range level {
+			if cat = strings.TrimSpace(cat); cat != "" {
+				merged = appendMissing(merged, []string{cat})
+			}
+		}
+	}
+	return merged
+}
+
+// BlockedCategoryLoader loads the effective blocked categories for a placement
+type BlockedCategoryLoader func(ctx context.Context, placementID string) ([]string, error)
+
+// BlockedCategoryCache caches effective blocked categories in memory for a
+// fixed TTL, keyed by placement, site and publisher
+type BlockedCategoryCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	load    BlockedCategoryLoader
+	entries map[blockedCategoryKey]blockedCategoryEntry
+}
+
+type blockedCategoryKey struct {
+	placementID string
+	siteID      string
+	publisherID string
+}
+
+type blockedCategoryEntry struct {
+	categories []string
+	expiresAt  time.Time
+}
+
+// NewBlockedCategoryCache creates a blocked category cache backed by load
+func NewBlockedCategoryCache(ttl time.Duration, load BlockedCategoryLoader) *BlockedCategoryCache {
+	return &BlockedCategoryCache{
+		ttl:     ttl,
+		load:    load,
+		entries: make(map[blockedCategoryKey]blockedCategoryEntry),
+	}
+}
+
+// Get returns the blocked categories for a placement, loading them when missing or expired
+func (c *BlockedCategoryCache) Get(ctx context.Context, placementID, siteID, publisherID string) ([]string, error) {
+	key := blockedCategoryKey{placementID: placementID, siteID: siteID, publisherID: publisherID}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.categories, nil
+	}
+
+	categories, err := c.load(ctx, placementID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = blockedCategoryEntry{
+		categories: categories,
+		expiresAt:  time.Now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+
+	return categories, nil
+}
+
+// Invalidate drops every cached entry involving a placement, site or publisher ID
+func (c *BlockedCategoryCache) Invalidate(id string) {
+	c.mu.Lock()
+	for key := range c.entries {
+		if key.placementID == id || key.siteID == id || key.publisherID == id {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
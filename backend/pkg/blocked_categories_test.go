@@ -0,0 +1,57 @@
+package ssp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeBlockedCategories(t *testing.T) {
+	got := MergeBlockedCategories(
+		[]string{"IAB7-39", "IAB25"},
+		nil,
+		[]string{"IAB25", " IAB26 ", ""},
+	)
+	expected := []string{"IAB7-39", "IAB25", "IAB26"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if got := MergeBlockedCategories(nil, []string{}); got != nil {
+		t.Errorf("Expected nil for no blocks, got %v", got)
+	}
+}
+
+func TestBlockedCategoryCache(t *testing.T) {
+	loads := 0
+	cache := NewBlockedCategoryCache(time.Minute, func(ctx context.Context, placementID string) ([]string, error) {
+		loads++
+		return []string{"IAB25"}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(context.Background(), "placement-1", "site-1", "pub-1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load while cached, got %d", loads)
+	}
+
+	// The placement moved to another site: a new triple is loaded separately
+	if _, err := cache.Get(context.Background(), "placement-1", "site-2", "pub-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected a load for a new site, got %d loads", loads)
+	}
+
+	cache.Invalidate("pub-1")
+	if _, err := cache.Get(context.Background(), "placement-1", "site-1", "pub-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads != 3 {
+		t.Errorf("Expected reload after publisher invalidate, got %d loads", loads)
+	}
+}
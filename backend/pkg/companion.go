@@ -0,0 +1,111 @@
+package ssp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+)
+
+// CompanionAdSpec describes a companion ad displayed alongside a video ad
+type CompanionAdSpec struct {
+	W            int    `json:"w"`
+	H            int    `json:"h"`
+	Resource     string `json:"resource,omitempty"`     // Static resource (image) URI
+	CreativeType string `json:"creativetype,omitempty"` // MIME type; derived from the URI when empty
+	ClickThrough string `json:"clickthrough,omitempty"`
+}
+
+// UnmarshalJSON decodes a bid and extracts companion ads from
+// bid.ext.companionads when present
+func (b *Bid) UnmarshalJSON(data []byte) error {
+	type bidAlias Bid
+	if err := json.Unmarshal(data, (*bidAlias)(b)); err != nil {
+		return err
+	}
+
+	// Companion ads are optional; a malformed ext must not drop the bid
+	var withExt struct {
+		Ext struct {
+			CompanionAds []CompanionAdSpec `json:"companionads"`
+		} `json:"ext"`
+	}
+	if err := json.Unmarshal(data, &withExt); err == nil {
+		b.CompanionAds = withExt.Ext.CompanionAds
+	}
+
+	return nil
+}
+
+// selectCompanions returns the bid companions that fit the placement's companion
+// slots. All companions are eligible when the placement declares no slots.
+func selectCompanions(bid *Bid, placement *Placement) []CompanionAdSpec {
+	if placement.Video == nil || len(placement.Video.CompanionAds) == 0 {
+		return bid.CompanionAds
+	}
+
+	selected := []CompanionAdSpec{}
+	for _, companion := range bid.CompanionAds {
+		for _, slot := range placement.Video.CompanionAds {
+			if companion.W == slot.W && companion.H == slot.H {
+				selected = append(selected, companion)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// companionAdsXML renders a VAST <Creative> containing <CompanionAds>, or an
+// empty string when there are no usable companions
+func companionAdsXML(companions []CompanionAdSpec) string {
+	var b strings.Builder
+
+	for _, companion := range companions {
+		if companion.Resource == "" {
+			continue
+		}
+
+		creativeType := companion.CreativeType
+		if creativeType == "" {
+			creativeType = mime.TypeByExtension(path.Ext(companion.Resource))
+		}
+		if creativeType == "" {
+			creativeType = "image/jpeg"
+		}
+
+		fmt.Fprintf(&b, `
+            <Companion width="%d" height="%d">
+              <StaticResource creativeType="%s"><![CDATA[%s]]></StaticResource>`,
+			companion.W, companion.H, xmlAttrEscape(creativeType), cdataEscape(companion.Resource))
+		if companion.ClickThrough != "" {
+			fmt.Fprintf(&b, `
+              <CompanionClickThrough><![CDATA[%s]]></CompanionClickThrough>`, cdataEscape(companion.ClickThrough))
+		}
+		b.WriteString(`
+            </Companion>`)
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return `
+        <Creative>
+          <CompanionAds>` + b.String() + `
+          </CompanionAds>
+        </Creative>`
+}
+
+// cdataEscape splits each "]]>" in a value placed inside a CDATA section
+// across two sections, so the value cannot end the section early
+func cdataEscape(s string) string {
+	return strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>")
+}
+
+// xmlAttrEscape escapes a value for use inside a double-quoted XML attribute
+func xmlAttrEscape(s string) string {
+	return strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;").Replace(s)
+}
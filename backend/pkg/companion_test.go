@@ -0,0 +1,96 @@
+package ssp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestBidUnmarshalCompanionAds(t *testing.T) {
+	data := `{"id":"b1","impid":"1","price":2.5,"ext":{"companionads":[
+		{"w":300,"h":250,"resource":"https://cdn.example.com/c.png","clickthrough":"https://example.com"},
+		{"w":728,"h":90,"resource":"https://cdn.example.com/l.gif"}]}}`
+
+	var bid Bid
+	if err := json.Unmarshal([]byte(data), &bid); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if bid.Price != 2.5 {
+		t.Errorf("price = %v, want 2.5", bid.Price)
+	}
+	if len(bid.CompanionAds) != 2 {
+		t.Fatalf("got %d companions, want 2", len(bid.CompanionAds))
+	}
+
+	var noExt Bid
+	if err := json.Unmarshal([]byte(`{"id":"b2","ext":{"companionads":"bad"}}`), &noExt); err != nil {
+		t.Fatalf("malformed ext should not fail the bid: %v", err)
+	}
+	if len(noExt.CompanionAds) != 0 {
+		t.Errorf("expected no companions, got %d", len(noExt.CompanionAds))
+	}
+}
+
+func TestGenerateVASTXMLCompanionAds(t *testing.T) {
+	tg := &TagGenerator{}
+	bid := &Bid{
+		ID:  "b1",
+		ADM: "https://cdn.example.com/video.mp4",
+		CompanionAds: []CompanionAdSpec{
+			{W: 300, H: 250, Resource: "https://cdn.example.com/c.png", ClickThrough: "https://example.com"},
+			{W: 728, H: 90, Resource: "https://cdn.example.com/l.gif"},
+		},
+	}
+	placement := &Placement{
+		Width:  640,
+		Height: 360,
+		Video:  &VideoSettings{CompanionAds: []CompanionAdSpec{{W: 300, H: 250}}},
+	}
+
+	vast := tg.GenerateVASTXML(bid, placement, bid.ID)
+	if !strings.Contains(vast, `<Companion width="300" height="250">`) {
+		t.Errorf("missing 300x250 companion:\n%s", vast)
+	}
+	if !strings.Contains(vast, `creativeType="image/png"`) {
+		t.Errorf("missing creative type:\n%s", vast)
+	}
+	if !strings.Contains(vast, "<CompanionClickThrough><![CDATA[https://example.com]]></CompanionClickThrough>") {
+		t.Errorf("missing click-through:\n%s", vast)
+	}
+	if strings.Contains(vast, `width="728"`) {
+		t.Errorf("companion not matching a placement slot was included:\n%s", vast)
+	}
+
+	bid.CompanionAds = nil
+	if strings.Contains(tg.GenerateVASTXML(bid, placement, bid.ID), "<CompanionAds>") {
+		t.Error("expected no CompanionAds element without companions")
+	}
+}
+
+func TestGenerateVASTXMLEscapesCDATA(t *testing.T) {
+	tg := &TagGenerator{}
+	resource := "https://cdn.example.com/c.png?x=]]><Injected/>"
+	bid := &Bid{
+		ID:           "b1",
+		ADM:          "https://example.com/?]]>",
+		IURL:         "https://cdn.example.com/video.mp4",
+		CompanionAds: []CompanionAdSpec{{W: 300, H: 250, Resource: resource}},
+	}
+	placement := &Placement{
+		Width:  640,
+		Height: 360,
+		Video:  &VideoSettings{CompanionAds: []CompanionAdSpec{{W: 300, H: 250}}},
+	}
+
+	var doc struct {
+		Resources []string `xml:"Ad>InLine>Creatives>Creative>CompanionAds>Companion>StaticResource"`
+		Injected  []string `xml:"Ad>InLine>Creatives>Creative>CompanionAds>Companion>Injected"`
+	}
+	if err := xml.Unmarshal([]byte(tg.GenerateVASTXML(bid, placement, bid.ID)), &doc); err != nil {
+		t.Fatalf("Expected well formed VAST, got %v", err)
+	}
+	if len(doc.Resources) != 1 || doc.Resources[0] != resource || len(doc.Injected) != 0 {
+		t.Errorf("Expected the companion resource kept inside CDATA, got %v", doc.Resources)
+	}
+}
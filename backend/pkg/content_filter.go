@@ -0,0 +1,119 @@
+package ssp
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/prebid/openrtb/v20/adcom1"
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+// DefaultAdultFloorPremium raises floors on adult content by 20%
+const DefaultAdultFloorPremium = 0.20
+
+// adultBlockedAttrs are creative attributes blocked on X-rated content
+var adultBlockedAttrs = []adcom1.CreativeAttribute{3, 13}
+
+// newsBlockedCategories are IAB categories that are brand-unsafe next to news content
+var newsBlockedCategories = []string{
+	"IAB7-39", // Sexuality
+	"IAB8-5",  // Cocktails/Beer
+	"IAB8-18", // Wine
+	"IAB9-9",  // Cigars
+	"IAB14-1", // Dating
+	"IAB25",   // Non-Standard Content
+	"IAB26",   // Illegal Content
+}
+
+// ContentFilter applies brand-safety rules to bid requests based on the
+// content the ad will run alongside
+type ContentFilter struct {
+	AdultFloorPremium float64 // Fractional floor increase for X-rated content (0.2 = +20%)
+}
+
+// Apply adds creative attribute blocks, floor premiums and category blocks
+// for the request's content rating and genre
+func (f *ContentFilter) Apply(bidRequest *openrtb2.BidRequest, rating, genre string) {
+	if strings.EqualFold(rating, "X") {
+		for i := range bidRequest.Imp {
+			imp := &bidRequest.Imp[i]
+			if imp.Video != nil {
+				imp.Video.BAttr = appendMissing(imp.Video.BAttr, adultBlockedAttrs)
+			}
+			if imp.Banner != nil {
+				imp.Banner.BAttr = appendMissing(imp.Banner.BAttr, adultBlockedAttrs)
+			}
+
+			imp.BidFloor *= 1 + f.AdultFloorPremium
+			if imp.PMP != nil {
+				for j := range imp.PMP.Deals {
+					imp.PMP.Deals[j].BidFloor *= 1 + f.AdultFloorPremium
+				}
+			}
+		}
+	}
+
+	if strings.EqualFold(genre, "news") {
+		bidRequest.BCat = appendMissing(bidRequest.BCat, newsBlockedCategories)
+	}
+}
+
+// contentRatingRank orders content ratings from least to most mature
+var contentRatingRank = map[string]int{
+	ContentRatingG:    1,
+	ContentRatingPG:   2,
+	ContentRatingPG13: 3,
+	ContentRatingR:    4,
+	ContentRatingX:    5,
+}
+
+// PublicaContentPolicy limits the content Publica SSAI ads may run alongside
+type PublicaContentPolicy struct {
+	MaxContentRating string   // Most mature content rating served; empty serves all ratings
+	AllowedGenres    []string // Genres served without brand-safety category blocks; empty allows all
+}
+
+// AllowsRating reports whether content with rating may be served. Unrated and
+// unrecognized ratings are allowed.
+func (p *PublicaContentPolicy) AllowsRating(rating string) bool {
+	maxRank, ok := contentRatingRank[strings.ToUpper(p.MaxContentRating)]
+	if !ok {
+		return true
+	}
+	rank, ok := contentRatingRank[strings.ToUpper(rating)]
+	return !ok || rank <= maxRank
+}
+
+// Apply blocks creative attributes unsuitable for MaxContentRating and, for
+// genres outside AllowedGenres, the brand-unsafe categories also blocked on news
+func (p *PublicaContentPolicy) Apply(bidRequest *openrtb2.BidRequest, genre string) {
+	if attrs := contentRatingBlockedAttrs[strings.ToUpper(p.MaxContentRating)]; len(attrs) > 0 {
+		blocked := make([]adcom1.CreativeAttribute, len(attrs))
+		for i, attr := range attrs {
+			blocked[i] = adcom1.CreativeAttribute(attr)
+		}
+		for i := range bidRequest.Imp {
+			imp := &bidRequest.Imp[i]
+			if imp.Video != nil {
+				imp.Video.BAttr = appendMissing(imp.Video.BAttr, blocked)
+			}
+			if imp.Banner != nil {
+				imp.Banner.BAttr = appendMissing(imp.Banner.BAttr, blocked)
+			}
+		}
+	}
+
+	if len(p.AllowedGenres) > 0 && !slices.ContainsFunc(p.AllowedGenres, func(g string) bool { return strings.EqualFold(g, genre) }) {
+		bidRequest.BCat = appendMissing(bidRequest.BCat, newsBlockedCategories)
+	}
+}
+
+// appendMissing appends the values not already present in list
+func appendMissing[T comparable](list, values []T) []T {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package ssp
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+func TestPublicaContentFilter(t *testing.T) {
+	h := NewPublicaHandler(nil, "")
+
+	adult := h.convertToOpenRTB(&PublicaSSAIRequest{FloorPrice: 2.0, ContentRating: "X", DealID: "deal-1"})
+	imp := adult.Imp[0]
+	if !slices.Contains(imp.Video.BAttr, 3) || !slices.Contains(imp.Video.BAttr, 13) {
+		t.Errorf("expected battr 3 and 13 for X-rated content, got %v", imp.Video.BAttr)
+	}
+	if math.Abs(imp.BidFloor-2.4) > 1e-9 {
+		t.Errorf("expected floor 2.4 with premium, got %f", imp.BidFloor)
+	}
+	if math.Abs(imp.PMP.Deals[0].BidFloor-2.4) > 1e-9 {
+		t.Errorf("expected deal floor 2.4 with premium, got %f", imp.PMP.Deals[0].BidFloor)
+	}
+
+	news := h.convertToOpenRTB(&PublicaSSAIRequest{FloorPrice: 2.0, ContentGenre: "News"})
+	if !slices.Contains(news.BCat, "IAB25") || !slices.Contains(news.BCat, "IAB26") {
+		t.Errorf("expected brand-unsafe categories blocked for news, got %v", news.BCat)
+	}
+	if news.Imp[0].BidFloor != 2.0 || len(news.Imp[0].Video.BAttr) != 0 {
+		t.Errorf("expected no adult rules for news content, got floor %f battr %v", news.Imp[0].BidFloor, news.Imp[0].Video.BAttr)
+	}
+}
+
+func TestContentFilterNoDuplicates(t *testing.T) {
+	f := &ContentFilter{}
+	req := &openrtb2.BidRequest{BCat: []string{"IAB25"}}
+
+	f.Apply(req, "", "news")
+	f.Apply(req, "", "news")
+
+	count := 0
+	for _, cat := range req.BCat {
+		if cat == "IAB25" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected IAB25 once, got %d times in %v", count, req.BCat)
+	}
+}
+
+func TestPublicaContentPolicy(t *testing.T) {
+	policy := &PublicaContentPolicy{MaxContentRating: ContentRatingPG, AllowedGenres: []string{"Sports", "Kids"}}
+
+	for rating, want := range map[string]bool{"": true, "G": true, "pg": true, "PG13": false, "R": false, "X": false, "TV-Y": true} {
+		if got := policy.AllowsRating(rating); got != want {
+			t.Errorf("AllowsRating(%q) = %v, want %v", rating, got, want)
+		}
+	}
+
+	h := NewPublicaHandler(nil, "")
+	h.ContentPolicy = policy
+
+	sports := h.convertToOpenRTB(&PublicaSSAIRequest{ContentRating: "G", ContentGenre: "sports"})
+	if !slices.Contains(sports.Imp[0].Video.BAttr, 9) || !slices.Contains(sports.Imp[0].Video.BAttr, 10) {
+		t.Errorf("Expected battr 9 and 10 for a PG maximum, got %v", sports.Imp[0].Video.BAttr)
+	}
+	if len(sports.BCat) != 0 {
+		t.Errorf("Expected no category blocks for an allowed genre, got %v", sports.BCat)
+	}
+
+	drama := h.convertToOpenRTB(&PublicaSSAIRequest{ContentRating: "G", ContentGenre: "Drama"})
+	if !slices.Contains(drama.BCat, "IAB25") {
+		t.Errorf("Expected brand-unsafe categories blocked for a genre outside the allowed list, got %v", drama.BCat)
+	}
+
+	if !(&PublicaContentPolicy{}).AllowsRating(ContentRatingX) {
+		t.Error("Expected a policy without a maximum rating to allow all ratings")
+	}
+}
@@ -0,0 +1,208 @@
+package ssp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxCreativeSize is the largest banner markup accepted, in bytes
+const DefaultMaxCreativeSize = 150 * 1024
+
+// Validation severities. Errors are hard failures and the ad must not be served.
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// ValidationWarning describes a problem found in creative markup
+type ValidationWarning struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// HasValidationErrors reports whether any warning is a hard failure
+func HasValidationErrors(warnings []ValidationWarning) bool {
+	for _, w := range warnings {
+		if w.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CreativeValidator checks winning bid markup before it is served
+type CreativeValidator interface {
+	Validate(adm string, adType string) []ValidationWarning
+}
+
+// AdTypeValidator dispatches validation to a validator per ad type.
+// Ad types without a validator are not checked.
+type AdTypeValidator map[string]CreativeValidator
+
+// NewCreativeValidator returns the default validators for banner and video markup
+func NewCreativeValidator() AdTypeValidator {
+	return AdTypeValidator{
+		"banner":            &HTMLValidator{MaxSize: DefaultMaxCreativeSize},
+		"video":             VASTValidator{},
+		AdTypeRewardedVideo: VASTValidator{},
+	}
+}
+
+// Validate validates markup with the validator registered for adType
+func (v AdTypeValidator) Validate(adm string, adType string) []ValidationWarning {
+	validator, ok := v[adType]
+	if !ok {
+		return nil
+	}
+	return validator.Validate(adm, adType)
+}
+
+// Script patterns that hijack the page or steal user data
+var unsafeScriptPatterns = []struct {
+	code    string
+	pattern *regexp.Regexp
+}{
+	{"cookie_access", regexp.MustCompile(`(?i)document\.cookie`)},
+	{"forced_redirect", regexp.MustCompile(`(?i)(top|parent)\.location(\.href)?\s*=`)},
+	{"javascript_source", regexp.MustCompile(`(?i)\bsrc\s*=\s*["']?\s*javascript:`)},
+}
+
+var imgTagPattern = regexp.MustCompile(`(?i)<img\b`)
+
+// HTMLValidator validates banner HTML markup
+type HTMLValidator struct {
+	MaxSize int // Maximum markup size in bytes; 0 disables the check
+}
+
+// Validate checks banner markup for size, script injection and image content
+func (v *HTMLValidator) Validate(adm string, adType string) []ValidationWarning {
+	if strings.TrimSpace(adm) == "" {
+		return []ValidationWarning{{Code: "empty_markup", Severity: SeverityError, Message: "ad markup is empty"}}
+	}
+
+	warnings := []ValidationWarning{}
+
+	if v.MaxSize > 0 && len(adm) > v.MaxSize {
+		warnings = append(warnings, ValidationWarning{
+			Code:     "max_size",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("markup is %d bytes, limit is %d", len(adm), v.MaxSize),
+		})
+	}
+
+	for _, unsafe := range unsafeScriptPatterns {
+		if match := unsafe.pattern.FindString(adm); match != "" {
+			warnings = append(warnings, ValidationWarning{
+				Code:     unsafe.code,
+				Severity: SeverityError,
+				Message:  "markup contains unsafe script: " + match,
+			})
+		}
+	}
+
+	if !imgTagPattern.MatchString(adm) {
+		warnings = append(warnings, ValidationWarning{
+			Code:     "missing_image",
+			Severity: SeverityWarning,
+			Message:  "markup has no <img> element",
+		})
+	}
+
+	return warnings
+}
+
+// vastDurationPattern matches VAST durations (HH:MM:SS or HH:MM:SS.mmm)
+var vastDurationPattern = regexp.MustCompile(`^\d{2}:[0-5]\d:[0-5]\d(\.\d{1,3})?$`)
+
+// vastDocument is the subset of VAST needed for validation
+type vastDocument struct {
+	Ads []struct {
+		InLine *struct {
+			Creatives []struct {
+				Linear *struct {
+					Duration   string   `xml:"Duration"`
+					MediaFiles []string `xml:"MediaFiles>MediaFile"`
+				} `xml:"Linear"`
+			} `xml:"Creatives>Creative"`
+		} `xml:"InLine"`
+		Wrapper *struct {
+			VASTAdTagURI string `xml:"VASTAdTagURI"`
+		} `xml:"Wrapper"`
+	} `xml:"Ad"`
+}
+
+// VASTValidator validates VAST video markup
+type VASTValidator struct{}
+
+// Validate checks VAST markup is well formed with a valid duration and media file.
+// Markup that is not XML (e.g. a VAST tag URL) is accepted with a warning.
+func (VASTValidator) Validate(adm string, adType string) []ValidationWarning {
+	adm = strings.TrimSpace(adm)
+	if adm == "" {
+		return []ValidationWarning{{Code: "empty_markup", Severity: SeverityError, Message: "ad markup is empty"}}
+	}
+
+	if !strings.HasPrefix(adm, "<") {
+		return []ValidationWarning{{Code: "not_vast_xml", Severity: SeverityWarning, Message: "markup is not inline VAST XML"}}
+	}
+
+	var doc vastDocument
+	if err := xml.Unmarshal([]byte(adm), &doc); err != nil {
+		return []ValidationWarning{{Code: "malformed_xml", Severity: SeverityError, Message: err.Error()}}
+	}
+
+	if len(doc.Ads) == 0 {
+		return []ValidationWarning{{Code: "no_ad", Severity: SeverityError, Message: "VAST document contains no <Ad>"}}
+	}
+
+	warnings := []ValidationWarning{}
+	for _, ad := range doc.Ads {
+		if ad.Wrapper != nil {
+			if strings.TrimSpace(ad.Wrapper.VASTAdTagURI) == "" {
+				warnings = append(warnings, ValidationWarning{Code: "missing_ad_tag_uri", Severity: SeverityError, Message: "wrapper has no <VASTAdTagURI>"})
+			}
+			continue
+		}
+		if ad.InLine == nil {
+			warnings = append(warnings, ValidationWarning{Code: "no_ad", Severity: SeverityError, Message: "<Ad> has neither <InLine> nor <Wrapper>"})
+			continue
+		}
+
+		hasLinear := false
+		for _, creative := range ad.InLine.Creatives {
+			linear := creative.Linear
+			if linear == nil {
+				continue
+			}
+			hasLinear = true
+
+			if !vastDurationPattern.MatchString(strings.TrimSpace(linear.Duration)) {
+				warnings = append(warnings, ValidationWarning{
+					Code:     "invalid_duration",
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("invalid duration %q", linear.Duration),
+				})
+			}
+
+			hasMedia := false
+			for _, media := range linear.MediaFiles {
+				if strings.TrimSpace(media) != "" {
+					hasMedia = true
+					break
+				}
+			}
+			if !hasMedia {
+				warnings = append(warnings, ValidationWarning{Code: "missing_media_file", Severity: SeverityError, Message: "linear creative has no <MediaFile>"})
+			}
+		}
+
+		if !hasLinear {
+			warnings = append(warnings, ValidationWarning{Code: "missing_linear", Severity: SeverityError, Message: "inline ad has no linear creative"})
+		}
+	}
+
+	return warnings
+}
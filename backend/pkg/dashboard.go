@@ -0,0 +1,88 @@
+package ssp
+
+import (
+	"sort"
+	"time"
+)
+
+// Publisher dashboard limits
+const (
+	DashboardTopSites      = 5 // Sites included in a publisher dashboard
+	DashboardTopPlacements = 3 // Placements included per dashboard site
+)
+
+// PublisherDashboard combines a publisher's account, totals and top sites and
+// placements for the self-serve dashboard
+type PublisherDashboard struct {
+	Publisher *Publisher       `json:"publisher"`
+	Start     time.Time        `json:"start"`
+	End       time.Time        `json:"end"`
+	Totals    *SupplyStats     `json:"totals"` // Across all of the publisher's sites
+	Sites     []*SiteDashboard `json:"sites"`  // Top sites by revenue
+}
+
+// SiteDashboard is one site in a publisher dashboard with its top placements by revenue
+type SiteDashboard struct {
+	Site          *Site            `json:"site"`
+	Stats         *SupplyStats     `json:"stats"`
+	TopPlacements []*PlacementPerf `json:"topPlacements"`
+}
+
+// SumSupplyStats totals daily stats rows into one, recomputing AvgCPM and CTR
+// from the summed revenue, clicks and impressions
+func SumSupplyStats(rows []*SupplyStats) *SupplyStats {
+	total := &SupplyStats{}
+	for _, row := range rows {
+		if total.PublisherID == "" {
+			total.PublisherID = row.PublisherID
+			total.SiteID = row.SiteID
+			total.PlacementID = row.PlacementID
+		}
+		total.Requests += row.Requests
+		total.Impressions += row.Impressions
+		total.Revenue += row.Revenue
+		total.Fills += row.Fills
+		total.Clicks += row.Clicks
+	}
+	if total.Impressions > 0 {
+		total.AvgCPM = total.Revenue / float64(total.Impressions)
+		total.CTR = float64(total.Clicks) / float64(total.Impressions)
+	}
+	return total
+}
+
+// TopSitesByRevenue returns up to n sites ordered by revenue, highest first.
+// Ties are broken by site ID so the order is stable.
+func TopSitesByRevenue(sites []*SiteDashboard, n int) []*SiteDashboard {
+	sorted := append([]*SiteDashboard{}, sites...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Stats.Revenue != sorted[j].Stats.Revenue {
+			return sorted[i].Stats.Revenue > sorted[j].Stats.Revenue
+		}
+		return sorted[i].Site.ID < sorted[j].Site.ID
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// TopPlacementsForSite picks up to n of a publisher's top placements that
+// belong to a site, keeping their revenue order
+func TopPlacementsForSite(top []*PlacementPerf, sitePlacements []*Placement, n int) []*PlacementPerf {
+	onSite := make(map[string]bool, len(sitePlacements))
+	for _, p := range sitePlacements {
+		onSite[p.ID] = true
+	}
+
+	result := []*PlacementPerf{}
+	for _, perf := range top {
+		if len(result) == n {
+			break
+		}
+		if onSite[perf.PlacementID] {
+			result = append(result, perf)
+		}
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package ssp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSumSupplyStats(t *testing.T) {
+	total := SumSupplyStats([]*SupplyStats{
+		{PublisherID: "pub-1", SiteID: "site-1", Requests: 100, Impressions: 10, Revenue: 20, Fills: 10, Clicks: 1, Date: "2024-01-01"},
+		{PublisherID: "pub-1", SiteID: "site-1", Requests: 300, Impressions: 30, Revenue: 100, Fills: 30, Clicks: 3, Date: "2024-01-02"},
+	})
+
+	if total.SiteID != "site-1" || total.Date != "" {
+		t.Errorf("Expected site-1 totals without a date, got %+v", total)
+	}
+	if total.Requests != 400 || total.Impressions != 40 || total.Revenue != 120 || total.Fills != 40 {
+		t.Errorf("Unexpected totals: %+v", total)
+	}
+	if math.Abs(total.AvgCPM-3.0) > 1e-9 {
+		t.Errorf("Expected weighted average CPM 3.0, got %f", total.AvgCPM)
+	}
+	if total.Clicks != 4 || math.Abs(total.CTR-0.1) > 1e-9 {
+		t.Errorf("Expected 4 clicks at CTR 0.1, got %d at %f", total.Clicks, total.CTR)
+	}
+
+	if empty := SumSupplyStats(nil); empty.Requests != 0 || empty.AvgCPM != 0 {
+		t.Errorf("Expected zero totals for no rows, got %+v", empty)
+	}
+}
+
+func TestTopSitesByRevenue(t *testing.T) {
+	var sites []*SiteDashboard
+	for _, s := range []struct {
+		id      string
+		revenue float64
+	}{{"a", 5}, {"b", 50}, {"c", 20}, {"d", 20}, {"e", 1}, {"f", 0}} {
+		sites = append(sites, &SiteDashboard{Site: &Site{ID: s.id}, Stats: &SupplyStats{Revenue: s.revenue}})
+	}
+
+	top := TopSitesByRevenue(sites, DashboardTopSites)
+	want := []string{"b", "c", "d", "a", "e"}
+	if len(top) != len(want) {
+		t.Fatalf("Expected %d sites, got %d", len(want), len(top))
+	}
+	for i, id := range want {
+		if top[i].Site.ID != id {
+			t.Errorf("Position %d: expected site %s, got %s", i, id, top[i].Site.ID)
+		}
+	}
+}
+
+func TestTopPlacementsForSite(t *testing.T) {
+	top := []*PlacementPerf{
+		{PlacementID: "p1", Revenue: 90},
+		{PlacementID: "other", Revenue: 80},
+		{PlacementID: "p2", Revenue: 70},
+		{PlacementID: "p3", Revenue: 60},
+		{PlacementID: "p4", Revenue: 50},
+	}
+	placements := []*Placement{{ID: "p4"}, {ID: "p3"}, {ID: "p2"}, {ID: "p1"}}
+
+	got := TopPlacementsForSite(top, placements, DashboardTopPlacements)
+	if len(got) != 3 || got[0].PlacementID != "p1" || got[1].PlacementID != "p2" || got[2].PlacementID != "p3" {
+		t.Errorf("Expected p1, p2, p3, got %+v", got)
+	}
+}
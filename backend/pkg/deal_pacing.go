@@ -0,0 +1,108 @@
+package ssp
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DealPacer spreads guaranteed deal delivery across the day. Each deal with a
+// daily impression budget gets an hourly quota of DailyBudget/24, scaled by
+// its DeliveryPacing. Counts are kept in memory per process.
+type DealPacer struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	deals map[string]*dealDelivery
+}
+
+// dealDelivery tracks a deal's budget and deliveries in the current UTC day and hour
+type dealDelivery struct {
+	dailyBudget int64
+	pacing      float64
+	day         time.Time
+	hour        time.Time
+	dayCount    int64
+	hourCount   int64
+}
+
+// NewDealPacer creates an empty deal pacer
+func NewDealPacer() *DealPacer {
+	return &DealPacer{
+		now:   time.Now,
+		deals: make(map[string]*dealDelivery),
+	}
+}
+
+// SetBudget sets a deal's daily impression budget and pacing multiplier
+// (1.0 delivers evenly; 0 is treated as 1.0). A zero budget removes pacing.
+func (p *DealPacer) SetBudget(dealID string, dailyBudget int64, pacing float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dailyBudget <= 0 {
+		delete(p.deals, dealID)
+		return
+	}
+
+	d, ok := p.deals[dealID]
+	if !ok {
+		d = &dealDelivery{}
+		p.deals[dealID] = d
+	}
+	d.dailyBudget = dailyBudget
+	d.pacing = pacing
+}
+
+// ShouldDeliver reports whether a deal is within its daily budget and hourly
+// pacing quota. Deals without a budget always deliver.
+func (p *DealPacer) ShouldDeliver(dealID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d, ok := p.deals[dealID]
+	if !ok {
+		return true
+	}
+	d.roll(p.now())
+
+	return d.dayCount < d.dailyBudget && d.hourCount < d.hourlyQuota()
+}
+
+// RecordDelivery counts a delivery against a deal's budget
+func (p *DealPacer) RecordDelivery(dealID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d, ok := p.deals[dealID]
+	if !ok {
+		return
+	}
+	d.roll(p.now())
+	d.dayCount++
+	d.hourCount++
+}
+
+// roll resets the counters when the UTC day or hour has changed
+func (d *dealDelivery) roll(now time.Time) {
+	now = now.UTC()
+	hour := now.Truncate(time.Hour)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if !day.Equal(d.day) {
+		d.day = day
+		d.dayCount = 0
+	}
+	if !hour.Equal(d.hour) {
+		d.hour = hour
+		d.hourCount = 0
+	}
+}
+
+// hourlyQuota returns the deliveries allowed in one hour, at least one
+func (d *dealDelivery) hourlyQuota() int64 {
+	pacing := d.pacing
+	if pacing <= 0 {
+		pacing = 1
+	}
+	return max(int64(math.Ceil(float64(d.dailyBudget)/24*pacing)), 1)
+}
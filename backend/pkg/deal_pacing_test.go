@@ -0,0 +1,52 @@
+package ssp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDealPacer(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 15, 0, 0, time.UTC)
+	pacer := NewDealPacer()
+	pacer.now = func() time.Time { return now }
+
+	// 48 a day paced at 1.5x allows 3 an hour
+	pacer.SetBudget("deal-1", 48, 1.5)
+	for i := 0; i < 3; i++ {
+		if !pacer.ShouldDeliver("deal-1") {
+			t.Fatalf("Expected delivery %d within the hourly quota", i+1)
+		}
+		pacer.RecordDelivery("deal-1")
+	}
+	if pacer.ShouldDeliver("deal-1") {
+		t.Error("Expected hourly quota to be exhausted")
+	}
+
+	now = now.Add(time.Hour)
+	if !pacer.ShouldDeliver("deal-1") {
+		t.Error("Expected quota to reset in the next hour")
+	}
+
+	if !pacer.ShouldDeliver("unpaced") {
+		t.Error("Expected deals without a budget to always deliver")
+	}
+}
+
+func TestDealPacerDailyBudget(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	pacer := NewDealPacer()
+	pacer.now = func() time.Time { return now }
+
+	// Pacing well ahead of even delivery is still capped by the daily budget
+	pacer.SetBudget("deal-1", 2, 24)
+	pacer.RecordDelivery("deal-1")
+	pacer.RecordDelivery("deal-1")
+	if pacer.ShouldDeliver("deal-1") {
+		t.Error("Expected daily budget to be exhausted")
+	}
+
+	now = now.Add(14 * time.Hour)
+	if !pacer.ShouldDeliver("deal-1") {
+		t.Error("Expected daily budget to reset on the next UTC day")
+	}
+}
@@ -0,0 +1,100 @@
+package ssp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// UnverifiedPublisherID replaces the publisher ID sent to DSPs for ad requests
+// from a domain the publisher has not verified
+const UnverifiedPublisherID = "unverified"
+
+// UnverifiedFloorFactor scales the placement floor for requests from unverified domains
+const UnverifiedFloorFactor = 0.5
+
+// DefaultDomainRegistryTTL is how long a publisher's verified domains are cached
+const DefaultDomainRegistryTTL = 5 * time.Minute
+
+// DomainRegistry reports whether a publisher may sell inventory on a domain
+type DomainRegistry interface {
+	IsAllowed(publisherID, domain string) (bool, error)
+	Invalidate(publisherID string) // Called after the publisher's domains change
+}
+
+// VerifiedDomainLoader loads the domains a publisher has verified
+type VerifiedDomainLoader func(ctx context.Context, publisherID string) ([]string, error)
+
+// RegistrationBasedRegistry allows a domain when it is, or is a subdomain of,
+// one of the publisher's verified domains. Verified domains are cached per
+// publisher for a fixed TTL.
+type RegistrationBasedRegistry struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	load    VerifiedDomainLoader
+	entries map[string]verifiedDomainsEntry
+}
+
+type verifiedDomainsEntry struct {
+	domains   []string
+	expiresAt time.Time
+}
+
+// NewRegistrationBasedRegistry creates a domain registry backed by load
+func NewRegistrationBasedRegistry(ttl time.Duration, load VerifiedDomainLoader) *RegistrationBasedRegistry {
+	return &RegistrationBasedRegistry{
+		ttl:     ttl,
+		load:    load,
+		entries: make(map[string]verifiedDomainsEntry),
+	}
+}
+
+// IsAllowed reports whether domain matches one of the publisher's verified domains
+func (r *RegistrationBasedRegistry) IsAllowed(publisherID, domain string) (bool, error) {
+	domains, err := r.verifiedDomains(publisherID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, verified := range domains {
+		if DomainMatches(verified, domain) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifiedDomains returns the publisher's verified domains, loading them when missing or expired
+func (r *RegistrationBasedRegistry) verifiedDomains(publisherID string) ([]string, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[publisherID]
+	r.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.domains, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	domains, err := r.load(ctx, publisherID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.entries[publisherID] = verifiedDomainsEntry{
+		domains:   domains,
+		expiresAt: time.Now().Add(r.ttl),
+	}
+	r.mu.Unlock()
+
+	return domains, nil
+}
+
+// Invalidate drops the cached domains for a publisher
+func (r *RegistrationBasedRegistry) Invalidate(publisherID string) {
+	r.mu.Lock()
+	delete(r.entries, publisherID)
+	r.mu.Unlock()
+}
@@ -0,0 +1,61 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistrationBasedRegistry(t *testing.T) {
+	loads := 0
+	registry := NewRegistrationBasedRegistry(time.Minute, func(ctx context.Context, publisherID string) ([]string, error) {
+		loads++
+		if publisherID == "pub-1" {
+			return []string{"example.com"}, nil
+		}
+		return []string{}, nil
+	})
+
+	tests := []struct {
+		publisherID string
+		domain      string
+		want        bool
+	}{
+		{"pub-1", "example.com", true},
+		{"pub-1", "news.example.com", true},
+		{"pub-1", "nytimes.com", false},
+		{"pub-1", "notexample.com", false},
+		{"pub-2", "example.com", false},
+	}
+	for _, tt := range tests {
+		allowed, err := registry.IsAllowed(tt.publisherID, tt.domain)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if allowed != tt.want {
+			t.Errorf("IsAllowed(%s, %s) = %v, want %v", tt.publisherID, tt.domain, allowed, tt.want)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("Expected one load per publisher while cached, got %d", loads)
+	}
+
+	registry.Invalidate("pub-1")
+	if _, err := registry.IsAllowed("pub-1", "example.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads != 3 {
+		t.Errorf("Expected a reload after Invalidate, got %d loads", loads)
+	}
+}
+
+func TestRegistrationBasedRegistryLoadError(t *testing.T) {
+	registry := NewRegistrationBasedRegistry(time.Minute, func(ctx context.Context, publisherID string) ([]string, error) {
+		return nil, errors.New("database unavailable")
+	})
+
+	if _, err := registry.IsAllowed("pub-1", "example.com"); err == nil {
+		t.Error("Expected load error to be returned")
+	}
+}
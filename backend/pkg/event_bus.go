@@ -0,0 +1,137 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SSP event types
+const (
+	EventWin                    = "win"                      // Payload: *AuctionNotice
+	EventLoss                   = "loss"                     // Payload: *AuctionNotice
+	EventPublisherStatusChanged = "publisher_status_changed" // Payload: *PublisherStatusChange
+)
+
+// Event bus defaults
+const (
+	DefaultEventQueueSize = 10000
+	DefaultEventWorkers   = 4
+)
+
+// eventHandlerTimeout bounds a single event handler call
+const eventHandlerTimeout = 5 * time.Second
+
+// SSPEvent is an internal event published off the request path
+type SSPEvent struct {
+	Type      string
+	Payload   interface{}
+	Timestamp time.Time
+}
+
+// EventHandler processes a published event
+type EventHandler func(ctx context.Context, event SSPEvent) error
+
+// EventBus decouples side effects such as win and loss notices and webhooks
+// from the code that triggers them
+type EventBus interface {
+	Publish(event SSPEvent)
+	Subscribe(eventType string, handler EventHandler)
+}
+
+// LocalEventBus is an in-process EventBus backed by a buffered channel and a
+// fixed set of workers. Events are dropped when the queue is full.
+type LocalEventBus struct {
+	events chan SSPEvent
+	onDrop func() // Called for each dropped event, e.g. to count drops
+	logger *slog.Logger
+	wg     sync.WaitGroup
+
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// NewLocalEventBus starts workers draining a queue of queueSize events
+func NewLocalEventBus(queueSize, workers int, onDrop func(), logger *slog.Logger) *LocalEventBus {
+	if queueSize <= 0 {
+		queueSize = DefaultEventQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultEventWorkers
+	}
+
+	bus := &LocalEventBus{
+		events:   make(chan SSPEvent, queueSize),
+		onDrop:   onDrop,
+		logger:   logger,
+		handlers: make(map[string][]EventHandler),
+	}
+
+	for i := 0; i < workers; i++ {
+		bus.wg.Add(1)
+		go bus.work()
+	}
+
+	return bus
+}
+
+// Subscribe registers a handler for an event type. Handlers run on the bus
+// workers in the order they were subscribed.
+func (b *LocalEventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mu.Lock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+	b.mu.Unlock()
+}
+
+// Publish queues an event without blocking. Timestamp defaults to now.
+func (b *LocalEventBus) Publish(event SSPEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	select {
+	case b.events <- event:
+	default:
+		if b.onDrop != nil {
+			b.onDrop()
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued events to be handled
+func (b *LocalEventBus) Close() {
+	close(b.events)
+	b.wg.Wait()
+}
+
+func (b *LocalEventBus) work() {
+	defer b.wg.Done()
+
+	for event := range b.events {
+		b.mu.RLock()
+		handlers := b.handlers[event.Type]
+		b.mu.RUnlock()
+
+		for _, handler := range handlers {
+			if err := b.handle(handler, event); err != nil {
+				b.logger.Error("Failed to handle event", "type", event.Type, "error", err)
+			}
+		}
+	}
+}
+
+// handle runs a handler with a timeout, turning a panic into an error so one
+// bad event cannot take down the worker and every event queued behind it
+func (b *LocalEventBus) handle(handler EventHandler, event SSPEvent) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventHandlerTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, event)
+}
@@ -0,0 +1,78 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestLocalEventBus(t *testing.T) {
+	bus := NewLocalEventBus(10, 2, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	handler := func(ctx context.Context, event SSPEvent) error {
+		mu.Lock()
+		received[event.Type]++
+		mu.Unlock()
+		if event.Timestamp.IsZero() {
+			t.Error("Expected published event to have a timestamp")
+		}
+		return nil
+	}
+
+	bus.Subscribe(EventWin, handler)
+	bus.Subscribe(EventWin, handler)
+	bus.Subscribe(EventLoss, func(ctx context.Context, event SSPEvent) error {
+		return errors.New("handler failed")
+	})
+
+	bus.Publish(SSPEvent{Type: EventWin})
+	bus.Publish(SSPEvent{Type: EventLoss})
+	bus.Publish(SSPEvent{Type: EventPublisherStatusChanged}) // No subscribers
+	bus.Close()
+
+	if received[EventWin] != 2 {
+		t.Errorf("Expected both win handlers to run, got %d calls", received[EventWin])
+	}
+}
+
+func TestLocalEventBusDrop(t *testing.T) {
+	drops := 0
+	bus := &LocalEventBus{
+		events:   make(chan SSPEvent, 1),
+		onDrop:   func() { drops++ },
+		handlers: make(map[string][]EventHandler),
+	}
+
+	bus.Publish(SSPEvent{Type: EventWin})
+	bus.Publish(SSPEvent{Type: EventWin})
+
+	if drops != 1 {
+		t.Errorf("Expected 1 dropped event with a full queue, got %d", drops)
+	}
+}
+
+func TestLocalEventBusRecoversPanics(t *testing.T) {
+	bus := NewLocalEventBus(10, 1, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var handled int
+	bus.Subscribe(EventWin, func(ctx context.Context, event SSPEvent) error {
+		panic("bad payload")
+	})
+	bus.Subscribe(EventLoss, func(ctx context.Context, event SSPEvent) error {
+		handled++
+		return nil
+	})
+
+	bus.Publish(SSPEvent{Type: EventWin})
+	bus.Publish(SSPEvent{Type: EventLoss})
+	bus.Close()
+
+	if handled != 1 {
+		t.Errorf("Expected the worker to keep handling events after a panic, got %d calls", handled)
+	}
+}
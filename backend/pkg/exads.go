@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -16,6 +20,121 @@ type EXADSClient struct {
 	endpoint string
 	apiKey   string
 	timeout  time.Duration
+
+	// Extension is injected into BidRequest.Ext as ext.exads; nil sends the request unchanged
+	Extension *EXADSExtension
+}
+
+// SupplyPartner.Ext keys holding EXADS extension fields
+const (
+	EXADSExtZoneID = "EXADS_ZONE_ID"
+	EXADSExtType   = "EXADS_TYPE"
+	EXADSExtSeatID = "EXADS_SEAT_ID"
+)
+
+// EXADSExtension carries the EXADS-specific fields sent in ext.exads of a bid request
+type EXADSExtension struct {
+	ZoneID int    `json:"zone_id,omitempty"`
+	Type   string `json:"type,omitempty"`
+	SeatID string `json:"seat_id,omitempty"`
+}
+
+// EXADSExtensionFromPartner reads the EXADS extension fields from a partner's
+// Ext map. It returns nil when the partner sets none of them.
+func EXADSExtensionFromPartner(partner *SupplyPartner) (*EXADSExtension, error) {
+	zoneID, hasZone := partner.Ext[EXADSExtZoneID]
+	extType, hasType := partner.Ext[EXADSExtType]
+	seatID, hasSeat := partner.Ext[EXADSExtSeatID]
+	if !hasZone && !hasType && !hasSeat {
+		return nil, nil
+	}
+
+	ext := &EXADSExtension{
+		Type:   extString(extType),
+		SeatID: extString(seatID),
+	}
+
+	switch v := zoneID.(type) {
+	case nil:
+	case int:
+		ext.ZoneID = v
+	case float64: // JSON numbers
+		if v != float64(int(v)) {
+			return nil, fmt.Errorf("%s must be an integer, got %v", EXADSExtZoneID, v)
+		}
+		ext.ZoneID = int(v)
+	case string:
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", EXADSExtZoneID, v)
+		}
+		ext.ZoneID = id
+	default:
+		return nil, fmt.Errorf("%s must be an integer, got %T", EXADSExtZoneID, zoneID)
+	}
+
+	return ext, nil
+}
+
+// extString formats an Ext value as a string, treating nil as empty
+func extString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// newEXADSClientForPartner creates an EXADS client carrying the partner's extension fields
+func newEXADSClientForPartner(partner *SupplyPartner) *EXADSClient {
+	client := NewEXADSClient(partner.Endpoint, partner.APIKey, partner.Timeout)
+	client.Extension, _ = EXADSExtensionFromPartner(partner) // Validated by ValidateSupplyPartner
+	return client
+}
+
+// withEXADSExtension returns a copy of bidReq with ext.exads set, keeping any
+// other ext fields. bidReq itself is shared across partners and left unchanged.
+func withEXADSExtension(bidReq *BidRequest, exads *EXADSExtension) (*BidRequest, error) {
+	fields, err := mergeExt(bidReq.Ext, "exads", exads)
+	if err != nil {
+		return nil, fmt.Errorf("bid request %w", err)
+	}
+
+	req := *bidReq
+	req.Ext = fields
+	return &req, nil
+}
+
+// mergeExt returns a copy of an ext object with key set, keeping its other
+// fields. ext may be nil or any value that marshals to a JSON object.
+func mergeExt(ext interface{}, key string, value interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if ext != nil {
+		raw, err := json.Marshal(ext)
+		if err != nil {
+			return nil, fmt.Errorf("ext could not be marshaled: %w", err)
+		}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("ext is not an object: %w", err)
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+	}
+	fields[key] = value
+	return fields, nil
+}
+
+// WithPartnerExtension returns bidReq with the partner-specific ext fields
+// set: ext.exads for EXADS partners. Other partners get bidReq unchanged.
+func WithPartnerExtension(bidReq *BidRequest, partner *SupplyPartner) (*BidRequest, error) {
+	if partner.Type != "exads" {
+		return bidReq, nil
+	}
+	exads, err := EXADSExtensionFromPartner(partner)
+	if err != nil || exads == nil {
+		return bidReq, err
+	}
+	return withEXADSExtension(bidReq, exads)
 }
 
 // NewEXADSClient creates a new EXADS client
@@ -30,8 +149,21 @@ func NewEXADSClient(endpoint, apiKey string, timeout time.Duration) *EXADSClient
 	}
 }
 
-// SendBidRequest sends an OpenRTB 2.5 bid request to EXADS
+// SendBidRequest sends an OpenRTB 2.5 bid request to EXADS with the client's
+// extension fields in ext.exads
 func (ec *EXADSClient) SendBidRequest(ctx context.Context, bidReq *BidRequest) (*BidResponse, error) {
+	return ec.sendBidRequest(ctx, ec.endpoint, bidReq, ec.Extension)
+}
+
+// sendBidRequest sends a bid request to a specific EXADS endpoint
+func (ec *EXADSClient) sendBidRequest(ctx context.Context, endpoint string, bidReq *BidRequest, exads *EXADSExtension) (*BidResponse, error) {
+	if exads != nil {
+		var err error
+		if bidReq, err = withEXADSExtension(bidReq, exads); err != nil {
+			return nil, err
+		}
+	}
+
 	// Marshal bid request
 	reqBody, err := json.Marshal(bidReq)
 	if err != nil {
@@ -39,7 +171,7 @@ func (ec *EXADSClient) SendBidRequest(ctx context.Context, bidReq *BidRequest) (
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", ec.endpoint, bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -110,48 +242,193 @@ func (ec *EXADSClient) SendWinNotice(ctx context.Context, nurl string, price flo
 
 // SupplyPartner represents an external supply partner configuration
 type SupplyPartner struct {
-	ID       string
-	Name     string
-	Type     string // "exads", "openrtb", "custom"
-	Endpoint string
-	APIKey   string
-	Timeout  time.Duration
-	Active   bool
-	QPS      int     // Queries per second limit
-	RevShare float64 // Partner revenue share (0.0-1.0)
+	ID              string        `json:"id"`
+	Name            string        `json:"name"`
+	Type            string        `json:"type"` // "exads", "openrtb", "custom"
+	Endpoint        string        `json:"endpoint"`
+	Endpoints       []string      `json:"endpoints,omitempty"`      // Regional endpoints; overrides Endpoint when set
+	LBStrategy      string        `json:"lbStrategy,omitempty"`     // round_robin (default) or random
+	HealthCheckURL  string        `json:"healthCheckUrl,omitempty"` // Probed by the health checker instead of the bid endpoint
+	APIKey          string        `json:"apiKey,omitempty"`
+	AuthType        string        `json:"authType,omitempty"`        // bearer (default), basic, hmac_sha256 or custom_header
+	AuthHeaderName  string        `json:"authHeaderName,omitempty"`  // custom_header: header sent; hmac_sha256: signature header, default X-Signature
+	AuthHeaderValue string        `json:"authHeaderValue,omitempty"` // custom_header: header value sent
+	SyncOrigins     []string      `json:"syncOrigins,omitempty"`     // Origins allowed to post user syncs, e.g. https://sync.dsp.example
+	Timeout         time.Duration `json:"-"`                         // Serialized as timeoutMs
+	Active          bool          `json:"active"`
+	QPS             int           `json:"qps"`      // Queries per second limit
+	RevShare        float64       `json:"revShare"` // Partner revenue share (0.0-1.0)
+	Priority        int           `json:"priority"` // Auction tie-breaker: lower value wins ties
+
+	// Partner-specific settings, e.g. EXADS_ZONE_ID, EXADS_TYPE and EXADS_SEAT_ID for EXADS
+	Ext map[string]interface{} `json:"ext,omitempty"`
+}
+
+// supplyPartnerJSON is the wire format of SupplyPartner with the timeout in milliseconds
+type supplyPartnerJSON struct {
+	supplyPartnerAlias
+	TimeoutMs int64 `json:"timeoutMs"`
+}
+
+type supplyPartnerAlias SupplyPartner
+
+// MarshalJSON encodes the partner with its timeout in milliseconds
+func (p SupplyPartner) MarshalJSON() ([]byte, error) {
+	return json.Marshal(supplyPartnerJSON{
+		supplyPartnerAlias: supplyPartnerAlias(p),
+		TimeoutMs:          p.Timeout.Milliseconds(),
+	})
+}
+
+// UnmarshalJSON decodes a partner with its timeout in milliseconds
+func (p *SupplyPartner) UnmarshalJSON(data []byte) error {
+	var decoded supplyPartnerJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*p = SupplyPartner(decoded.supplyPartnerAlias)
+	p.Timeout = time.Duration(decoded.TimeoutMs) * time.Millisecond
+	return nil
+}
+
+// ValidateSupplyPartner checks a partner configuration for required fields and sane limits
+func ValidateSupplyPartner(partner *SupplyPartner) error {
+	if partner.ID == "" {
+		return errors.New("partner ID is required")
+	}
+
+	if partner.Type == "" {
+		return errors.New("partner type is required")
+	}
+
+	if partner.Endpoint == "" && len(partner.Endpoints) == 0 {
+		return errors.New("partner endpoint is required")
+	}
+
+	for _, endpoint := range partner.Endpoints {
+		if endpoint == "" {
+			return errors.New("partner endpoints must not be empty")
+		}
+	}
+
+	if _, err := NewLoadBalancer(partner.LBStrategy); err != nil {
+		return err
+	}
+
+	if partner.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", partner.Timeout)
+	}
+
+	if partner.QPS < 0 {
+		return fmt.Errorf("QPS must not be negative, got %d", partner.QPS)
+	}
+
+	if partner.RevShare < 0 || partner.RevShare > 1 {
+		return fmt.Errorf("revShare must be between 0 and 1, got %f", partner.RevShare)
+	}
+
+	if err := validatePartnerAuth(partner); err != nil {
+		return err
+	}
+
+	if _, err := EXADSExtensionFromPartner(partner); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // PartnerManager manages multiple supply partners
 type PartnerManager struct {
+	mu          sync.RWMutex
 	partners    map[string]*SupplyPartner
+	balancers   map[string]LoadBalancer // Per-partner endpoint selection, created on first use
 	exadsClient *EXADSClient
+
+	// Health checker state, see StartHealthChecker
+	healthFailures map[string]int  // Consecutive failed checks per partner
+	healthDisabled map[string]bool // Partners deactivated by the health checker
+
+	// OnHealthCheck is called with the outcome of each partner health check,
+	// e.g. to export partner health as a metric
+	OnHealthCheck func(partnerID string, healthy bool)
+}
+
+// partnerConfig is the serialized form of the partner map
+type partnerConfig struct {
+	Partners []*SupplyPartner `json:"partners"`
 }
 
 // NewPartnerManager creates a new partner manager
 func NewPartnerManager() *PartnerManager {
 	return &PartnerManager{
-		partners: make(map[string]*SupplyPartner),
+		partners:       make(map[string]*SupplyPartner),
+		balancers:      make(map[string]LoadBalancer),
+		healthFailures: make(map[string]int),
+		healthDisabled: make(map[string]bool),
 	}
 }
 
 // AddPartner adds a supply partner
 func (pm *PartnerManager) AddPartner(partner *SupplyPartner) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
 	pm.partners[partner.ID] = partner
+	delete(pm.balancers, partner.ID)
+	pm.resetHealth(partner.ID)
 
-	// Initialize EXADS client if needed
+	// Initialize EXADS client if needed, with the partner's extension fields
 	if partner.Type == "exads" && pm.exadsClient == nil {
-		pm.exadsClient = NewEXADSClient(partner.Endpoint, partner.APIKey, partner.Timeout)
+		pm.exadsClient = newEXADSClientForPartner(partner)
+	}
+}
+
+// UpdatePartner replaces the configuration of an existing supply partner
+func (pm *PartnerManager) UpdatePartner(partner *SupplyPartner) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.partners[partner.ID]; !ok {
+		return fmt.Errorf("partner not found: %s", partner.ID)
 	}
+
+	pm.partners[partner.ID] = partner
+	delete(pm.balancers, partner.ID)
+	pm.resetHealth(partner.ID)
+	return nil
+}
+
+// RemovePartner removes a supply partner, reporting whether it existed
+func (pm *PartnerManager) RemovePartner(id string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.partners[id]; !ok {
+		return false
+	}
+
+	delete(pm.partners, id)
+	delete(pm.balancers, id)
+	pm.resetHealth(id)
+	return true
 }
 
 // GetPartner retrieves a partner by ID
 func (pm *PartnerManager) GetPartner(id string) (*SupplyPartner, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	partner, ok := pm.partners[id]
 	return partner, ok
 }
 
 // GetActivePartners returns all active partners
 func (pm *PartnerManager) GetActivePartners() []*SupplyPartner {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	active := []*SupplyPartner{}
 	for _, p := range pm.partners {
 		if p.Active {
@@ -161,14 +438,116 @@ func (pm *PartnerManager) GetActivePartners() []*SupplyPartner {
 	return active
 }
 
+// GetPartnersOrdered returns the active partners sorted by Priority (lowest
+// first), then by ID, so callers iterate them in a deterministic order
+func (pm *PartnerManager) GetPartnersOrdered() []*SupplyPartner {
+	partners := pm.GetActivePartners()
+	sort.Slice(partners, func(i, j int) bool {
+		if partners[i].Priority != partners[j].Priority {
+			return partners[i].Priority < partners[j].Priority
+		}
+		return partners[i].ID < partners[j].ID
+	})
+	return partners
+}
+
+// MarshalJSON serializes the complete partner configuration, ordered by partner ID.
+// Partners deactivated by the health checker are serialized as active, so a
+// transient outage is never persisted as configuration.
+func (pm *PartnerManager) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pm.config(false))
+}
+
+// ExportJSON serializes the partner configuration like MarshalJSON with every
+// credential replaced by RedactedSecret. Importing the export fails until the
+// credentials are provided again.
+func (pm *PartnerManager) ExportJSON() ([]byte, error) {
+	return json.Marshal(pm.config(true))
+}
+
+// config snapshots the partner configuration, optionally with credentials redacted
+func (pm *PartnerManager) config(redact bool) partnerConfig {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	config := partnerConfig{Partners: make([]*SupplyPartner, 0, len(pm.partners))}
+	for id, p := range pm.partners {
+		if pm.healthDisabled[id] || redact {
+			configured := *p
+			if pm.healthDisabled[id] {
+				configured.Active = true
+			}
+			if redact {
+				redactPartnerAuth(&configured)
+			}
+			p = &configured
+		}
+		config.Partners = append(config.Partners, p)
+	}
+	sort.Slice(config.Partners, func(i, j int) bool {
+		return config.Partners[i].ID < config.Partners[j].ID
+	})
+
+	return config
+}
+
+// UnmarshalJSON replaces the complete partner configuration.
+// Every partner is validated first; on error the existing configuration is left untouched.
+func (pm *PartnerManager) UnmarshalJSON(data []byte) error {
+	var config partnerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid partner config: %w", err)
+	}
+
+	partners := make(map[string]*SupplyPartner, len(config.Partners))
+	var exadsClient *EXADSClient
+	for i, p := range config.Partners {
+		if p == nil {
+			return fmt.Errorf("partner %d: partner is null", i)
+		}
+		if err := ValidateSupplyPartner(p); err != nil {
+			return fmt.Errorf("partner %d: %w", i, err)
+		}
+		if _, exists := partners[p.ID]; exists {
+			return fmt.Errorf("partner %d: duplicate partner ID: %s", i, p.ID)
+		}
+		partners[p.ID] = p
+
+		if p.Type == "exads" && exadsClient == nil {
+			exadsClient = newEXADSClientForPartner(p)
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.partners = partners
+	pm.balancers = make(map[string]LoadBalancer)
+	pm.healthFailures = make(map[string]int)
+	pm.healthDisabled = make(map[string]bool)
+	pm.exadsClient = exadsClient
+	return nil
+}
+
 // SendToPartner sends a bid request to appropriate partner
 func (pm *PartnerManager) SendToPartner(ctx context.Context, partner *SupplyPartner, bidReq *BidRequest) (*BidResponse, error) {
+	endpoint := pm.SelectEndpoint(partner)
+
 	switch partner.Type {
 	case "exads":
+		pm.mu.Lock()
 		if pm.exadsClient == nil {
-			pm.exadsClient = NewEXADSClient(partner.Endpoint, partner.APIKey, partner.Timeout)
+			pm.exadsClient = newEXADSClientForPartner(partner)
 		}
-		return pm.exadsClient.SendBidRequest(ctx, bidReq)
+		exadsClient := pm.exadsClient
+		pm.mu.Unlock()
+
+		// The client is shared by all EXADS partners; send this partner's own extension
+		exads, err := EXADSExtensionFromPartner(partner)
+		if err != nil {
+			return nil, err
+		}
+		return exadsClient.sendBidRequest(ctx, endpoint, bidReq, exads)
 	case "openrtb":
 		// Generic OpenRTB client
 		client := &http.Client{Timeout: partner.Timeout}
@@ -177,17 +556,14 @@ func (pm *PartnerManager) SendToPartner(ctx context.Context, partner *SupplyPart
 			return nil, err
 		}
 
-		req, err := http.NewRequestWithContext(ctx, "POST", partner.Endpoint, bytes.NewReader(reqBody))
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(reqBody))
 		if err != nil {
 			return nil, err
 		}
 
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("X-OpenRTB-Version", "2.5")
-
-		if partner.APIKey != "" {
-			req.Header.Set("Authorization", "Bearer "+partner.APIKey)
-		}
+		applyPartnerAuth(req, partner, reqBody)
 
 		resp, err := client.Do(req)
 		if err != nil {
@@ -213,3 +589,24 @@ func (pm *PartnerManager) SendToPartner(ctx context.Context, partner *SupplyPart
 		return nil, fmt.Errorf("unsupported partner type: %s", partner.Type)
 	}
 }
+
+// SelectEndpoint picks the endpoint for the next request to a partner,
+// load balancing across Endpoints when configured
+func (pm *PartnerManager) SelectEndpoint(partner *SupplyPartner) string {
+	if len(partner.Endpoints) == 0 {
+		return partner.Endpoint
+	}
+
+	pm.mu.Lock()
+	lb, ok := pm.balancers[partner.ID]
+	if !ok {
+		var err error
+		if lb, err = NewLoadBalancer(partner.LBStrategy); err != nil {
+			lb = &RoundRobinLB{}
+		}
+		pm.balancers[partner.ID] = lb
+	}
+	pm.mu.Unlock()
+
+	return lb.Next(partner.Endpoints)
+}
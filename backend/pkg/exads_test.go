@@ -0,0 +1,298 @@
+package ssp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPartnerManagerJSONRoundTrip(t *testing.T) {
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{
+		ID:       "dsp-1",
+		Name:     "DSP One",
+		Type:     "dsp",
+		Endpoint: "https://dsp.example.com/bid",
+		Timeout:  150 * time.Millisecond,
+		Active:   true,
+		QPS:      500,
+		RevShare: 0.2,
+		Priority: 1,
+	})
+	pm.AddPartner(&SupplyPartner{
+		ID:       "exads-1",
+		Name:     "EXADS",
+		Type:     "exads",
+		Endpoint: "https://exads.example.com/rtb",
+		APIKey:   "secret",
+		Timeout:  100 * time.Millisecond,
+		QPS:      1000,
+		RevShare: 0.3,
+	})
+
+	data, err := json.Marshal(pm)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	restored := NewPartnerManager()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	partner, ok := restored.GetPartner("dsp-1")
+	if !ok {
+		t.Fatal("Expected partner dsp-1 after import")
+	}
+	if partner.Timeout != 150*time.Millisecond {
+		t.Errorf("Expected timeout 150ms, got %s", partner.Timeout)
+	}
+	if partner.Priority != 1 || partner.QPS != 500 || !partner.Active {
+		t.Errorf("Partner fields not restored: %+v", partner)
+	}
+
+	if _, ok := restored.GetPartner("exads-1"); !ok {
+		t.Error("Expected partner exads-1 after import")
+	}
+	if len(restored.GetActivePartners()) != 1 {
+		t.Errorf("Expected 1 active partner, got %d", len(restored.GetActivePartners()))
+	}
+}
+
+func TestPartnerManagerExportRedactsCredentials(t *testing.T) {
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "bearer", Type: "dsp", Endpoint: "https://a.example.com", APIKey: "bearer-token"})
+	pm.AddPartner(&SupplyPartner{ID: "basic", Type: "dsp", Endpoint: "https://b.example.com", AuthType: PartnerAuthBasic, APIKey: "user:password"})
+	pm.AddPartner(&SupplyPartner{ID: "hmac", Type: "dsp", Endpoint: "https://c.example.com", AuthType: PartnerAuthHMACSHA256, APIKey: "signing-key"})
+	pm.AddPartner(&SupplyPartner{ID: "header", Type: "dsp", Endpoint: "https://d.example.com", AuthType: PartnerAuthCustomHeader, AuthHeaderName: "X-Token", AuthHeaderValue: "header-secret"})
+
+	data, err := pm.ExportJSON()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	for _, secret := range []string{"bearer-token", "password", "signing-key", "header-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be redacted from the export", secret)
+		}
+	}
+	if !strings.Contains(string(data), `"user:`+RedactedSecret+`"`) {
+		t.Error("Expected the basic auth username to be kept")
+	}
+	if p, _ := pm.GetPartner("bearer"); p.APIKey != "bearer-token" {
+		t.Error("Expected export not to modify the live partner")
+	}
+
+	restored := NewPartnerManager()
+	if err := restored.UnmarshalJSON(data); err == nil {
+		t.Fatal("Expected import of redacted credentials to fail")
+	}
+
+	filled := strings.NewReplacer(
+		`"apiKey":"`+RedactedSecret+`"`, `"apiKey":"new-key"`,
+		`"user:`+RedactedSecret+`"`, `"user:new-password"`,
+		`"authHeaderValue":"`+RedactedSecret+`"`, `"authHeaderValue":"new-secret"`,
+	).Replace(string(data))
+	if err := restored.UnmarshalJSON([]byte(filled)); err != nil {
+		t.Fatalf("Expected import with credentials provided again to succeed: %v", err)
+	}
+}
+
+func TestPartnerManagerUnmarshalInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"Malformed JSON", `{"partners": [`},
+		{"Missing ID", `{"partners": [{"type": "dsp", "endpoint": "https://dsp.example.com"}]}`},
+		{"Missing endpoint", `{"partners": [{"id": "dsp-1", "type": "dsp"}]}`},
+		{"Invalid rev share", `{"partners": [{"id": "dsp-1", "type": "dsp", "endpoint": "https://dsp.example.com", "revShare": 1.5}]}`},
+		{"Duplicate ID", `{"partners": [
+			{"id": "dsp-1", "type": "dsp", "endpoint": "https://a.example.com"},
+			{"id": "dsp-1", "type": "dsp", "endpoint": "https://b.example.com"}
+		]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPartnerManager()
+			pm.AddPartner(&SupplyPartner{ID: "existing", Type: "dsp", Endpoint: "https://dsp.example.com", Active: true})
+
+			if err := pm.UnmarshalJSON([]byte(tt.config)); err == nil {
+				t.Fatal("Expected error but got none")
+			}
+
+			// Existing configuration must survive a failed import
+			if _, ok := pm.GetPartner("existing"); !ok {
+				t.Error("Expected existing partner to be kept after failed import")
+			}
+		})
+	}
+}
+
+func TestPartnerManagerUpdateRemove(t *testing.T) {
+	pm := NewPartnerManager()
+
+	if err := pm.UpdatePartner(&SupplyPartner{ID: "missing"}); err == nil {
+		t.Error("Expected error updating unknown partner")
+	}
+
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Name: "DSP One", Active: true})
+	if err := pm.UpdatePartner(&SupplyPartner{ID: "dsp-1", Name: "DSP One", Active: false}); err != nil {
+		t.Fatalf("UpdatePartner failed: %v", err)
+	}
+	if len(pm.GetActivePartners()) != 0 {
+		t.Error("Expected updated partner to be inactive")
+	}
+
+	if !pm.RemovePartner("dsp-1") {
+		t.Error("Expected RemovePartner to report an existing partner")
+	}
+	if _, ok := pm.GetPartner("dsp-1"); ok {
+		t.Error("Expected partner to be removed")
+	}
+	if pm.RemovePartner("dsp-1") {
+		t.Error("Expected RemovePartner to report a missing partner")
+	}
+}
+
+func TestPartnerManagerGetPartnersOrdered(t *testing.T) {
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-c", Active: true, Priority: 1})
+	pm.AddPartner(&SupplyPartner{ID: "dsp-b", Active: true, Priority: 2})
+	pm.AddPartner(&SupplyPartner{ID: "dsp-a", Active: true, Priority: 1})
+	pm.AddPartner(&SupplyPartner{ID: "dsp-d", Active: false, Priority: 0})
+
+	expected := []string{"dsp-a", "dsp-c", "dsp-b"}
+	for i := 0; i < 5; i++ {
+		partners := pm.GetPartnersOrdered()
+		if len(partners) != len(expected) {
+			t.Fatalf("Expected %d active partners, got %d", len(expected), len(partners))
+		}
+		for j, p := range partners {
+			if p.ID != expected[j] {
+				t.Errorf("Expected partner %d to be %s, got %s", j, expected[j], p.ID)
+			}
+		}
+	}
+}
+
+// Run with -race to detect unsynchronized access to the partner map
+func TestPartnerManagerConcurrentAccess(t *testing.T) {
+	pm := NewPartnerManager()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := fmt.Sprintf("partner-%d-%d", i, j%5)
+				pm.AddPartner(&SupplyPartner{ID: id, Type: "dsp", Active: true})
+				pm.UpdatePartner(&SupplyPartner{ID: id, Type: "dsp", Active: j%2 == 0})
+				if j%3 == 0 {
+					pm.RemovePartner(id)
+				}
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pm.GetActivePartners()
+				pm.GetPartner(fmt.Sprintf("partner-%d-%d", i, j%5))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEXADSExtensionInjected(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// Ext as decoded from a JSON partner config
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{
+		ID:       "exads-1",
+		Type:     "exads",
+		Endpoint: server.URL,
+		Timeout:  time.Second,
+		Ext: map[string]interface{}{
+			EXADSExtZoneID: float64(4242),
+			EXADSExtType:   "banner",
+			EXADSExtSeatID: "seat-7",
+		},
+	})
+	partner, _ := pm.GetPartner("exads-1")
+
+	bidReq := &BidRequest{ID: "req-1", Ext: map[string]interface{}{"prebid": true}}
+	if _, err := pm.SendToPartner(context.Background(), partner, bidReq); err != nil {
+		t.Fatalf("SendToPartner failed: %v", err)
+	}
+
+	ext, _ := received["ext"].(map[string]interface{})
+	exads, _ := ext["exads"].(map[string]interface{})
+	if exads == nil {
+		t.Fatalf("Expected ext.exads in request, got %v", received["ext"])
+	}
+	if exads["zone_id"] != float64(4242) || exads["type"] != "banner" || exads["seat_id"] != "seat-7" {
+		t.Errorf("Unexpected ext.exads: %v", exads)
+	}
+	if ext["prebid"] != true {
+		t.Error("Expected existing ext fields to be kept")
+	}
+	if _, ok := bidReq.Ext.(map[string]interface{})["exads"]; ok {
+		t.Error("Expected the shared bid request to be left unchanged")
+	}
+}
+
+func TestEXADSExtensionFromPartner(t *testing.T) {
+	ext, err := EXADSExtensionFromPartner(&SupplyPartner{})
+	if err != nil || ext != nil {
+		t.Errorf("Expected no extension for a partner without ext fields, got %v, %v", ext, err)
+	}
+
+	ext, err = EXADSExtensionFromPartner(&SupplyPartner{Ext: map[string]interface{}{EXADSExtZoneID: "17"}})
+	if err != nil || ext == nil || ext.ZoneID != 17 {
+		t.Errorf("Expected zone ID 17 from a string, got %v, %v", ext, err)
+	}
+
+	for _, zoneID := range []interface{}{"abc", 1.5, true} {
+		partner := &SupplyPartner{ID: "exads-1", Type: "exads", Endpoint: "https://exads.example.com", Ext: map[string]interface{}{EXADSExtZoneID: zoneID}}
+		if err := ValidateSupplyPartner(partner); err == nil {
+			t.Errorf("Expected zone ID %v to be rejected", zoneID)
+		}
+	}
+}
+
+func TestWithPartnerExtension(t *testing.T) {
+	bidReq := &BidRequest{ID: "req-1"}
+
+	got, err := WithPartnerExtension(bidReq, &SupplyPartner{Type: "openrtb", Ext: map[string]interface{}{EXADSExtZoneID: 1}})
+	if err != nil || got != bidReq {
+		t.Errorf("Expected non-EXADS partners to get the request unchanged, got %v, %v", got, err)
+	}
+
+	got, err = WithPartnerExtension(bidReq, &SupplyPartner{Type: "exads", Ext: map[string]interface{}{EXADSExtZoneID: "17", EXADSExtSeatID: "seat-7"}})
+	if err != nil {
+		t.Fatalf("WithPartnerExtension failed: %v", err)
+	}
+	ext, _ := got.Ext.(map[string]interface{})
+	if exads, _ := ext["exads"].(*EXADSExtension); exads == nil || exads.ZoneID != 17 || exads.SeatID != "seat-7" {
+		t.Errorf("Unexpected ext.exads: %v", ext["exads"])
+	}
+	if bidReq.Ext != nil {
+		t.Error("Expected the shared bid request to be left unchanged")
+	}
+}
@@ -0,0 +1,56 @@
+package ssp
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoEnricher resolves request IP addresses to locations using a MaxMind GeoIP2/GeoLite2 City database
+type GeoEnricher struct {
+	db *geoip2.Reader
+}
+
+// NewGeoEnricher opens a MaxMind City database
+func NewGeoEnricher(dbPath string) (*GeoEnricher, error) {
+	db, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+
+	return &GeoEnricher{db: db}, nil
+}
+
+// Lookup returns the IP-derived location for an address
+func (g *GeoEnricher) Lookup(ip string) (*Geo, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	record, err := g.db.City(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("GeoIP lookup failed: %w", err)
+	}
+
+	geo := &Geo{
+		Type:    2, // IP address
+		Country: CountryAlpha3(record.Country.IsoCode),
+		City:    record.City.Names["en"],
+		ZIP:     record.Postal.Code,
+		Lat:     record.Location.Latitude,
+		Lon:     record.Location.Longitude,
+	}
+
+	if len(record.Subdivisions) > 0 {
+		geo.Region = record.Subdivisions[0].IsoCode
+	}
+
+	return geo, nil
+}
+
+// Close closes the GeoIP database
+func (g *GeoEnricher) Close() error {
+	return g.db.Close()
+}
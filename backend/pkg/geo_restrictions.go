@@ -0,0 +1,122 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Geo restriction types
+const (
+	GeoRestrictionAllow = "allow"
+	GeoRestrictionBlock = "block"
+)
+
+// GeoRestriction allows or blocks a country for a placement
+type GeoRestriction struct {
+	Country string `json:"country"` // ISO-3166-1 Alpha-3
+	Type    string `json:"type"`    // "allow" or "block"
+}
+
+// ValidateGeoRestrictions checks country codes and restriction types, and rejects duplicate countries
+func ValidateGeoRestrictions(restrictions []GeoRestriction) error {
+	seen := make(map[string]bool, len(restrictions))
+	for i, r := range restrictions {
+		if !IsCountryAlpha3(r.Country) {
+			return fmt.Errorf("restriction %d: invalid ISO-3166-1 alpha-3 country code: %q", i, r.Country)
+		}
+
+		if r.Type != GeoRestrictionAllow && r.Type != GeoRestrictionBlock {
+			return fmt.Errorf("restriction %d: type must be %q or %q, got %q", i, GeoRestrictionAllow, GeoRestrictionBlock, r.Type)
+		}
+
+		if seen[r.Country] {
+			return fmt.Errorf("restriction %d: duplicate country: %s", i, r.Country)
+		}
+		seen[r.Country] = true
+	}
+
+	return nil
+}
+
+// GeoAllowed reports whether a country may be served under the given restrictions.
+// Blocked countries are always rejected; when any allow rule exists, only allowed
+// countries are served.
+func GeoAllowed(restrictions []GeoRestriction, country string) bool {
+	hasAllowList := false
+	allowed := false
+
+	for _, r := range restrictions {
+		switch r.Type {
+		case GeoRestrictionBlock:
+			if r.Country == country {
+				return false
+			}
+		case GeoRestrictionAllow:
+			hasAllowList = true
+			if r.Country == country {
+				allowed = true
+			}
+		}
+	}
+
+	return !hasAllowList || allowed
+}
+
+// GeoRestrictionLoader loads the geo restrictions for a placement
+type GeoRestrictionLoader func(ctx context.Context, placementID string) ([]GeoRestriction, error)
+
+// GeoRestrictionCache caches placement geo restrictions in memory for a fixed TTL
+type GeoRestrictionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	load    GeoRestrictionLoader
+	entries map[string]geoRestrictionEntry
+}
+
+type geoRestrictionEntry struct {
+	restrictions []GeoRestriction
+	expiresAt    time.Time
+}
+
+// NewGeoRestrictionCache creates a geo restriction cache backed by load
+func NewGeoRestrictionCache(ttl time.Duration, load GeoRestrictionLoader) *GeoRestrictionCache {
+	return &GeoRestrictionCache{
+		ttl:     ttl,
+		load:    load,
+		entries: make(map[string]geoRestrictionEntry),
+	}
+}
+
+// Get returns the restrictions for a placement, loading them when missing or expired
+func (c *GeoRestrictionCache) Get(ctx context.Context, placementID string) ([]GeoRestriction, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[placementID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.restrictions, nil
+	}
+
+	restrictions, err := c.load(ctx, placementID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[placementID] = geoRestrictionEntry{
+		restrictions: restrictions,
+		expiresAt:    time.Now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+
+	return restrictions, nil
+}
+
+// Invalidate drops the cached restrictions for a placement
+func (c *GeoRestrictionCache) Invalidate(placementID string) {
+	c.mu.Lock()
+	delete(c.entries, placementID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,78 @@
+package ssp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGeoAllowed(t *testing.T) {
+	tests := []struct {
+		name         string
+		restrictions []GeoRestriction
+		country      string
+		expected     bool
+	}{
+		{"No restrictions", nil, "USA", true},
+		{"Blocked country", []GeoRestriction{{Country: "RUS", Type: GeoRestrictionBlock}}, "RUS", false},
+		{"Not blocked", []GeoRestriction{{Country: "RUS", Type: GeoRestrictionBlock}}, "USA", true},
+		{"Allowed country", []GeoRestriction{{Country: "USA", Type: GeoRestrictionAllow}}, "USA", true},
+		{"Outside allow list", []GeoRestriction{{Country: "USA", Type: GeoRestrictionAllow}}, "CAN", false},
+		{"Block with allow list", []GeoRestriction{
+			{Country: "USA", Type: GeoRestrictionAllow},
+			{Country: "CAN", Type: GeoRestrictionBlock},
+		}, "CAN", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GeoAllowed(tt.restrictions, tt.country); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateGeoRestrictions(t *testing.T) {
+	valid := []GeoRestriction{{Country: "USA", Type: GeoRestrictionAllow}, {Country: "GBR", Type: GeoRestrictionBlock}}
+	if err := ValidateGeoRestrictions(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := [][]GeoRestriction{
+		{{Country: "US", Type: GeoRestrictionAllow}},
+		{{Country: "XYZ", Type: GeoRestrictionAllow}},
+		{{Country: "USA", Type: "deny"}},
+		{{Country: "USA", Type: GeoRestrictionAllow}, {Country: "USA", Type: GeoRestrictionBlock}},
+	}
+	for _, restrictions := range invalid {
+		if err := ValidateGeoRestrictions(restrictions); err == nil {
+			t.Errorf("Expected error for %+v", restrictions)
+		}
+	}
+}
+
+func TestGeoRestrictionCache(t *testing.T) {
+	loads := 0
+	cache := NewGeoRestrictionCache(time.Minute, func(ctx context.Context, placementID string) ([]GeoRestriction, error) {
+		loads++
+		return []GeoRestriction{{Country: "USA", Type: GeoRestrictionAllow}}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(context.Background(), "placement-1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load while cached, got %d", loads)
+	}
+
+	cache.Invalidate("placement-1")
+	if _, err := cache.Get(context.Background(), "placement-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected reload after invalidate, got %d loads", loads)
+	}
+}
@@ -0,0 +1,176 @@
+package ssp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// InvoiceMonthFormat is the layout of invoice billing months, e.g. 2024-01
+const InvoiceMonthFormat = "2006-01"
+
+// InvoiceIssuer identifies the SSP on publisher invoices
+type InvoiceIssuer struct {
+	Name    string
+	Address string // May span several lines
+	Email   string
+}
+
+// Invoice is a publisher's monthly revenue statement
+type Invoice struct {
+	Number       string // Payment reference number
+	Month        time.Time
+	IssuedAt     time.Time
+	Issuer       InvoiceIssuer
+	Publisher    *Publisher
+	Lines        []DailyImpression
+	Impressions  int64
+	GrossRevenue float64
+	RevShare     float64 // Effective publisher revenue share over the month (0.0-1.0)
+	NetPayout    float64
+}
+
+// NewInvoice totals a publisher's daily revenue for a billing month and
+// applies the rev share in effect on each day to get the net payout. Days not
+// covered by history fall back to the publisher's current rev share.
+func NewInvoice(issuer InvoiceIssuer, pub *Publisher, month time.Time, lines []DailyImpression, history []*RevShareHistory, issuedAt time.Time) *Invoice {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	inv := &Invoice{
+		Number:    InvoiceNumber(pub.ID, month),
+		Month:     month,
+		IssuedAt:  issuedAt,
+		Issuer:    issuer,
+		Publisher: pub,
+		Lines:     lines,
+		RevShare:  revShareAt(history, month, pub.RevShare),
+	}
+
+	for _, line := range lines {
+		inv.Impressions += line.Impressions
+		inv.GrossRevenue += line.Revenue
+		inv.NetPayout += line.Revenue * revShareAt(history, line.Date, pub.RevShare)
+	}
+	if inv.GrossRevenue > 0 {
+		inv.RevShare = inv.NetPayout / inv.GrossRevenue
+	}
+
+	return inv
+}
+
+// revShareAt returns the rev share of the history period covering t, or
+// fallback if none does
+func revShareAt(history []*RevShareHistory, t time.Time, fallback float64) float64 {
+	for _, h := range history {
+		if t.Before(h.EffectiveFrom) {
+			continue
+		}
+		if h.EffectiveTo == nil || t.Before(*h.EffectiveTo) {
+			return h.RevShare
+		}
+	}
+	return fallback
+}
+
+// InvoiceNumber returns the payment reference for a publisher's billing month,
+// e.g. INV-202401-3F2A9C1E. It is stable so a re-issued invoice keeps its reference.
+func InvoiceNumber(publisherID string, month time.Time) string {
+	id := strings.ToUpper(strings.ReplaceAll(publisherID, "-", ""))
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return fmt.Sprintf("INV-%s-%s", month.Format("200601"), id)
+}
+
+// Filename returns the attachment filename for the invoice PDF
+func (inv *Invoice) Filename() string {
+	return fmt.Sprintf("invoice-%s.pdf", inv.Month.Format(InvoiceMonthFormat))
+}
+
+// WritePDF renders the invoice as an A4 PDF
+func (inv *Invoice) WritePDF(w io.Writer) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+inv.Number, true)
+	pdf.SetAuthor(inv.Issuer.Name, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+
+	// Core fonts are cp1252; translate names and addresses from UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 10, "INVOICE", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, "Reference: "+inv.Number, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Issued: "+inv.IssuedAt.UTC().Format("2006-01-02"), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Period: "+inv.Month.Format("January 2006"), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	// From / Bill to blocks
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 6, "From", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr(joinLines(inv.Issuer.Name, inv.Issuer.Address, inv.Issuer.Email)), "", "L", false)
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 6, "Bill to", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr(joinLines(inv.Publisher.Name, inv.Publisher.Email, inv.Publisher.PaymentInfo)), "", "L", false)
+	pdf.Ln(6)
+
+	// Daily line items
+	const dateW, impW, revW = 60.0, 55.0, 55.0
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(dateW, 7, "Date", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(impW, 7, "Impressions", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(revW, 7, "Revenue (USD)", "1", 1, "R", true, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range inv.Lines {
+		pdf.CellFormat(dateW, 6, line.Date.UTC().Format("2006-01-02"), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(impW, 6, fmt.Sprintf("%d", line.Impressions), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(revW, 6, fmt.Sprintf("%.2f", line.Revenue), "1", 1, "R", false, 0, "")
+	}
+	if len(inv.Lines) == 0 {
+		pdf.CellFormat(dateW+impW+revW, 6, "No impressions in this period", "1", 1, "C", false, 0, "")
+	}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(dateW, 7, "Total", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(impW, 7, fmt.Sprintf("%d", inv.Impressions), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(revW, 7, fmt.Sprintf("%.2f", inv.GrossRevenue), "1", 1, "R", true, 0, "")
+	pdf.Ln(6)
+
+	// Summary
+	const labelW = dateW + impW
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(labelW, 6, "Gross revenue", "", 0, "R", false, 0, "")
+	pdf.CellFormat(revW, 6, fmt.Sprintf("$%.2f", inv.GrossRevenue), "", 1, "R", false, 0, "")
+	pdf.CellFormat(labelW, 6, "Publisher revenue share", "", 0, "R", false, 0, "")
+	pdf.CellFormat(revW, 6, fmt.Sprintf("%.1f%%", inv.RevShare*100), "", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(labelW, 8, "Net payout", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(revW, 8, fmt.Sprintf("$%.2f", inv.NetPayout), "T", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(0, 5, "Please quote reference "+inv.Number+" in all correspondence about this payment.", "", "L", false)
+
+	return pdf.Output(w)
+}
+
+// joinLines joins the non-empty parts of an address block with newlines
+func joinLines(parts ...string) string {
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			lines = append(lines, part)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package ssp
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewInvoice(t *testing.T) {
+	pub := &Publisher{
+		ID:          "3f2a9c1e-0b7d-4e8a-9c1f-2d3e4f5a6b7c",
+		Name:        "Café Media",
+		Email:       "billing@cafe.example",
+		RevShare:    0.70,
+		PaymentInfo: "1 Main St\nSpringfield",
+	}
+	lines := []DailyImpression{
+		{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Impressions: 1000, Revenue: 2.50},
+		{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Impressions: 3000, Revenue: 7.50},
+	}
+
+	inv := NewInvoice(InvoiceIssuer{Name: "AdNexus"}, pub, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), lines, nil, time.Now())
+
+	if inv.Number != "INV-202401-3F2A9C1E" {
+		t.Errorf("Expected reference INV-202401-3F2A9C1E, got %s", inv.Number)
+	}
+	if inv.Filename() != "invoice-2024-01.pdf" {
+		t.Errorf("Expected filename invoice-2024-01.pdf, got %s", inv.Filename())
+	}
+	if inv.Impressions != 4000 {
+		t.Errorf("Expected 4000 impressions, got %d", inv.Impressions)
+	}
+	if math.Abs(inv.GrossRevenue-10.0) > 1e-9 {
+		t.Errorf("Expected gross revenue 10.0, got %f", inv.GrossRevenue)
+	}
+	if math.Abs(inv.NetPayout-7.0) > 1e-9 {
+		t.Errorf("Expected net payout 7.0, got %f", inv.NetPayout)
+	}
+
+	var buf bytes.Buffer
+	if err := inv.WritePDF(&buf); err != nil {
+		t.Fatalf("WritePDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Error("Expected output to be a PDF document")
+	}
+}
+
+func TestNewInvoiceRevShareChangeMidMonth(t *testing.T) {
+	pub := &Publisher{ID: "pub-1", Name: "Growing Site", RevShare: 0.80}
+	changed := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	history := []*RevShareHistory{
+		{PublisherID: "pub-1", RevShare: 0.60, EffectiveFrom: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), EffectiveTo: &changed},
+		{PublisherID: "pub-1", RevShare: 0.80, EffectiveFrom: changed},
+	}
+	lines := []DailyImpression{
+		{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Impressions: 1000, Revenue: 10.0},
+		{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Impressions: 1000, Revenue: 10.0},
+		{Date: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), Impressions: 1000, Revenue: 10.0},
+	}
+
+	inv := NewInvoice(InvoiceIssuer{Name: "AdNexus"}, pub, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), lines, history, time.Now())
+
+	// Days before the change are paid at the old rate, not the current one
+	if math.Abs(inv.NetPayout-20.0) > 1e-9 {
+		t.Errorf("Expected net payout 20.0 (6+6+8), got %f", inv.NetPayout)
+	}
+	if math.Abs(inv.RevShare-20.0/30.0) > 1e-9 {
+		t.Errorf("Expected effective rev share %f, got %f", 20.0/30.0, inv.RevShare)
+	}
+}
+
+func TestInvoiceWithoutImpressions(t *testing.T) {
+	pub := &Publisher{ID: "pub-1", Name: "Quiet Site", RevShare: 0.80}
+
+	inv := NewInvoice(InvoiceIssuer{Name: "AdNexus"}, pub, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), nil, nil, time.Now())
+
+	if inv.Number != "INV-202402-PUB1" {
+		t.Errorf("Expected reference INV-202402-PUB1, got %s", inv.Number)
+	}
+	if inv.GrossRevenue != 0 || inv.NetPayout != 0 {
+		t.Errorf("Expected zero totals, got gross %f net %f", inv.GrossRevenue, inv.NetPayout)
+	}
+
+	var buf bytes.Buffer
+	if err := inv.WritePDF(&buf); err != nil {
+		t.Fatalf("WritePDF failed: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package ssp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultBotUAPatterns matches well-known crawler user agents
+var DefaultBotUAPatterns = []string{
+	`(?i)Googlebot`,
+	`(?i)AdsBot`,
+	`(?i)Mediapartners`,
+}
+
+// IVTFilter detects invalid traffic (IVT) before a request reaches the auction
+type IVTFilter interface {
+	IsInvalid(ip, ua string) bool
+}
+
+// IPBlacklistFilter flags requests originating from known datacenter or bot IP ranges
+type IPBlacklistFilter struct {
+	networks []*net.IPNet
+}
+
+// NewIPBlacklistFilter creates an IP blacklist filter from CIDR ranges.
+// Bare IP addresses are accepted and treated as single-host ranges.
+func NewIPBlacklistFilter(cidrs []string) (*IPBlacklistFilter, error) {
+	filter := &IPBlacklistFilter{}
+
+	for _, entry := range cidrs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %s: %w", entry, err)
+		}
+		filter.networks = append(filter.networks, network)
+	}
+
+	return filter, nil
+}
+
+// LoadIPBlacklistFile reads CIDR ranges from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func LoadIPBlacklistFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IP blacklist: %w", err)
+	}
+	defer file.Close()
+
+	cidrs := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cidrs = append(cidrs, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IP blacklist: %w", err)
+	}
+
+	return cidrs, nil
+}
+
+// IsInvalid reports whether the IP falls within a blacklisted range
+func (f *IPBlacklistFilter) IsInvalid(ip, ua string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range f.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of blacklisted ranges
+func (f *IPBlacklistFilter) Len() int {
+	return len(f.networks)
+}
+
+// UABotFilter flags requests whose User-Agent matches known bot patterns
+type UABotFilter struct {
+	patterns []*regexp.Regexp
+}
+
+// NewUABotFilter creates a User-Agent bot filter from the default patterns
+// plus any custom regular expressions
+func NewUABotFilter(customPatterns []string) (*UABotFilter, error) {
+	filter := &UABotFilter{}
+
+	patterns := append([]string{}, DefaultBotUAPatterns...)
+	patterns = append(patterns, customPatterns...)
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bot UA pattern %q: %w", pattern, err)
+		}
+		filter.patterns = append(filter.patterns, re)
+	}
+
+	return filter, nil
+}
+
+// IsInvalid reports whether the User-Agent matches a bot pattern
+func (f *UABotFilter) IsInvalid(ip, ua string) bool {
+	if ua == "" {
+		return false
+	}
+
+	for _, re := range f.patterns {
+		if re.MatchString(ua) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package ssp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIPBlacklistFilter(t *testing.T) {
+	filter, err := NewIPBlacklistFilter([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		invalid bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"2001:db8::1", true},
+		{"8.8.8.8", false},
+		{"not-an-ip", false},
+	}
+
+	for _, tt := range tests {
+		if got := filter.IsInvalid(tt.ip, ""); got != tt.invalid {
+			t.Errorf("IsInvalid(%s) = %v, expected %v", tt.ip, got, tt.invalid)
+		}
+	}
+}
+
+func TestIPBlacklistFilterInvalidCIDR(t *testing.T) {
+	if _, err := NewIPBlacklistFilter([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("Expected error for invalid CIDR")
+	}
+}
+
+func TestLoadIPBlacklistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	content := "# datacenter ranges\n10.0.0.0/8\n\n172.16.0.0/12\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write blacklist file: %v", err)
+	}
+
+	cidrs, err := LoadIPBlacklistFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load blacklist file: %v", err)
+	}
+
+	if len(cidrs) != 2 {
+		t.Errorf("Expected 2 ranges, got %d", len(cidrs))
+	}
+}
+
+func TestUABotFilter(t *testing.T) {
+	filter, err := NewUABotFilter([]string{`(?i)HeadlessChrome`})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ua   string
+		bot  bool
+	}{
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"AdsBot", "AdsBot-Google (+http://www.google.com/adsbot.html)", true},
+		{"Mediapartners", "Mediapartners-Google", true},
+		{"custom pattern", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 HeadlessChrome/120.0.0.0 Safari/537.36", true},
+		{"desktop browser", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", false},
+		{"mobile browser", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.IsInvalid("1.2.3.4", tt.ua); got != tt.bot {
+				t.Errorf("IsInvalid(%q) = %v, expected %v", tt.ua, got, tt.bot)
+			}
+		})
+	}
+}
+
+func TestUABotFilterInvalidPattern(t *testing.T) {
+	if _, err := NewUABotFilter([]string{"([unclosed"}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
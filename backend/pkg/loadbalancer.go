@@ -0,0 +1,56 @@
+package ssp
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Partner endpoint load balancing strategies
+const (
+	LBStrategyRoundRobin = "round_robin"
+	LBStrategyRandom     = "random"
+)
+
+// LoadBalancer selects one of a partner's endpoints for each request
+type LoadBalancer interface {
+	Next(endpoints []string) string
+}
+
+// NewLoadBalancer creates a load balancer for a strategy. An empty strategy
+// defaults to round robin.
+func NewLoadBalancer(strategy string) (LoadBalancer, error) {
+	switch strategy {
+	case "", LBStrategyRoundRobin:
+		return &RoundRobinLB{}, nil
+	case LBStrategyRandom:
+		return RandomLB{}, nil
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy: %s", strategy)
+	}
+}
+
+// RoundRobinLB cycles through endpoints in order
+type RoundRobinLB struct {
+	next atomic.Uint64
+}
+
+// Next returns the next endpoint in rotation, or "" when there are none
+func (lb *RoundRobinLB) Next(endpoints []string) string {
+	if len(endpoints) == 0 {
+		return ""
+	}
+	n := lb.next.Add(1) - 1
+	return endpoints[n%uint64(len(endpoints))]
+}
+
+// RandomLB picks an endpoint uniformly at random
+type RandomLB struct{}
+
+// Next returns a random endpoint, or "" when there are none
+func (RandomLB) Next(endpoints []string) string {
+	if len(endpoints) == 0 {
+		return ""
+	}
+	return endpoints[rand.IntN(len(endpoints))]
+}
@@ -0,0 +1,76 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRoundRobinLB(t *testing.T) {
+	lb := &RoundRobinLB{}
+	endpoints := []string{"a", "b", "c"}
+
+	for i, expected := range []string{"a", "b", "c", "a", "b"} {
+		if got := lb.Next(endpoints); got != expected {
+			t.Errorf("call %d: expected %s, got %s", i, expected, got)
+		}
+	}
+
+	if got := lb.Next(nil); got != "" {
+		t.Errorf("expected empty endpoint for no endpoints, got %s", got)
+	}
+}
+
+func TestRandomLB(t *testing.T) {
+	lb := RandomLB{}
+	endpoints := []string{"a", "b"}
+
+	for i := 0; i < 20; i++ {
+		if got := lb.Next(endpoints); got != "a" && got != "b" {
+			t.Fatalf("unexpected endpoint %s", got)
+		}
+	}
+}
+
+func TestNewLoadBalancerUnknownStrategy(t *testing.T) {
+	if _, err := NewLoadBalancer("least_conn"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestSendToPartnerLoadBalancesEndpoints(t *testing.T) {
+	hits := map[string]int{}
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name]++
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	}
+	east := newServer("east")
+	defer east.Close()
+	west := newServer("west")
+	defer west.Close()
+
+	pm := NewPartnerManager()
+	partner := &SupplyPartner{
+		ID:         "p1",
+		Type:       "openrtb",
+		Endpoints:  []string{east.URL, west.URL},
+		LBStrategy: LBStrategyRoundRobin,
+		Timeout:    time.Second,
+		Active:     true,
+	}
+	pm.AddPartner(partner)
+
+	for i := 0; i < 4; i++ {
+		if _, err := pm.SendToPartner(context.Background(), partner, &BidRequest{ID: "req"}); err != nil {
+			t.Fatalf("SendToPartner failed: %v", err)
+		}
+	}
+
+	if hits["east"] != 2 || hits["west"] != 2 {
+		t.Errorf("expected 2 requests per endpoint, got %v", hits)
+	}
+}
@@ -0,0 +1,340 @@
+package ssp
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLogExportRows caps the rows returned by a single log export page
+const MaxLogExportRows = 10000
+
+// LogQuery selects raw log rows for export. Rows are ordered by timestamp and
+// ID; AfterTimestamp and AfterID continue from the last row of a previous page.
+type LogQuery struct {
+	PartnerID      string
+	Start          time.Time
+	End            time.Time
+	Limit          int
+	AfterTimestamp time.Time
+	AfterID        string
+}
+
+// PageSize returns the row limit clamped to MaxLogExportRows
+func (q LogQuery) PageSize() int {
+	if q.Limit <= 0 || q.Limit > MaxLogExportRows {
+		return MaxLogExportRows
+	}
+	return q.Limit
+}
+
+// LogRecord is a raw log row that can be exported as CSV
+type LogRecord interface {
+	CSVRecord() []string
+	// Cursor returns the keyset pagination position of the row
+	Cursor() (time.Time, string)
+}
+
+// BidLogRecord is an exported ssp_bids row
+type BidLogRecord struct {
+	BidID        string    `json:"bidId"`
+	RequestID    string    `json:"requestId"`
+	ImpID        string    `json:"impId"`
+	PlacementID  string    `json:"placementId"`
+	SiteID       string    `json:"siteId"`
+	PublisherID  string    `json:"publisherId"`
+	PartnerID    string    `json:"partnerId"`
+	PartnerName  string    `json:"partnerName"`
+	Price        float64   `json:"price"`
+	Currency     string    `json:"currency"`
+	ADomains     []string  `json:"adomain"`
+	Timestamp    time.Time `json:"timestamp"`
+	Won          bool      `json:"won"`
+	ClearedPrice float64   `json:"clearedPrice"`
+}
+
+// BidLogCSVHeader is the CSV header for BidLogRecord rows
+var BidLogCSVHeader = []string{
+	"bid_id", "request_id", "imp_id", "placement_id", "site_id", "publisher_id",
+	"partner_id", "partner_name", "price", "currency", "adomain", "timestamp",
+	"won", "cleared_price",
+}
+
+// CSVRecord returns the row in BidLogCSVHeader order
+func (r BidLogRecord) CSVRecord() []string {
+	return []string{
+		r.BidID, r.RequestID, r.ImpID, r.PlacementID, r.SiteID, r.PublisherID,
+		r.PartnerID, r.PartnerName, formatFloat(r.Price), r.Currency,
+		strings.Join(r.ADomains, ";"), r.Timestamp.UTC().Format(time.RFC3339),
+		strconv.FormatBool(r.Won), formatFloat(r.ClearedPrice),
+	}
+}
+
+// Cursor returns the row's timestamp and bid ID
+func (r BidLogRecord) Cursor() (time.Time, string) {
+	return r.Timestamp, r.BidID
+}
+
+// ImpressionLogRecord is an exported ssp_impressions row
+type ImpressionLogRecord struct {
+	ImpressionID     string    `json:"impressionId"`
+	BidID            string    `json:"bidId"`
+	RequestID        string    `json:"requestId"`
+	PlacementID      string    `json:"placementId"`
+	SiteID           string    `json:"siteId"`
+	PublisherID      string    `json:"publisherId"`
+	PartnerID        string    `json:"partnerId"`
+	Price            float64   `json:"price"`
+	PublisherRevenue float64   `json:"publisherRevenue"`
+	Timestamp        time.Time `json:"timestamp"`
+	Country          string    `json:"country"`
+	DeviceType       string    `json:"deviceType"`
+}
+
+// ImpressionLogCSVHeader is the CSV header for ImpressionLogRecord rows
+var ImpressionLogCSVHeader = []string{
+	"impression_id", "bid_id", "request_id", "placement_id", "site_id",
+	"publisher_id", "partner_id", "price", "publisher_revenue", "timestamp",
+	"country", "device_type",
+}
+
+// CSVRecord returns the row in ImpressionLogCSVHeader order
+func (r ImpressionLogRecord) CSVRecord() []string {
+	return []string{
+		r.ImpressionID, r.BidID, r.RequestID, r.PlacementID, r.SiteID,
+		r.PublisherID, r.PartnerID, formatFloat(r.Price), formatFloat(r.PublisherRevenue),
+		r.Timestamp.UTC().Format(time.RFC3339), r.Country, r.DeviceType,
+	}
+}
+
+// Cursor returns the row's timestamp and impression ID
+func (r ImpressionLogRecord) Cursor() (time.Time, string) {
+	return r.Timestamp, r.ImpressionID
+}
+
+// ClickLogRecord is an exported ssp_clicks row
+type ClickLogRecord struct {
+	ClickID      string    `json:"clickId"`
+	ImpressionID string    `json:"impressionId"`
+	BidID        string    `json:"bidId"`
+	PlacementID  string    `json:"placementId"`
+	SiteID       string    `json:"siteId"`
+	PublisherID  string    `json:"publisherId"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// ClickLogCSVHeader is the CSV header for ClickLogRecord rows
+var ClickLogCSVHeader = []string{
+	"click_id", "impression_id", "bid_id", "placement_id", "site_id", "publisher_id", "timestamp",
+}
+
+// CSVRecord returns the row in ClickLogCSVHeader order
+func (r ClickLogRecord) CSVRecord() []string {
+	return []string{
+		r.ClickID, r.ImpressionID, r.BidID, r.PlacementID, r.SiteID, r.PublisherID,
+		r.Timestamp.UTC().Format(time.RFC3339),
+	}
+}
+
+// Cursor returns the row's timestamp and click ID
+func (r ClickLogRecord) Cursor() (time.Time, string) {
+	return r.Timestamp, r.ClickID
+}
+
+// WriteLogCSV writes a header row followed by one row per record. Values
+// come from bidders and pixels, so each is made safe to open in a spreadsheet.
+func WriteLogCSV[T LogRecord](w io.Writer, header []string, records []T) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := record.CSVRecord()
+		for i, value := range row {
+			row[i] = csvSafe(value)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvSafe prefixes a value that a spreadsheet would evaluate as a formula
+// (one starting with =, +, - or @) with a single quote
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// logFilter builds the shared WHERE clause for log export queries. idColumn is
+// the row ID used as the pagination tie-breaker; partnerFilter is the condition
+// applied when PartnerID is set.
+func (q LogQuery) logFilter(idColumn, partnerFilter string) (string, []interface{}) {
+	where := "timestamp >= ? AND timestamp < ?"
+	args := []interface{}{q.Start, q.End}
+
+	if q.PartnerID != "" {
+		where += " AND " + partnerFilter
+		args = append(args, q.PartnerID)
+	}
+
+	if !q.AfterTimestamp.IsZero() {
+		where += " AND (timestamp, " + idColumn + ") > (?, ?)"
+		args = append(args, q.AfterTimestamp, q.AfterID)
+	}
+
+	args = append(args, q.PageSize())
+	return where, args
+}
+
+// GetBidLogs retrieves raw bid log rows
+func (as *AnalyticsStore) GetBidLogs(ctx context.Context, q LogQuery) ([]BidLogRecord, error) {
+	where, args := q.logFilter("bid_id", "partner_id = ?")
+	query := `
+		SELECT
+			bid_id, request_id, imp_id, placement_id, site_id, publisher_id,
+			partner_id, partner_name, price, currency, adomain, timestamp,
+			won, cleared_price
+		FROM ssp_bids
+		WHERE ` + where + `
+		ORDER BY timestamp, bid_id
+		LIMIT ?
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []BidLogRecord{}
+	for rows.Next() {
+		var r BidLogRecord
+		var won uint8
+		if err := rows.Scan(
+			&r.BidID,
+			&r.RequestID,
+			&r.ImpID,
+			&r.PlacementID,
+			&r.SiteID,
+			&r.PublisherID,
+			&r.PartnerID,
+			&r.PartnerName,
+			&r.Price,
+			&r.Currency,
+			&r.ADomains,
+			&r.Timestamp,
+			&won,
+			&r.ClearedPrice,
+		); err != nil {
+			return nil, err
+		}
+		r.Won = won == 1
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetImpressionLogs retrieves raw impression log rows
+func (as *AnalyticsStore) GetImpressionLogs(ctx context.Context, q LogQuery) ([]ImpressionLogRecord, error) {
+	where, args := q.logFilter("impression_id", "partner_id = ?")
+	query := `
+		SELECT
+			impression_id, bid_id, request_id, placement_id, site_id, publisher_id,
+			partner_id, price, publisher_revenue, timestamp, country, device_type
+		FROM ssp_impressions
+		WHERE ` + where + `
+		ORDER BY timestamp, impression_id
+		LIMIT ?
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []ImpressionLogRecord{}
+	for rows.Next() {
+		var r ImpressionLogRecord
+		if err := rows.Scan(
+			&r.ImpressionID,
+			&r.BidID,
+			&r.RequestID,
+			&r.PlacementID,
+			&r.SiteID,
+			&r.PublisherID,
+			&r.PartnerID,
+			&r.Price,
+			&r.PublisherRevenue,
+			&r.Timestamp,
+			&r.Country,
+			&r.DeviceType,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// GetClickLogs retrieves raw click log rows. Clicks carry no partner ID, so a
+// partner filter matches clicks on that partner's impressions.
+func (as *AnalyticsStore) GetClickLogs(ctx context.Context, q LogQuery) ([]ClickLogRecord, error) {
+	where, args := q.logFilter("click_id", "bid_id IN (SELECT bid_id FROM ssp_impressions WHERE partner_id = ?)")
+	query := `
+		SELECT
+			click_id, impression_id, bid_id, placement_id, site_id, publisher_id, timestamp
+		FROM ssp_clicks
+		WHERE ` + where + `
+		ORDER BY timestamp, click_id
+		LIMIT ?
+	`
+
+	ctx, cancel := as.queryContext(ctx)
+	defer cancel()
+
+	rows, err := as.connection().Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []ClickLogRecord{}
+	for rows.Next() {
+		var r ClickLogRecord
+		if err := rows.Scan(
+			&r.ClickID,
+			&r.ImpressionID,
+			&r.BidID,
+			&r.PlacementID,
+			&r.SiteID,
+			&r.PublisherID,
+			&r.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,74 @@
+package ssp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogQueryPageSize(t *testing.T) {
+	tests := map[int]int{0: MaxLogExportRows, -1: MaxLogExportRows, 50: 50, MaxLogExportRows + 1: MaxLogExportRows}
+	for limit, expected := range tests {
+		if got := (LogQuery{Limit: limit}).PageSize(); got != expected {
+			t.Errorf("Limit %d: expected page size %d, got %d", limit, expected, got)
+		}
+	}
+}
+
+func TestLogQueryFilter(t *testing.T) {
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	q := LogQuery{Start: start, End: start.AddDate(0, 0, 1), Limit: 100}
+
+	where, args := q.logFilter("bid_id", "partner_id = ?")
+	if where != "timestamp >= ? AND timestamp < ?" || len(args) != 3 {
+		t.Errorf("Unexpected filter without partner or cursor: %q %v", where, args)
+	}
+
+	q.PartnerID = "partner-1"
+	q.AfterTimestamp = start.Add(time.Hour)
+	q.AfterID = "bid-9"
+	where, args = q.logFilter("bid_id", "partner_id = ?")
+
+	expected := "timestamp >= ? AND timestamp < ? AND partner_id = ? AND (timestamp, bid_id) > (?, ?)"
+	if where != expected {
+		t.Errorf("Unexpected filter:\n got: %s\nwant: %s", where, expected)
+	}
+	if len(args) != 6 || args[2] != "partner-1" || args[4] != "bid-9" || args[5] != 100 {
+		t.Errorf("Unexpected args: %v", args)
+	}
+}
+
+func TestWriteLogCSV(t *testing.T) {
+	records := []BidLogRecord{{
+		BidID:        "bid-1",
+		PartnerID:    "partner-1",
+		Price:        1.5,
+		ADomains:     []string{"a.com", "b.com"},
+		Timestamp:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		Won:          true,
+		ClearedPrice: 1.25,
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteLogCSV(&buf, BidLogCSVHeader, records); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	expected := "bid_id,request_id,imp_id,placement_id,site_id,publisher_id,partner_id,partner_name,price,currency,adomain,timestamp,won,cleared_price\n" +
+		"bid-1,,,,,,partner-1,,1.5,,a.com;b.com,2024-01-15T10:30:00Z,true,1.25\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV:\n got: %s\nwant: %s", buf.String(), expected)
+	}
+
+	// Bidder-supplied values must not run as spreadsheet formulas
+	records[0].ADomains = []string{"=HYPERLINK(\"http://evil.example\")"}
+	records[0].PartnerName = "@SUM(A1)"
+	buf.Reset()
+	if err := WriteLogCSV(&buf, BidLogCSVHeader, records); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	if !strings.Contains(buf.String(), `'@SUM(A1)`) || !strings.Contains(buf.String(), `"'=HYPERLINK(""http://evil.example"")"`) {
+		t.Errorf("Expected formula values prefixed with a quote, got: %s", buf.String())
+	}
+}
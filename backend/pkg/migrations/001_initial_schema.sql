@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS publishers (
+	id VARCHAR(255) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	domain VARCHAR(255) NOT NULL,
+	active BOOLEAN DEFAULT true,
+	rev_share DECIMAL(3, 2) DEFAULT 0.70,
+	payment_info TEXT,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS sites (
+	id VARCHAR(255) PRIMARY KEY,
+	publisher_id VARCHAR(255) NOT NULL REFERENCES publishers(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	domain VARCHAR(255) NOT NULL,
+	page VARCHAR(500),
+	cat JSONB,
+	active BOOLEAN DEFAULT true,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS placements (
+	id VARCHAR(255) PRIMARY KEY,
+	site_id VARCHAR(255) NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	ad_type VARCHAR(50) NOT NULL,
+	width INT,
+	height INT,
+	min_bid_floor DECIMAL(10, 4) DEFAULT 0,
+	active BOOLEAN DEFAULT true,
+	formats JSONB,
+	video JSONB,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_publishers_active ON publishers(active);
+CREATE INDEX IF NOT EXISTS idx_publishers_email ON publishers(email);
+CREATE INDEX IF NOT EXISTS idx_sites_publisher_id ON sites(publisher_id);
+CREATE INDEX IF NOT EXISTS idx_sites_active ON sites(active);
+CREATE INDEX IF NOT EXISTS idx_placements_site_id ON placements(site_id);
+CREATE INDEX IF NOT EXISTS idx_placements_active ON placements(active);
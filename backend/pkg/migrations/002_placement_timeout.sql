@@ -0,0 +1,2 @@
+-- Per-placement partner bid timeout
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS timeout_ms INT;
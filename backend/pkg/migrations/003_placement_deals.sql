@@ -0,0 +1,2 @@
+-- Private marketplace and programmatic guaranteed deals per placement
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS deals JSONB;
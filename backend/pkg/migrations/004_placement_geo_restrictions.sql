@@ -0,0 +1,9 @@
+-- Per-placement country allow/block lists
+CREATE TYPE geo_restriction_type AS ENUM ('allow', 'block');
+
+CREATE TABLE IF NOT EXISTS placement_geo_restrictions (
+	placement_id VARCHAR(255) NOT NULL REFERENCES placements(id) ON DELETE CASCADE,
+	country_code CHAR(3) NOT NULL,
+	restriction_type geo_restriction_type NOT NULL,
+	PRIMARY KEY (placement_id, country_code)
+);
@@ -0,0 +1,2 @@
+-- Site-level content rating for brand safety
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS content_rating VARCHAR(10);
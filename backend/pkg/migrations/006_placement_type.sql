@@ -0,0 +1,2 @@
+-- Video placement type (in-stream or outstream variants)
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS placement_type VARCHAR(20);
@@ -0,0 +1,2 @@
+-- Server-side reward callback for rewarded video placements
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS reward_callback_url TEXT;
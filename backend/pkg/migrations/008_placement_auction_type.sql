@@ -0,0 +1,2 @@
+-- OpenRTB auction type sent to DSPs (1=first price, 2=second price; NULL uses second price)
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS auction_type SMALLINT;
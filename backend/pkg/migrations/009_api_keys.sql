@@ -0,0 +1,10 @@
+-- Publisher API keys. Only the SHA-256 hash of a key is stored.
+-- expires_at is NULL for the current key and set for a rotated key in its grace period.
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_hash CHAR(64) PRIMARY KEY,
+	publisher_id VARCHAR(255) NOT NULL REFERENCES publishers(id) ON DELETE CASCADE,
+	created_at TIMESTAMP DEFAULT NOW(),
+	expires_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_publisher ON api_keys(publisher_id);
@@ -0,0 +1,3 @@
+-- Time-of-day scheduling: when enabled, placements only serve within their weekly time slots
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS schedule_enabled BOOLEAN DEFAULT false;
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS schedule JSONB;
@@ -0,0 +1,7 @@
+-- Publisher onboarding status. Existing publishers keep their current serving state.
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending';
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS status_reason TEXT;
+
+UPDATE publishers SET status = CASE WHEN active THEN 'active' ELSE 'suspended' END;
+
+CREATE INDEX IF NOT EXISTS idx_publishers_status ON publishers(status);
@@ -0,0 +1,2 @@
+-- Per-user impression frequency cap for a site
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS impression_cap JSONB;
@@ -0,0 +1,2 @@
+-- Target fill rate below which the auction relaxes the placement floor (0 disables)
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS min_fill_rate DOUBLE PRECISION DEFAULT 0;
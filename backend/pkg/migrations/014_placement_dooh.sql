@@ -0,0 +1,2 @@
+-- Digital out-of-home venue metadata sent to DSPs in the OpenRTB 2.6 dooh object
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS dooh JSONB;
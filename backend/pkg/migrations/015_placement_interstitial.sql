@@ -0,0 +1,2 @@
+-- Interstitial placements cover the full screen and are flagged to DSPs with imp.instl
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS interstitial BOOLEAN DEFAULT false;
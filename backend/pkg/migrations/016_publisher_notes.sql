@@ -0,0 +1,3 @@
+-- Internal account notes and free-form metadata (account manager, contract tier, ...)
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS notes VARCHAR(2048);
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS metadata JSONB;
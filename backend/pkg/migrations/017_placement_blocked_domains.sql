@@ -0,0 +1,2 @@
+-- Advertiser domains the publisher blocks on a placement, sent to DSPs as badv
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS blocked_domains JSONB;
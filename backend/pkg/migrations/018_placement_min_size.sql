@@ -0,0 +1,3 @@
+-- Minimum creative size for multi-size banner placements; 0 means no minimum
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS min_width INTEGER DEFAULT 0;
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS min_height INTEGER DEFAULT 0;
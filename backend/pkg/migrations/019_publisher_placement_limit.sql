@@ -0,0 +1,2 @@
+-- Per-site placement quota; 0 uses the default of 50
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS max_placements_per_site INTEGER DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Image shown in the noscript fallback of display tags when JavaScript is disabled
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS fallback_image_url TEXT;
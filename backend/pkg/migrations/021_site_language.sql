@@ -0,0 +1,2 @@
+-- ISO 639-1 content language of a site, sent to DSPs as wlang
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS language VARCHAR(2);
@@ -0,0 +1,2 @@
+-- GetSiteByDomain looks sites up by domain when checking ad request referers
+CREATE INDEX IF NOT EXISTS idx_sites_domain ON sites(domain);
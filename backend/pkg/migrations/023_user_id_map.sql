@@ -0,0 +1,8 @@
+-- DSP buyer UIDs synced against the SSP's first-party user ID
+CREATE TABLE IF NOT EXISTS user_id_map (
+	ssp_user_id VARCHAR(64) NOT NULL,
+	partner_id VARCHAR(255) NOT NULL,
+	buyer_uid VARCHAR(256) NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (ssp_user_id, partner_id)
+);
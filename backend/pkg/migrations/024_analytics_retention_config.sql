@@ -0,0 +1,6 @@
+-- Per-table ClickHouse analytics retention, applied on startup and when changed
+CREATE TABLE IF NOT EXISTS analytics_retention_config (
+	table_name VARCHAR(64) PRIMARY KEY,
+	retention_days INT NOT NULL CHECK (retention_days > 0),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- IAB categories blocked by a publisher, site or placement, unioned into bcat
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS blocked_categories JSONB;
+ALTER TABLE sites ADD COLUMN IF NOT EXISTS blocked_categories JSONB;
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS blocked_categories JSONB;
@@ -0,0 +1,6 @@
+-- Deployment settings that override environment variables, e.g. the SSP identity
+CREATE TABLE IF NOT EXISTS ssp_config (
+	key VARCHAR(64) PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
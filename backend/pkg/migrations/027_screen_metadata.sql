@@ -0,0 +1,2 @@
+-- DOOH screen metadata sent to DSPs in imp.ext.dooh
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS screen_metadata JSONB;
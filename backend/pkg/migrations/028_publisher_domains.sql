@@ -0,0 +1,8 @@
+-- Domains a publisher has proven ownership of; ad requests from other domains
+-- are sold as unverified inventory
+CREATE TABLE IF NOT EXISTS publisher_domains (
+	publisher_id VARCHAR(255) NOT NULL REFERENCES publishers(id) ON DELETE CASCADE,
+	domain VARCHAR(255) NOT NULL,
+	verified_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (publisher_id, domain)
+);
@@ -0,0 +1,12 @@
+-- Revenue share periods per publisher for contract auditing. The current rate
+-- has no effective_to; rates set at signup have no changed_by.
+CREATE TABLE IF NOT EXISTS publisher_revshare_history (
+	id SERIAL PRIMARY KEY,
+	publisher_id VARCHAR(255) NOT NULL REFERENCES publishers(id) ON DELETE CASCADE,
+	rev_share DECIMAL(3, 2) NOT NULL,
+	effective_from TIMESTAMP NOT NULL,
+	effective_to TIMESTAMP,
+	changed_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_publisher_revshare_history_publisher ON publisher_revshare_history(publisher_id, effective_from);
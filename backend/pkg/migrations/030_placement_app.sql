@@ -0,0 +1,2 @@
+-- Mobile app inventory, sent to DSPs as app in place of site
+ALTER TABLE placements ADD COLUMN IF NOT EXISTS app JSONB;
@@ -0,0 +1,13 @@
+-- Exports of a day of a ClickHouse analytics table to object storage
+CREATE TABLE IF NOT EXISTS analytics_exports (
+	id VARCHAR(255) PRIMARY KEY,
+	table_name VARCHAR(64) NOT NULL,
+	export_date DATE NOT NULL,
+	bucket TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_exports_table_date ON analytics_exports(table_name, export_date);
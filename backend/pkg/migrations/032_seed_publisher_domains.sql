@@ -0,0 +1,6 @@
+-- Approved publishers start out verified on their account domain, which was
+-- reviewed at onboarding. Site domains are self-declared and are not seeded;
+-- they must be verified through the admin API.
+INSERT INTO publisher_domains (publisher_id, domain)
+SELECT id, lower(trim(domain)) FROM publishers WHERE status = 'active' AND trim(domain) <> ''
+ON CONFLICT (publisher_id, domain) DO NOTHING;
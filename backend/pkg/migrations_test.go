@@ -0,0 +1,23 @@
+package ssp
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d (%s)", i, i+1, m.version, m.name)
+		}
+		if m.sql == "" {
+			t.Errorf("Migration %s is empty", m.name)
+		}
+	}
+}
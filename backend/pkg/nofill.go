@@ -0,0 +1,47 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// No-fill reasons recorded when an ad request or partner produces no ad
+const (
+	NoFillNoPartners      = "no_partners"      // No active demand partners
+	NoFillNoBid           = "no_bid"           // Partners responded without bids
+	NoFillAllBelowFloor   = "all_below_floor"  // Bids received but none cleared the floor
+	NoFillTimeout         = "timeout"          // Partners did not respond in time
+	NoFillError           = "error"            // Partner requests failed
+	NoFillInvalidResponse = "invalid_response" // Partner response could not be parsed
+)
+
+// ErrInvalidBidResponse is returned when a partner bid response cannot be parsed
+var ErrInvalidBidResponse = errors.New("invalid bid response")
+
+// IsTimeoutError reports whether err was caused by a deadline or network timeout
+func IsTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// PartnerNoFillReason classifies a single partner's bid outcome.
+// It returns an empty string when the partner returned bids.
+func PartnerNoFillReason(err error, hasBids bool) string {
+	switch {
+	case err == nil && hasBids:
+		return ""
+	case err == nil:
+		return NoFillNoBid
+	case IsTimeoutError(err):
+		return NoFillTimeout
+	case errors.Is(err, ErrInvalidBidResponse):
+		return NoFillInvalidResponse
+	default:
+		return NoFillError
+	}
+}
@@ -0,0 +1,31 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPartnerNoFillReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		hasBids  bool
+		expected string
+	}{
+		{"Bids returned", nil, true, ""},
+		{"No bid (204)", nil, false, NoFillNoBid},
+		{"Timeout", fmt.Errorf("failed to send request: %w", context.DeadlineExceeded), false, NoFillTimeout},
+		{"Invalid JSON", fmt.Errorf("%w: %w", ErrInvalidBidResponse, errors.New("unexpected EOF")), false, NoFillInvalidResponse},
+		{"Bad request", errors.New("unexpected status code: 400"), false, NoFillError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PartnerNoFillReason(tt.err, tt.hasBids); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
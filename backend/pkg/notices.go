@@ -0,0 +1,72 @@
+package ssp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenRTB loss reason codes (list 5.25)
+const (
+	LossReasonLostToHigherBid  = 102
+	LossReasonCreativeFiltered = 200 // Creative failed SSP validation
+	LossReasonCreativeSize     = 203 // Creative size not allowed
+)
+
+// AuctionNotice is the payload of win and loss events for a partner bid
+type AuctionNotice struct {
+	RequestID    string
+	PlacementID  string
+	PartnerID    string
+	Bid          *Bid
+	ClearedPrice float64 // Price the winning bid cleared at
+	LossReason   int     // Set on loss notices
+}
+
+// expandNoticeMacros substitutes the OpenRTB auction macros in a notice URL
+func expandNoticeMacros(noticeURL string, notice *AuctionNotice) string {
+	replacer := strings.NewReplacer(
+		"${AUCTION_ID}", notice.RequestID,
+		"${AUCTION_BID_ID}", notice.Bid.ID,
+		"${AUCTION_IMP_ID}", notice.Bid.ImpID,
+		"${AUCTION_PRICE}", strconv.FormatFloat(notice.ClearedPrice, 'f', -1, 64),
+		"${AUCTION_CURRENCY}", "USD",
+		"${AUCTION_LOSS}", strconv.Itoa(notice.LossReason),
+	)
+	return replacer.Replace(noticeURL)
+}
+
+// SendWinNotice calls the winning bid's NURL with auction macros expanded
+func SendWinNotice(ctx context.Context, client *http.Client, notice *AuctionNotice) error {
+	return sendNotice(ctx, client, notice.Bid.NURL, notice)
+}
+
+// SendLossNotice calls a losing bid's LURL with auction macros expanded
+func SendLossNotice(ctx context.Context, client *http.Client, notice *AuctionNotice) error {
+	return sendNotice(ctx, client, notice.Bid.LURL, notice)
+}
+
+func sendNotice(ctx context.Context, client *http.Client, noticeURL string, notice *AuctionNotice) error {
+	if noticeURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, expandNoticeMacros(noticeURL, notice), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create notice request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notice request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notice returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendNotices(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notice := &AuctionNotice{
+		RequestID:    "req-1",
+		Bid:          &Bid{ID: "bid-1", ImpID: "imp-1", NURL: server.URL + "/win?price=${AUCTION_PRICE}&id=${AUCTION_ID}", LURL: server.URL + "/loss?reason=${AUCTION_LOSS}&price=${AUCTION_PRICE}"},
+		ClearedPrice: 1.25,
+		LossReason:   LossReasonLostToHigherBid,
+	}
+
+	if err := SendWinNotice(context.Background(), server.Client(), notice); err != nil {
+		t.Fatalf("Failed to send win notice: %v", err)
+	}
+	if err := SendLossNotice(context.Background(), server.Client(), notice); err != nil {
+		t.Fatalf("Failed to send loss notice: %v", err)
+	}
+
+	expected := []string{"price=1.25&id=req-1", "reason=102&price=1.25"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d notices, got %v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected notice query %q, got %q", expected[i], got[i])
+		}
+	}
+
+	// Bids without a notice URL are skipped
+	if err := SendLossNotice(context.Background(), server.Client(), &AuctionNotice{Bid: &Bid{ID: "bid-2"}}); err != nil {
+		t.Errorf("Expected no error without an LURL, got %v", err)
+	}
+}
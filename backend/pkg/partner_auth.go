@@ -0,0 +1,123 @@
+package ssp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supply partner authentication methods
+const (
+	PartnerAuthBearer       = "bearer"        // Authorization: Bearer <APIKey>
+	PartnerAuthBasic        = "basic"         // HTTP Basic auth; APIKey is "username:password"
+	PartnerAuthHMACSHA256   = "hmac_sha256"   // Hex HMAC-SHA256 of "<timestamp>.<body>" keyed with APIKey, in AuthHeaderName
+	PartnerAuthCustomHeader = "custom_header" // AuthHeaderName: AuthHeaderValue
+)
+
+// DefaultHMACSignatureHeader carries the request signature when AuthHeaderName is not set
+const DefaultHMACSignatureHeader = "X-Signature"
+
+// HMACTimestampHeader carries the Unix time signed with the body, so a captured
+// request cannot be replayed once the partner's tolerance window has passed
+const HMACTimestampHeader = "X-Timestamp"
+
+// RedactedSecret stands in for partner credentials in exported configuration
+const RedactedSecret = "[REDACTED]"
+
+// redactPartnerAuth replaces a partner's credentials with RedactedSecret.
+// The basic auth username is kept so only the password has to be provided again.
+func redactPartnerAuth(partner *SupplyPartner) {
+	if partner.APIKey != "" {
+		if username, _, ok := strings.Cut(partner.APIKey, ":"); ok && partner.AuthType == PartnerAuthBasic {
+			partner.APIKey = username + ":" + RedactedSecret
+		} else {
+			partner.APIKey = RedactedSecret
+		}
+	}
+	if partner.AuthHeaderValue != "" {
+		partner.AuthHeaderValue = RedactedSecret
+	}
+}
+
+// validatePartnerAuth checks that a partner has the credentials its AuthType needs
+func validatePartnerAuth(partner *SupplyPartner) error {
+	if strings.Contains(partner.APIKey, RedactedSecret) || partner.AuthHeaderValue == RedactedSecret {
+		return errors.New("partner credentials are redacted; provide apiKey and authHeaderValue again")
+	}
+
+	switch partner.AuthType {
+	case "", PartnerAuthBearer:
+		return nil
+	case PartnerAuthBasic:
+		if !strings.Contains(partner.APIKey, ":") {
+			return errors.New("basic auth requires apiKey in username:password form")
+		}
+	case PartnerAuthHMACSHA256:
+		if partner.APIKey == "" {
+			return errors.New("hmac_sha256 auth requires apiKey as the signing secret")
+		}
+	case PartnerAuthCustomHeader:
+		if partner.AuthHeaderName == "" || partner.AuthHeaderValue == "" {
+			return errors.New("custom_header auth requires authHeaderName and authHeaderValue")
+		}
+	default:
+		return fmt.Errorf("unknown authType: %s", partner.AuthType)
+	}
+	return nil
+}
+
+// PartnerAuthenticator returns a func authenticating requests to partner the
+// same way SendToPartner does, for bid requests sent by the Bidder
+func PartnerAuthenticator(partner *SupplyPartner) func(req *http.Request, body []byte) {
+	return func(req *http.Request, body []byte) {
+		if partner.Type == "exads" {
+			if partner.APIKey != "" {
+				req.Header.Set("X-EXADS-API-Key", partner.APIKey)
+			}
+			return
+		}
+		applyPartnerAuth(req, partner, body)
+	}
+}
+
+// applyPartnerAuth authenticates a request to a partner using its AuthType.
+// body is the request body, signed for hmac_sha256.
+func applyPartnerAuth(req *http.Request, partner *SupplyPartner, body []byte) {
+	switch partner.AuthType {
+	case PartnerAuthBasic:
+		username, password, _ := strings.Cut(partner.APIKey, ":")
+		req.SetBasicAuth(username, password)
+	case PartnerAuthHMACSHA256:
+		header := partner.AuthHeaderName
+		if header == "" {
+			header = DefaultHMACSignatureHeader
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HMACTimestampHeader, timestamp)
+		req.Header.Set(header, signHMACSHA256(partner.APIKey, hmacMessage(timestamp, body)))
+	case PartnerAuthCustomHeader:
+		req.Header.Set(partner.AuthHeaderName, partner.AuthHeaderValue)
+	default:
+		if partner.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+partner.APIKey)
+		}
+	}
+}
+
+// hmacMessage builds the signed message "<timestamp>.<body>"
+func hmacMessage(timestamp string, body []byte) []byte {
+	return append([]byte(timestamp+"."), body...)
+}
+
+// signHMACSHA256 returns the hex HMAC-SHA256 of message keyed with secret
+func signHMACSHA256(secret string, message []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(message)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,110 @@
+package ssp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSendToPartnerAuth(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	send := func(partner *SupplyPartner) {
+		t.Helper()
+		partner.ID, partner.Type, partner.Endpoint, partner.Timeout = "dsp-1", "openrtb", server.URL, time.Second
+		if err := ValidateSupplyPartner(partner); err != nil {
+			t.Fatalf("Invalid partner: %v", err)
+		}
+		if _, err := NewPartnerManager().SendToPartner(context.Background(), partner, &BidRequest{ID: "req-1"}); err != nil {
+			t.Fatalf("SendToPartner failed: %v", err)
+		}
+	}
+
+	send(&SupplyPartner{APIKey: "token"})
+	if auth := got.Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("Expected bearer auth by default, got %q", auth)
+	}
+
+	send(&SupplyPartner{AuthType: PartnerAuthBasic, APIKey: "user:pa:ss"})
+	if user, pass, ok := got.BasicAuth(); !ok || user != "user" || pass != "pa:ss" {
+		t.Errorf("Expected basic auth user/pa:ss, got %q/%q (%v)", user, pass, ok)
+	}
+
+	send(&SupplyPartner{AuthType: PartnerAuthCustomHeader, AuthHeaderName: "X-Partner-Token", AuthHeaderValue: "secret"})
+	if v := got.Header.Get("X-Partner-Token"); v != "secret" {
+		t.Errorf("Expected custom header value secret, got %q", v)
+	}
+	if got.Header.Get("Authorization") != "" {
+		t.Error("Expected no Authorization header with custom_header auth")
+	}
+
+	send(&SupplyPartner{AuthType: PartnerAuthHMACSHA256, APIKey: "signing-key"})
+	timestamp := got.Header.Get(HMACTimestampHeader)
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("Expected a current Unix timestamp in %s, got %q", HMACTimestampHeader, timestamp)
+	}
+	if sig := got.Header.Get(DefaultHMACSignatureHeader); sig != signHMACSHA256("signing-key", hmacMessage(timestamp, gotBody)) {
+		t.Errorf("Expected HMAC signature of the timestamp and body, got %q", sig)
+	}
+}
+
+func TestValidatePartnerAuth(t *testing.T) {
+	invalid := []*SupplyPartner{
+		{AuthType: "digest"},
+		{AuthType: PartnerAuthBasic, APIKey: "no-colon"},
+		{AuthType: PartnerAuthHMACSHA256},
+		{AuthType: PartnerAuthCustomHeader, AuthHeaderName: "X-Token"},
+	}
+	for _, partner := range invalid {
+		if err := validatePartnerAuth(partner); err == nil {
+			t.Errorf("Expected %+v to be rejected", partner)
+		}
+	}
+
+	// Known vector from RFC 4231 test case 2
+	if sig := signHMACSHA256("Jefe", []byte("what do ya want for nothing?")); sig != "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		t.Errorf("Unexpected HMAC-SHA256 signature %s", sig)
+	}
+}
+
+func TestBidderSendBidRequestAuth(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	bidder := NewBidder("ssp-1", time.Second)
+	send := func(partner *SupplyPartner) {
+		t.Helper()
+		dp := &DemandPartner{ID: partner.ID, Endpoint: server.URL, Authenticate: PartnerAuthenticator(partner)}
+		if _, err := bidder.SendBidRequest(context.Background(), &BidRequest{ID: "req-1"}, dp); err != nil {
+			t.Fatalf("SendBidRequest failed: %v", err)
+		}
+	}
+
+	send(&SupplyPartner{ID: "dsp-1", Type: "openrtb", AuthType: PartnerAuthCustomHeader, AuthHeaderName: "X-Partner-Token", AuthHeaderValue: "secret"})
+	if v := got.Header.Get("X-Partner-Token"); v != "secret" {
+		t.Errorf("Expected custom header value secret, got %q", v)
+	}
+
+	send(&SupplyPartner{ID: "exads-1", Type: "exads", APIKey: "exads-key"})
+	if v := got.Header.Get("X-EXADS-API-Key"); v != "exads-key" {
+		t.Errorf("Expected EXADS API key header, got %q", v)
+	}
+	if got.Header.Get("Authorization") != "" {
+		t.Error("Expected no Authorization header for EXADS partners")
+	}
+}
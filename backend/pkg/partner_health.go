@@ -0,0 +1,149 @@
+package ssp
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PartnerHealthCheckTimeout bounds each partner health check request
+const PartnerHealthCheckTimeout = 2 * time.Second
+
+// PartnerHealthFailureThreshold is the number of consecutive failed checks
+// after which a partner is deactivated
+const PartnerHealthFailureThreshold = 2
+
+// StartHealthChecker checks every active partner's health each interval
+// until ctx is done. A partner failing PartnerHealthFailureThreshold checks in
+// a row is deactivated, and reactivated on its next successful check. Partners
+// deactivated by configuration are not checked.
+func (pm *PartnerManager) StartHealthChecker(ctx context.Context, interval time.Duration) {
+	client := &http.Client{Timeout: PartnerHealthCheckTimeout}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pm.checkPartnerHealth(ctx, client)
+			}
+		}
+	}()
+}
+
+// checkPartnerHealth runs one round of health checks and updates partner state
+func (pm *PartnerManager) checkPartnerHealth(ctx context.Context, client *http.Client) {
+	pm.mu.RLock()
+	partners := make([]*SupplyPartner, 0, len(pm.partners))
+	for id, p := range pm.partners {
+		if p.Active || pm.healthDisabled[id] {
+			partners = append(partners, p)
+		}
+	}
+	pm.mu.RUnlock()
+
+	healthy := make([]bool, len(partners))
+	var wg sync.WaitGroup
+	for i, p := range partners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			healthy[i] = pingPartner(ctx, client, p)
+		}()
+	}
+	wg.Wait()
+
+	for i, p := range partners {
+		pm.recordHealth(p, healthy[i])
+		if pm.OnHealthCheck != nil {
+			pm.OnHealthCheck(p.ID, healthy[i])
+		}
+	}
+}
+
+// recordHealth applies one health check result to a partner
+func (pm *PartnerManager) recordHealth(partner *SupplyPartner, healthy bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// The partner was replaced or removed while being checked
+	if pm.partners[partner.ID] != partner {
+		return
+	}
+
+	if healthy {
+		delete(pm.healthFailures, partner.ID)
+		if pm.healthDisabled[partner.ID] {
+			delete(pm.healthDisabled, partner.ID)
+			partner.Active = true
+		}
+		return
+	}
+
+	pm.healthFailures[partner.ID]++
+	if partner.Active && pm.healthFailures[partner.ID] >= PartnerHealthFailureThreshold {
+		partner.Active = false
+		pm.healthDisabled[partner.ID] = true
+	}
+}
+
+// resetHealth forgets the health checker state of a partner. Callers must hold pm.mu.
+func (pm *PartnerManager) resetHealth(id string) {
+	delete(pm.healthFailures, id)
+	delete(pm.healthDisabled, id)
+}
+
+// pingPartner sends a HEAD request to the partner's health check URL or, when
+// none is configured, to each of its bid endpoints. The partner is healthy
+// only when every probe answers 200 in time; a partner whose bid endpoints
+// reject HEAD needs a HealthCheckURL.
+func pingPartner(ctx context.Context, client *http.Client, partner *SupplyPartner) bool {
+	endpoints := healthCheckEndpoints(partner)
+	if len(endpoints) == 0 {
+		return false
+	}
+
+	for _, endpoint := range endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+		if err != nil {
+			return false
+		}
+		applyPartnerAuth(req, partner, nil)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// healthCheckEndpoints returns the URLs probed for a partner: its health check
+// URL, or else its primary and regional bid endpoints
+func healthCheckEndpoints(partner *SupplyPartner) []string {
+	if partner.HealthCheckURL != "" {
+		return []string{partner.HealthCheckURL}
+	}
+
+	var endpoints []string
+	if partner.Endpoint != "" {
+		endpoints = append(endpoints, partner.Endpoint)
+	}
+	for _, endpoint := range partner.Endpoints {
+		if endpoint != "" && !slices.Contains(endpoints, endpoint) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,174 @@
+package ssp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPartnerHealthCheck(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected HEAD request, got %s", r.Method)
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Type: "openrtb", Endpoint: server.URL, Active: true})
+	pm.AddPartner(&SupplyPartner{ID: "dsp-paused", Type: "openrtb", Endpoint: server.URL, Active: false})
+
+	health := map[string]bool{}
+	pm.OnHealthCheck = func(partnerID string, healthy bool) {
+		health[partnerID] = healthy
+	}
+
+	active := func() bool {
+		p, _ := pm.GetPartner("dsp-1")
+		return p.Active
+	}
+
+	ctx := context.Background()
+	pm.checkPartnerHealth(ctx, server.Client())
+	if !active() {
+		t.Error("Expected partner to stay active after one failed check")
+	}
+	if health["dsp-1"] {
+		t.Error("Expected failed check to be reported unhealthy")
+	}
+
+	pm.checkPartnerHealth(ctx, server.Client())
+	if active() {
+		t.Error("Expected partner to be deactivated after two failed checks")
+	}
+
+	status.Store(http.StatusOK)
+	pm.checkPartnerHealth(ctx, server.Client())
+	if !active() {
+		t.Error("Expected partner to be reactivated after recovering")
+	}
+	if !health["dsp-1"] {
+		t.Error("Expected recovered partner to be reported healthy")
+	}
+
+	if _, checked := health["dsp-paused"]; checked {
+		t.Error("Expected partner deactivated by configuration not to be checked")
+	}
+	if p, _ := pm.GetPartner("dsp-paused"); p.Active {
+		t.Error("Expected partner deactivated by configuration to stay inactive")
+	}
+}
+
+func TestPartnerHealthCheckFailuresMustBeConsecutive(t *testing.T) {
+	var status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Type: "openrtb", Endpoint: server.URL, Active: true})
+
+	ctx := context.Background()
+	for _, code := range []int{http.StatusBadGateway, http.StatusOK, http.StatusBadGateway} {
+		status.Store(int32(code))
+		pm.checkPartnerHealth(ctx, server.Client())
+	}
+
+	if len(pm.GetActivePartners()) != 1 {
+		t.Error("Expected non-consecutive failures to keep the partner active")
+	}
+}
+
+func TestPartnerHealthCheckURL(t *testing.T) {
+	var probed atomic.Bool
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed.Store(true)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer health.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Type: "openrtb", Endpoint: "http://127.0.0.1:1/bid", HealthCheckURL: health.URL, Active: true})
+
+	ctx := context.Background()
+	pm.checkPartnerHealth(ctx, health.Client())
+	pm.checkPartnerHealth(ctx, health.Client())
+
+	if !probed.Load() {
+		t.Error("Expected the health check URL to be probed")
+	}
+	if len(pm.GetActivePartners()) != 0 {
+		t.Error("Expected partner failing its health check URL to be deactivated")
+	}
+}
+
+func TestPartnerHealthCheckEveryEndpoint(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	var downProbed atomic.Bool
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downProbed.Store(true)
+		w.WriteHeader(http.StatusMethodNotAllowed) // Any non-200 is unhealthy
+	}))
+	defer down.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Type: "openrtb", Endpoints: []string{up.URL, down.URL}, Active: true})
+
+	ctx := context.Background()
+	pm.checkPartnerHealth(ctx, up.Client())
+	pm.checkPartnerHealth(ctx, up.Client())
+
+	if !downProbed.Load() {
+		t.Error("Expected every regional endpoint to be probed")
+	}
+	if len(pm.GetActivePartners()) != 0 {
+		t.Error("Expected partner with a failing regional endpoint to be deactivated")
+	}
+}
+
+func TestPartnerHealthStateNotPersisted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "dsp-1", Type: "openrtb", Endpoint: server.URL, Active: true})
+	pm.AddPartner(&SupplyPartner{ID: "dsp-paused", Type: "openrtb", Endpoint: server.URL, Active: false})
+
+	ctx := context.Background()
+	pm.checkPartnerHealth(ctx, server.Client())
+	pm.checkPartnerHealth(ctx, server.Client())
+	if len(pm.GetActivePartners()) != 0 {
+		t.Fatal("Expected partner to be deactivated by the health checker")
+	}
+
+	data, err := json.Marshal(pm)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	restored := NewPartnerManager()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if p, _ := restored.GetPartner("dsp-1"); !p.Active {
+		t.Error("Expected partner deactivated by the health checker to be persisted as active")
+	}
+	if p, _ := restored.GetPartner("dsp-paused"); p.Active {
+		t.Error("Expected partner deactivated by configuration to stay inactive")
+	}
+	if p, _ := pm.GetPartner("dsp-1"); p.Active {
+		t.Error("Expected marshaling not to reactivate the live partner")
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -27,85 +30,47 @@ func NewPostgresStore(connString string) (*PostgresStore, error) {
 
 	store := &PostgresStore{db: db}
 
-	// Create tables if they don't exist
-	if err := store.createTables(); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	// Apply pending schema migrations
+	if err := store.migrate(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return store, nil
 }
 
-// createTables creates the necessary database tables
-func (ps *PostgresStore) createTables() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS publishers (
-		id VARCHAR(255) PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) NOT NULL,
-		domain VARCHAR(255) NOT NULL,
-		active BOOLEAN DEFAULT true,
-		rev_share DECIMAL(3, 2) DEFAULT 0.70,
-		payment_info TEXT,
-		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
-	);
-
-	CREATE TABLE IF NOT EXISTS sites (
-		id VARCHAR(255) PRIMARY KEY,
-		publisher_id VARCHAR(255) NOT NULL REFERENCES publishers(id) ON DELETE CASCADE,
-		name VARCHAR(255) NOT NULL,
-		domain VARCHAR(255) NOT NULL,
-		page VARCHAR(500),
-		cat JSONB,
-		active BOOLEAN DEFAULT true,
-		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
-	);
-
-	CREATE TABLE IF NOT EXISTS placements (
-		id VARCHAR(255) PRIMARY KEY,
-		site_id VARCHAR(255) NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
-		name VARCHAR(255) NOT NULL,
-		ad_type VARCHAR(50) NOT NULL,
-		width INT,
-		height INT,
-		min_bid_floor DECIMAL(10, 4) DEFAULT 0,
-		active BOOLEAN DEFAULT true,
-		formats JSONB,
-		video JSONB,
-		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_publishers_active ON publishers(active);
-	CREATE INDEX IF NOT EXISTS idx_publishers_email ON publishers(email);
-	CREATE INDEX IF NOT EXISTS idx_sites_publisher_id ON sites(publisher_id);
-	CREATE INDEX IF NOT EXISTS idx_sites_active ON sites(active);
-	CREATE INDEX IF NOT EXISTS idx_placements_site_id ON placements(site_id);
-	CREATE INDEX IF NOT EXISTS idx_placements_active ON placements(active);
-	`
-
-	_, err := ps.db.Exec(schema)
-	return err
-}
-
 // Publisher operations
 
 // CreatePublisher creates a new publisher
 func (ps *PostgresStore) CreatePublisher(ctx context.Context, pub *Publisher) error {
+	metadataJSON, err := json.Marshal(pub.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(pub.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
 	query := `
-		INSERT INTO publishers (id, name, email, domain, active, rev_share, payment_info, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO publishers (id, name, email, domain, active, status, status_reason, rev_share, payment_info, notes, metadata, max_placements_per_site, blocked_categories, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
-	_, err := ps.db.ExecContext(ctx, query,
+	_, err = ps.db.ExecContext(ctx, query,
 		pub.ID,
 		pub.Name,
 		pub.Email,
 		pub.Domain,
 		pub.Active,
+		pub.Status,
+		pub.StatusReason,
 		pub.RevShare,
 		pub.PaymentInfo,
+		pub.Notes,
+		metadataJSON,
+		pub.MaxPlacementsPerSite,
+		blockedCategoriesJSON,
 		pub.CreatedAt,
 		pub.UpdatedAt,
 	)
@@ -113,29 +78,72 @@ func (ps *PostgresStore) CreatePublisher(ctx context.Context, pub *Publisher) er
 	return err
 }
 
-// GetPublisher retrieves a publisher by ID
-func (ps *PostgresStore) GetPublisher(ctx context.Context, id string) (*Publisher, error) {
-	query := `
-		SELECT id, name, email, domain, active, rev_share, payment_info, created_at, updated_at
-		FROM publishers
-		WHERE id = $1
-	`
-
+// scanPublisher scans a publisher row selected as
+// id, name, email, domain, active, status, status_reason, rev_share, payment_info, notes, metadata, max_placements_per_site, blocked_categories, created_at, updated_at
+func scanPublisher(row rowScanner) (*Publisher, error) {
 	pub := &Publisher{}
-	var paymentInfo sql.NullString
+	var paymentInfo, statusReason, notes sql.NullString
+	var maxPlacementsPerSite sql.NullInt32
+	var metadataJSON, blockedCategoriesJSON []byte
 
-	err := ps.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&pub.ID,
 		&pub.Name,
 		&pub.Email,
 		&pub.Domain,
 		&pub.Active,
+		&pub.Status,
+		&statusReason,
 		&pub.RevShare,
 		&paymentInfo,
+		&notes,
+		&metadataJSON,
+		&maxPlacementsPerSite,
+		&blockedCategoriesJSON,
 		&pub.CreatedAt,
 		&pub.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if paymentInfo.Valid {
+		pub.PaymentInfo = paymentInfo.String
+	}
+	if statusReason.Valid {
+		pub.StatusReason = statusReason.String
+	}
+	if notes.Valid {
+		pub.Notes = notes.String
+	}
+	if maxPlacementsPerSite.Valid {
+		pub.MaxPlacementsPerSite = int(maxPlacementsPerSite.Int32)
+	}
+
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &pub.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	if len(blockedCategoriesJSON) > 0 {
+		if err := json.Unmarshal(blockedCategoriesJSON, &pub.BlockedCategories); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blocked categories: %w", err)
+		}
+	}
+
+	return pub, nil
+}
+
+// GetPublisher retrieves a publisher by ID
+func (ps *PostgresStore) GetPublisher(ctx context.Context, id string) (*Publisher, error) {
+	query := `
+		SELECT id, name, email, domain, active, status, status_reason, rev_share, payment_info, notes, metadata, max_placements_per_site, blocked_categories, created_at, updated_at
+		FROM publishers
+		WHERE id = $1
+	`
 
+	pub, err := scanPublisher(ps.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("publisher not found: %s", id)
 	}
@@ -143,8 +151,24 @@ func (ps *PostgresStore) GetPublisher(ctx context.Context, id string) (*Publishe
 		return nil, err
 	}
 
-	if paymentInfo.Valid {
-		pub.PaymentInfo = paymentInfo.String
+	return pub, nil
+}
+
+// GetPublisherBySite retrieves the publisher that owns a site in a single query
+func (ps *PostgresStore) GetPublisherBySite(ctx context.Context, siteID string) (*Publisher, error) {
+	query := `
+		SELECT p.id, p.name, p.email, p.domain, p.active, p.status, p.status_reason, p.rev_share, p.payment_info, p.notes, p.metadata, p.max_placements_per_site, p.blocked_categories, p.created_at, p.updated_at
+		FROM publishers p
+		JOIN sites s ON s.publisher_id = p.id
+		WHERE s.id = $1
+	`
+
+	pub, err := scanPublisher(ps.db.QueryRowContext(ctx, query, siteID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("publisher not found for site: %s", siteID)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return pub, nil
@@ -153,7 +177,7 @@ func (ps *PostgresStore) GetPublisher(ctx context.Context, id string) (*Publishe
 // ListPublishers lists publishers
 func (ps *PostgresStore) ListPublishers(ctx context.Context, activeOnly bool) ([]*Publisher, error) {
 	query := `
-		SELECT id, name, email, domain, active, rev_share, payment_info, created_at, updated_at
+		SELECT id, name, email, domain, active, status, status_reason, rev_share, payment_info, notes, metadata, max_placements_per_site, blocked_categories, created_at, updated_at
 		FROM publishers
 	`
 
@@ -172,44 +196,62 @@ func (ps *PostgresStore) ListPublishers(ctx context.Context, activeOnly bool) ([
 	publishers := []*Publisher{}
 
 	for rows.Next() {
-		pub := &Publisher{}
-		var paymentInfo sql.NullString
-
-		err := rows.Scan(
-			&pub.ID,
-			&pub.Name,
-			&pub.Email,
-			&pub.Domain,
-			&pub.Active,
-			&pub.RevShare,
-			&paymentInfo,
-			&pub.CreatedAt,
-			&pub.UpdatedAt,
-		)
-
+		pub, err := scanPublisher(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if paymentInfo.Valid {
-			pub.PaymentInfo = paymentInfo.String
-		}
-
 		publishers = append(publishers, pub)
 	}
 
 	return publishers, nil
 }
 
-// UpdatePublisher updates a publisher
-func (ps *PostgresStore) UpdatePublisher(ctx context.Context, pub *Publisher) error {
+// UpdatePublisher updates a publisher. A change of RevShare closes the
+// publisher's current revenue share period and opens a new one attributed to changedBy.
+func (ps *PostgresStore) UpdatePublisher(ctx context.Context, pub *Publisher, changedBy string) error {
+	metadataJSON, err := json.Marshal(pub.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(pub.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Compare at the column's precision so an unchanged rate records no history
+	var revShareChanged bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT rev_share IS DISTINCT FROM CAST($2 AS DECIMAL(3, 2)) FROM publishers WHERE id = $1 FOR UPDATE",
+		pub.ID, pub.RevShare,
+	).Scan(&revShareChanged)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("publisher not found: %s", pub.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if revShareChanged {
+		if err := closeRevSharePeriod(ctx, tx, pub.ID, pub.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE publishers
-		SET name = $2, email = $3, domain = $4, active = $5, rev_share = $6, payment_info = $7, updated_at = $8
+		SET name = $2, email = $3, domain = $4, active = $5, rev_share = $6, payment_info = $7, notes = $8, metadata = $9, max_placements_per_site = $10, blocked_categories = $11, updated_at = $12
 		WHERE id = $1
 	`
 
-	_, err := ps.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		pub.ID,
 		pub.Name,
 		pub.Email,
@@ -217,12 +259,110 @@ func (ps *PostgresStore) UpdatePublisher(ctx context.Context, pub *Publisher) er
 		pub.Active,
 		pub.RevShare,
 		pub.PaymentInfo,
+		pub.Notes,
+		metadataJSON,
+		pub.MaxPlacementsPerSite,
+		blockedCategoriesJSON,
 		pub.UpdatedAt,
 	)
+	if err != nil {
+		return err
+	}
+
+	if revShareChanged {
+		query = `
+			INSERT INTO publisher_revshare_history (publisher_id, rev_share, effective_from, changed_by)
+			VALUES ($1, $2, $3, $4)
+		`
+		if _, err := tx.ExecContext(ctx, query, pub.ID, pub.RevShare, pub.UpdatedAt, changedBy); err != nil {
+			return err
+		}
+	}
 
+	return tx.Commit()
+}
+
+// closeRevSharePeriod ends the publisher's current revenue share period at
+// effectiveTo. Publishers without one get a closed period covering their rate
+// since signup.
+func closeRevSharePeriod(ctx context.Context, tx *sql.Tx, publisherID string, effectiveTo time.Time) error {
+	query := `
+		UPDATE publisher_revshare_history
+		SET effective_to = $2
+		WHERE publisher_id = $1 AND effective_to IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, publisherID, effectiveTo)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	query = `
+		INSERT INTO publisher_revshare_history (publisher_id, rev_share, effective_from, effective_to)
+		SELECT id, rev_share, created_at, $2
+		FROM publishers
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, publisherID, effectiveTo)
 	return err
 }
 
+// GetRevShareHistory lists a publisher's revenue share periods, oldest first
+func (ps *PostgresStore) GetRevShareHistory(ctx context.Context, publisherID string) ([]*RevShareHistory, error) {
+	query := `
+		SELECT publisher_id, rev_share, effective_from, effective_to, changed_by
+		FROM publisher_revshare_history
+		WHERE publisher_id = $1
+		ORDER BY effective_from, id
+	`
+
+	rows, err := ps.db.QueryContext(ctx, query, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*RevShareHistory{}
+	for rows.Next() {
+		h := &RevShareHistory{}
+		var effectiveTo sql.NullTime
+		var changedBy sql.NullString
+		if err := rows.Scan(&h.PublisherID, &h.RevShare, &h.EffectiveFrom, &effectiveTo, &changedBy); err != nil {
+			return nil, err
+		}
+		if effectiveTo.Valid {
+			h.EffectiveTo = &effectiveTo.Time
+		}
+		h.ChangedBy = changedBy.String
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+// SetPublisherStatus changes a publisher's onboarding status. Only active
+// publishers are marked active for serving.
+func (ps *PostgresStore) SetPublisherStatus(ctx context.Context, id, status, reason string) error {
+	query := `
+		UPDATE publishers
+		SET status = $2, status_reason = $3, active = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	result, err := ps.db.ExecContext(ctx, query, id, status, reason, status == PublisherStatusActive, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("publisher not found: %s", id)
+	}
+
+	return nil
+}
+
 // DeletePublisher deletes a publisher
 func (ps *PostgresStore) DeletePublisher(ctx context.Context, id string) error {
 	query := "DELETE FROM publishers WHERE id = $1"
@@ -232,6 +372,65 @@ func (ps *PostgresStore) DeletePublisher(ctx context.Context, id string) error {
 
 // Site operations
 
+// siteColumns lists the site columns in the order scanSite expects
+const siteColumns = `id, publisher_id, name, domain, page, cat, content_rating, language, impression_cap, blocked_categories, active, created_at, updated_at`
+
+// scanSite scans a site row selected with siteColumns
+func scanSite(row rowScanner) (*Site, error) {
+	site := &Site{}
+	var page, contentRating, language sql.NullString
+	var catJSON, capJSON, blockedCategoriesJSON []byte
+
+	err := row.Scan(
+		&site.ID,
+		&site.PublisherID,
+		&site.Name,
+		&site.Domain,
+		&page,
+		&catJSON,
+		&contentRating,
+		&language,
+		&capJSON,
+		&blockedCategoriesJSON,
+		&site.Active,
+		&site.CreatedAt,
+		&site.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Valid {
+		site.Page = page.String
+	}
+	if contentRating.Valid {
+		site.ContentRating = contentRating.String
+	}
+	if language.Valid {
+		site.Language = language.String
+	}
+
+	if len(catJSON) > 0 {
+		if err := json.Unmarshal(catJSON, &site.Cat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
+		}
+	}
+
+	if len(capJSON) > 0 {
+		if err := json.Unmarshal(capJSON, &site.ImpressionCap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal impression cap: %w", err)
+		}
+	}
+
+	if len(blockedCategoriesJSON) > 0 {
+		if err := json.Unmarshal(blockedCategoriesJSON, &site.BlockedCategories); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blocked categories: %w", err)
+		}
+	}
+
+	return site, nil
+}
+
 // CreateSite creates a new site
 func (ps *PostgresStore) CreateSite(ctx context.Context, site *Site) error {
 	catJSON, err := json.Marshal(site.Cat)
@@ -239,9 +438,19 @@ func (ps *PostgresStore) CreateSite(ctx context.Context, site *Site) error {
 		return fmt.Errorf("failed to marshal categories: %w", err)
 	}
 
+	capJSON, err := json.Marshal(site.ImpressionCap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal impression cap: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(site.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
 	query := `
-		INSERT INTO sites (id, publisher_id, name, domain, page, cat, active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO sites (id, publisher_id, name, domain, page, cat, content_rating, language, impression_cap, blocked_categories, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	_, err = ps.db.ExecContext(ctx, query,
@@ -251,6 +460,10 @@ func (ps *PostgresStore) CreateSite(ctx context.Context, site *Site) error {
 		site.Domain,
 		site.Page,
 		catJSON,
+		site.ContentRating,
+		site.Language,
+		capJSON,
+		blockedCategoriesJSON,
 		site.Active,
 		site.CreatedAt,
 		site.UpdatedAt,
@@ -262,27 +475,12 @@ func (ps *PostgresStore) CreateSite(ctx context.Context, site *Site) error {
 // GetSite retrieves a site by ID
 func (ps *PostgresStore) GetSite(ctx context.Context, id string) (*Site, error) {
 	query := `
-		SELECT id, publisher_id, name, domain, page, cat, active, created_at, updated_at
+		SELECT ` + siteColumns + `
 		FROM sites
 		WHERE id = $1
 	`
 
-	site := &Site{}
-	var page sql.NullString
-	var catJSON []byte
-
-	err := ps.db.QueryRowContext(ctx, query, id).Scan(
-		&site.ID,
-		&site.PublisherID,
-		&site.Name,
-		&site.Domain,
-		&page,
-		&catJSON,
-		&site.Active,
-		&site.CreatedAt,
-		&site.UpdatedAt,
-	)
-
+	site, err := scanSite(ps.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("site not found: %s", id)
 	}
@@ -290,14 +488,26 @@ func (ps *PostgresStore) GetSite(ctx context.Context, id string) (*Site, error)
 		return nil, err
 	}
 
-	if page.Valid {
-		site.Page = page.String
-	}
+	return site, nil
+}
 
-	if len(catJSON) > 0 {
-		if err := json.Unmarshal(catJSON, &site.Cat); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
-		}
+// GetSiteByDomain retrieves the active site registered for a domain. When
+// several sites share the domain the most recently created one is returned.
+func (ps *PostgresStore) GetSiteByDomain(ctx context.Context, domain string) (*Site, error) {
+	query := `
+		SELECT ` + siteColumns + `
+		FROM sites
+		WHERE domain = $1 AND active = true
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	site, err := scanSite(ps.db.QueryRowContext(ctx, query, domain))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("site not found for domain: %s", domain)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	return site, nil
@@ -306,7 +516,7 @@ func (ps *PostgresStore) GetSite(ctx context.Context, id string) (*Site, error)
 // ListSites lists sites for a publisher
 func (ps *PostgresStore) ListSites(ctx context.Context, publisherID string, activeOnly bool) ([]*Site, error) {
 	query := `
-		SELECT id, publisher_id, name, domain, page, cat, active, created_at, updated_at
+		SELECT ` + siteColumns + `
 		FROM sites
 		WHERE publisher_id = $1
 	`
@@ -326,36 +536,11 @@ func (ps *PostgresStore) ListSites(ctx context.Context, publisherID string, acti
 	sites := []*Site{}
 
 	for rows.Next() {
-		site := &Site{}
-		var page sql.NullString
-		var catJSON []byte
-
-		err := rows.Scan(
-			&site.ID,
-			&site.PublisherID,
-			&site.Name,
-			&site.Domain,
-			&page,
-			&catJSON,
-			&site.Active,
-			&site.CreatedAt,
-			&site.UpdatedAt,
-		)
-
+		site, err := scanSite(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if page.Valid {
-			site.Page = page.String
-		}
-
-		if len(catJSON) > 0 {
-			if err := json.Unmarshal(catJSON, &site.Cat); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
-			}
-		}
-
 		sites = append(sites, site)
 	}
 
@@ -369,9 +554,19 @@ func (ps *PostgresStore) UpdateSite(ctx context.Context, site *Site) error {
 		return fmt.Errorf("failed to marshal categories: %w", err)
 	}
 
+	capJSON, err := json.Marshal(site.ImpressionCap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal impression cap: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(site.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
 	query := `
 		UPDATE sites
-		SET name = $2, domain = $3, page = $4, cat = $5, active = $6, updated_at = $7
+		SET name = $2, domain = $3, page = $4, cat = $5, content_rating = $6, language = $7, impression_cap = $8, blocked_categories = $9, active = $10, updated_at = $11
 		WHERE id = $1
 	`
 
@@ -381,6 +576,10 @@ func (ps *PostgresStore) UpdateSite(ctx context.Context, site *Site) error {
 		site.Domain,
 		site.Page,
 		catJSON,
+		site.ContentRating,
+		site.Language,
+		capJSON,
+		blockedCategoriesJSON,
 		site.Active,
 		site.UpdatedAt,
 	)
@@ -397,54 +596,34 @@ func (ps *PostgresStore) DeleteSite(ctx context.Context, id string) error {
 
 // Placement operations
 
-// CreatePlacement creates a new placement
-func (ps *PostgresStore) CreatePlacement(ctx context.Context, placement *Placement) error {
-	formatsJSON, err := json.Marshal(placement.Formats)
-	if err != nil {
-		return fmt.Errorf("failed to marshal formats: %w", err)
-	}
+// placementColumns lists the placement columns in the order scanPlacement expects
+const placementColumns = `id, site_id, name, ad_type, width, height, min_bid_floor, active, formats, video, timeout_ms, deals, placement_type, reward_callback_url, auction_type, schedule_enabled, schedule, min_fill_rate, dooh, interstitial, blocked_domains, min_width, min_height, fallback_image_url, blocked_categories, screen_metadata, app, created_at, updated_at`
 
-	videoJSON, err := json.Marshal(placement.Video)
-	if err != nil {
-		return fmt.Errorf("failed to marshal video settings: %w", err)
+// qualifyColumns prefixes each column in a comma-separated list with a table
+// alias, for selecting one table's columns in a JOIN
+func qualifyColumns(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, col := range cols {
+		cols[i] = alias + "." + col
 	}
-
-	query := `
-		INSERT INTO placements (id, site_id, name, ad_type, width, height, min_bid_floor, active, formats, video, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`
-
-	_, err = ps.db.ExecContext(ctx, query,
-		placement.ID,
-		placement.SiteID,
-		placement.Name,
-		placement.AdType,
-		placement.Width,
-		placement.Height,
-		placement.MinBidFloor,
-		placement.Active,
-		formatsJSON,
-		videoJSON,
-		placement.CreatedAt,
-		placement.UpdatedAt,
-	)
-
-	return err
+	return strings.Join(cols, ", ")
 }
 
-// GetPlacement retrieves a placement by ID
-func (ps *PostgresStore) GetPlacement(ctx context.Context, id string) (*Placement, error) {
-	query := `
-		SELECT id, site_id, name, ad_type, width, height, min_bid_floor, active, formats, video, created_at, updated_at
-		FROM placements
-		WHERE id = $1
-	`
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanPlacement scans a placement row selected with placementColumns
+func scanPlacement(row rowScanner) (*Placement, error) {
 	placement := &Placement{}
-	var width, height sql.NullInt32
-	var formatsJSON, videoJSON []byte
+	var width, height, timeoutMs, auctionType, minWidth, minHeight sql.NullInt32
+	var placementType, rewardCallbackURL, fallbackImageURL sql.NullString
+	var scheduleEnabled, interstitial sql.NullBool
+	var minFillRate sql.NullFloat64
+	var formatsJSON, videoJSON, dealsJSON, scheduleJSON, doohJSON, blockedDomainsJSON, blockedCategoriesJSON, screenMetadataJSON, appJSON []byte
 
-	err := ps.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&placement.ID,
 		&placement.SiteID,
 		&placement.Name,
@@ -455,13 +634,26 @@ func (ps *PostgresStore) GetPlacement(ctx context.Context, id string) (*Placemen
 		&placement.Active,
 		&formatsJSON,
 		&videoJSON,
+		&timeoutMs,
+		&dealsJSON,
+		&placementType,
+		&rewardCallbackURL,
+		&auctionType,
+		&scheduleEnabled,
+		&scheduleJSON,
+		&minFillRate,
+		&doohJSON,
+		&interstitial,
+		&blockedDomainsJSON,
+		&minWidth,
+		&minHeight,
+		&fallbackImageURL,
+		&blockedCategoriesJSON,
+		&screenMetadataJSON,
+		&appJSON,
 		&placement.CreatedAt,
 		&placement.UpdatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("placement not found: %s", id)
-	}
 	if err != nil {
 		return nil, err
 	}
@@ -472,6 +664,32 @@ func (ps *PostgresStore) GetPlacement(ctx context.Context, id string) (*Placemen
 	if height.Valid {
 		placement.Height = int(height.Int32)
 	}
+	if timeoutMs.Valid {
+		placement.TimeoutMs = int(timeoutMs.Int32)
+	}
+	if placementType.Valid {
+		placement.PlacementType = placementType.String
+	}
+	if rewardCallbackURL.Valid {
+		placement.RewardCallbackURL = rewardCallbackURL.String
+	}
+	if fallbackImageURL.Valid {
+		placement.FallbackImageURL = fallbackImageURL.String
+	}
+	if auctionType.Valid {
+		placement.AuctionType = int(auctionType.Int32)
+	}
+	if minWidth.Valid {
+		placement.MinWidth = int(minWidth.Int32)
+	}
+	if minHeight.Valid {
+		placement.MinHeight = int(minHeight.Int32)
+	}
+	placement.ScheduleEnabled = scheduleEnabled.Valid && scheduleEnabled.Bool
+	placement.Interstitial = interstitial.Valid && interstitial.Bool
+	if minFillRate.Valid {
+		placement.MinFillRate = minFillRate.Float64
+	}
 
 	if len(formatsJSON) > 0 {
 		if err := json.Unmarshal(formatsJSON, &placement.Formats); err != nil {
@@ -485,16 +703,170 @@ func (ps *PostgresStore) GetPlacement(ctx context.Context, id string) (*Placemen
 		}
 	}
 
+	if len(dealsJSON) > 0 {
+		if err := json.Unmarshal(dealsJSON, &placement.Deals); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal deals: %w", err)
+		}
+	}
+
+	if len(scheduleJSON) > 0 {
+		if err := json.Unmarshal(scheduleJSON, &placement.Schedule); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schedule: %w", err)
+		}
+	}
+
+	if len(doohJSON) > 0 {
+		if err := json.Unmarshal(doohJSON, &placement.DOOH); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dooh settings: %w", err)
+		}
+	}
+
+	if len(blockedDomainsJSON) > 0 {
+		if err := json.Unmarshal(blockedDomainsJSON, &placement.BlockedDomains); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blocked domains: %w", err)
+		}
+	}
+
+	if len(blockedCategoriesJSON) > 0 {
+		if err := json.Unmarshal(blockedCategoriesJSON, &placement.BlockedCategories); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blocked categories: %w", err)
+		}
+	}
+
+	if len(screenMetadataJSON) > 0 {
+		if err := json.Unmarshal(screenMetadataJSON, &placement.ScreenMetadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal screen metadata: %w", err)
+		}
+	}
+
+	if len(appJSON) > 0 {
+		if err := json.Unmarshal(appJSON, &placement.App); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal app: %w", err)
+		}
+	}
+
+	return placement, nil
+}
+
+// CreatePlacement creates a new placement
+func (ps *PostgresStore) CreatePlacement(ctx context.Context, placement *Placement) error {
+	formatsJSON, err := json.Marshal(placement.Formats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal formats: %w", err)
+	}
+
+	videoJSON, err := json.Marshal(placement.Video)
+	if err != nil {
+		return fmt.Errorf("failed to marshal video settings: %w", err)
+	}
+
+	dealsJSON, err := json.Marshal(placement.Deals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deals: %w", err)
+	}
+
+	scheduleJSON, err := json.Marshal(placement.Schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	doohJSON, err := json.Marshal(placement.DOOH)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dooh settings: %w", err)
+	}
+
+	blockedDomainsJSON, err := json.Marshal(placement.BlockedDomains)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked domains: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(placement.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
+	screenMetadataJSON, err := json.Marshal(placement.ScreenMetadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal screen metadata: %w", err)
+	}
+
+	appJSON, err := json.Marshal(placement.App)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app: %w", err)
+	}
+
+	query := `
+		INSERT INTO placements (id, site_id, name, ad_type, width, height, min_bid_floor, active, formats, video, timeout_ms, deals, placement_type, reward_callback_url, auction_type, schedule_enabled, schedule, min_fill_rate, dooh, interstitial, blocked_domains, min_width, min_height, fallback_image_url, blocked_categories, screen_metadata, app, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29)
+	`
+
+	_, err = ps.db.ExecContext(ctx, query,
+		placement.ID,
+		placement.SiteID,
+		placement.Name,
+		placement.AdType,
+		placement.Width,
+		placement.Height,
+		placement.MinBidFloor,
+		placement.Active,
+		formatsJSON,
+		videoJSON,
+		placement.TimeoutMs,
+		dealsJSON,
+		placement.PlacementType,
+		placement.RewardCallbackURL,
+		placement.AuctionType,
+		placement.ScheduleEnabled,
+		scheduleJSON,
+		placement.MinFillRate,
+		doohJSON,
+		placement.Interstitial,
+		blockedDomainsJSON,
+		placement.MinWidth,
+		placement.MinHeight,
+		placement.FallbackImageURL,
+		blockedCategoriesJSON,
+		screenMetadataJSON,
+		appJSON,
+		placement.CreatedAt,
+		placement.UpdatedAt,
+	)
+
+	return err
+}
+
+// GetPlacement retrieves a placement by ID
+func (ps *PostgresStore) GetPlacement(ctx context.Context, id string) (*Placement, error) {
+	query := `
+		SELECT ` + placementColumns + `
+		FROM placements
+		WHERE id = $1
+	`
+
+	placement, err := scanPlacement(ps.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("placement not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	return placement, nil
 }
 
-// ListPlacements lists placements for a site
-func (ps *PostgresStore) ListPlacements(ctx context.Context, siteID string, activeOnly bool) ([]*Placement, error) {
+// ListPlacements lists placements for a site, optionally only those of one ad type
+func (ps *PostgresStore) ListPlacements(ctx context.Context, siteID string, adType string, activeOnly bool) ([]*Placement, error) {
 	query := `
-		SELECT id, site_id, name, ad_type, width, height, min_bid_floor, active, formats, video, created_at, updated_at
+		SELECT ` + placementColumns + `
 		FROM placements
 		WHERE site_id = $1
 	`
+	args := []interface{}{siteID}
+
+	if adType != "" {
+		args = append(args, adType)
+		query += fmt.Sprintf(" AND ad_type = $%d", len(args))
+	}
 
 	if activeOnly {
 		query += " AND active = true"
@@ -502,7 +874,7 @@ func (ps *PostgresStore) ListPlacements(ctx context.Context, siteID string, acti
 
 	query += " ORDER BY created_at DESC"
 
-	rows, err := ps.db.QueryContext(ctx, query, siteID)
+	rows, err := ps.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -511,52 +883,57 @@ func (ps *PostgresStore) ListPlacements(ctx context.Context, siteID string, acti
 	placements := []*Placement{}
 
 	for rows.Next() {
-		placement := &Placement{}
-		var width, height sql.NullInt32
-		var formatsJSON, videoJSON []byte
-
-		err := rows.Scan(
-			&placement.ID,
-			&placement.SiteID,
-			&placement.Name,
-			&placement.AdType,
-			&width,
-			&height,
-			&placement.MinBidFloor,
-			&placement.Active,
-			&formatsJSON,
-			&videoJSON,
-			&placement.CreatedAt,
-			&placement.UpdatedAt,
-		)
-
+		placement, err := scanPlacement(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		if width.Valid {
-			placement.Width = int(width.Int32)
-		}
-		if height.Valid {
-			placement.Height = int(height.Int32)
-		}
+		placements = append(placements, placement)
+	}
 
-		if len(formatsJSON) > 0 {
-			if err := json.Unmarshal(formatsJSON, &placement.Formats); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal formats: %w", err)
-			}
-		}
+	return placements, nil
+}
 
-		if len(videoJSON) > 0 {
-			if err := json.Unmarshal(videoJSON, &placement.Video); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal video settings: %w", err)
-			}
+// ListPlacementsByPublisher lists the placements on all of a publisher's sites,
+// optionally only those of one ad type
+func (ps *PostgresStore) ListPlacementsByPublisher(ctx context.Context, publisherID string, adType string, activeOnly bool) ([]*Placement, error) {
+	query := `
+		SELECT ` + qualifyColumns("pl", placementColumns) + `
+		FROM placements pl
+		JOIN sites s ON s.id = pl.site_id
+		WHERE s.publisher_id = $1
+	`
+	args := []interface{}{publisherID}
+
+	if adType != "" {
+		args = append(args, adType)
+		query += fmt.Sprintf(" AND pl.ad_type = $%d", len(args))
+	}
+
+	if activeOnly {
+		query += " AND pl.active = true"
+	}
+
+	query += " ORDER BY pl.created_at DESC"
+
+	rows, err := ps.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	placements := []*Placement{}
+
+	for rows.Next() {
+		placement, err := scanPlacement(rows)
+		if err != nil {
+			return nil, err
 		}
 
 		placements = append(placements, placement)
 	}
 
-	return placements, nil
+	return placements, rows.Err()
 }
 
 // UpdatePlacement updates a placement
@@ -571,9 +948,44 @@ func (ps *PostgresStore) UpdatePlacement(ctx context.Context, placement *Placeme
 		return fmt.Errorf("failed to marshal video settings: %w", err)
 	}
 
+	dealsJSON, err := json.Marshal(placement.Deals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deals: %w", err)
+	}
+
+	scheduleJSON, err := json.Marshal(placement.Schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	doohJSON, err := json.Marshal(placement.DOOH)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dooh settings: %w", err)
+	}
+
+	blockedDomainsJSON, err := json.Marshal(placement.BlockedDomains)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked domains: %w", err)
+	}
+
+	blockedCategoriesJSON, err := json.Marshal(placement.BlockedCategories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal blocked categories: %w", err)
+	}
+
+	screenMetadataJSON, err := json.Marshal(placement.ScreenMetadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal screen metadata: %w", err)
+	}
+
+	appJSON, err := json.Marshal(placement.App)
+	if err != nil {
+		return fmt.Errorf("failed to marshal app: %w", err)
+	}
+
 	query := `
 		UPDATE placements
-		SET name = $2, ad_type = $3, width = $4, height = $5, min_bid_floor = $6, active = $7, formats = $8, video = $9, updated_at = $10
+		SET name = $2, ad_type = $3, width = $4, height = $5, min_bid_floor = $6, active = $7, formats = $8, video = $9, timeout_ms = $10, deals = $11, placement_type = $12, reward_callback_url = $13, auction_type = $14, schedule_enabled = $15, schedule = $16, min_fill_rate = $17, dooh = $18, interstitial = $19, blocked_domains = $20, min_width = $21, min_height = $22, fallback_image_url = $23, blocked_categories = $24, screen_metadata = $25, app = $26, updated_at = $27
 		WHERE id = $1
 	`
 
@@ -587,12 +999,37 @@ func (ps *PostgresStore) UpdatePlacement(ctx context.Context, placement *Placeme
 		placement.Active,
 		formatsJSON,
 		videoJSON,
+		placement.TimeoutMs,
+		dealsJSON,
+		placement.PlacementType,
+		placement.RewardCallbackURL,
+		placement.AuctionType,
+		placement.ScheduleEnabled,
+		scheduleJSON,
+		placement.MinFillRate,
+		doohJSON,
+		placement.Interstitial,
+		blockedDomainsJSON,
+		placement.MinWidth,
+		placement.MinHeight,
+		placement.FallbackImageURL,
+		blockedCategoriesJSON,
+		screenMetadataJSON,
+		appJSON,
 		placement.UpdatedAt,
 	)
 
 	return err
 }
 
+// GetPlacementCountBySite returns the number of placements on a site.
+// Placements are hard-deleted, so every row counts toward the site quota.
+func (ps *PostgresStore) GetPlacementCountBySite(ctx context.Context, siteID string) (int, error) {
+	var count int
+	err := ps.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM placements WHERE site_id = $1", siteID).Scan(&count)
+	return count, err
+}
+
 // DeletePlacement deletes a placement
 func (ps *PostgresStore) DeletePlacement(ctx context.Context, id string) error {
 	query := "DELETE FROM placements WHERE id = $1"
@@ -600,6 +1037,372 @@ func (ps *PostgresStore) DeletePlacement(ctx context.Context, id string) error {
 	return err
 }
 
+// GetEffectiveBlockedCategories returns the IAB categories blocked on a
+// placement: the union of its publisher's, site's and its own blocks
+func (ps *PostgresStore) GetEffectiveBlockedCategories(ctx context.Context, placementID string) ([]string, error) {
+	query := `
+		SELECT p.blocked_categories, s.blocked_categories, pl.blocked_categories
+		FROM placements pl
+		JOIN sites s ON s.id = pl.site_id
+		JOIN publishers p ON p.id = s.publisher_id
+		WHERE pl.id = $1
+	`
+
+	var levelsJSON [3][]byte
+	err := ps.db.QueryRowContext(ctx, query, placementID).Scan(&levelsJSON[0], &levelsJSON[1], &levelsJSON[2])
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("placement not found: %s", placementID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([][]string, len(levelsJSON))
+	for i, levelJSON := range levelsJSON {
+		if len(levelJSON) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(levelJSON, &levels[i]); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blocked categories: %w", err)
+		}
+	}
+
+	return MergeBlockedCategories(levels...), nil
+}
+
+// Geo restriction operations
+
+// GetGeoRestrictions retrieves the geo restrictions for a placement
+func (ps *PostgresStore) GetGeoRestrictions(ctx context.Context, placementID string) ([]GeoRestriction, error) {
+	query := `
+		SELECT country_code, restriction_type
+		FROM placement_geo_restrictions
+		WHERE placement_id = $1
+		ORDER BY country_code
+	`
+
+	rows, err := ps.db.QueryContext(ctx, query, placementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restrictions := []GeoRestriction{}
+	for rows.Next() {
+		var r GeoRestriction
+		if err := rows.Scan(&r.Country, &r.Type); err != nil {
+			return nil, err
+		}
+		restrictions = append(restrictions, r)
+	}
+
+	return restrictions, rows.Err()
+}
+
+// SetGeoRestrictions replaces all geo restrictions for a placement
+func (ps *PostgresStore) SetGeoRestrictions(ctx context.Context, placementID string, restrictions []GeoRestriction) error {
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM placement_geo_restrictions WHERE placement_id = $1", placementID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO placement_geo_restrictions (placement_id, country_code, restriction_type)
+		VALUES ($1, $2, $3)
+	`
+
+	for _, r := range restrictions {
+		if _, err := tx.ExecContext(ctx, query, placementID, r.Country, r.Type); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Publisher domain operations
+
+// AddPublisherDomain records a domain the publisher has verified ownership of
+func (ps *PostgresStore) AddPublisherDomain(ctx context.Context, publisherID, domain string) error {
+	query := `
+		INSERT INTO publisher_domains (publisher_id, domain, verified_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (publisher_id, domain) DO UPDATE SET verified_at = EXCLUDED.verified_at
+	`
+	_, err := ps.db.ExecContext(ctx, query, publisherID, domain, time.Now())
+	return err
+}
+
+// ListPublisherDomains lists a publisher's verified domains
+func (ps *PostgresStore) ListPublisherDomains(ctx context.Context, publisherID string) ([]string, error) {
+	rows, err := ps.db.QueryContext(ctx, "SELECT domain FROM publisher_domains WHERE publisher_id = $1 ORDER BY domain", publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []string{}
+	for rows.Next() {
+		var domain string
+		if err := rows.Scan(&domain); err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+
+	return domains, rows.Err()
+}
+
+// API key operations
+
+// RotateAPIKey issues a new API key for a publisher. The current key stays
+// valid for APIKeyGracePeriod; a key already in its grace period is deleted.
+func (ps *PostgresStore) RotateAPIKey(ctx context.Context, publisherID string) (string, error) {
+	key, err := GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM api_keys WHERE publisher_id = $1 AND expires_at IS NOT NULL", publisherID); err != nil {
+		return "", err
+	}
+
+	query := `
+		UPDATE api_keys
+		SET expires_at = $2
+		WHERE publisher_id = $1 AND expires_at IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, query, publisherID, time.Now().Add(APIKeyGracePeriod)); err != nil {
+		return "", err
+	}
+
+	query = `
+		INSERT INTO api_keys (key_hash, publisher_id, created_at)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, query, HashAPIKey(key), publisherID, time.Now()); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// ErrAPIKeyNotFound is returned when an API key is unknown or expired
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// GetPublisherIDByAPIKey returns the publisher owning a valid, unexpired API key
+func (ps *PostgresStore) GetPublisherIDByAPIKey(ctx context.Context, key string) (string, error) {
+	query := `
+		SELECT publisher_id
+		FROM api_keys
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	var publisherID string
+	err := ps.db.QueryRowContext(ctx, query, HashAPIKey(key)).Scan(&publisherID)
+	if err == sql.ErrNoRows {
+		return "", ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return publisherID, nil
+}
+
+// User sync operations
+
+// SetBuyerUID stores a partner's user ID for an SSP user, replacing any previous one
+func (ps *PostgresStore) SetBuyerUID(ctx context.Context, sspUserID, partnerID, buyerUID string) error {
+	query := `
+		INSERT INTO user_id_map (ssp_user_id, partner_id, buyer_uid, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (ssp_user_id, partner_id)
+		DO UPDATE SET buyer_uid = EXCLUDED.buyer_uid, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := ps.db.ExecContext(ctx, query, sspUserID, partnerID, buyerUID)
+	return err
+}
+
+// GetBuyerUIDs returns an SSP user's synced partner user IDs keyed by partner ID
+func (ps *PostgresStore) GetBuyerUIDs(ctx context.Context, sspUserID string) (map[string]string, error) {
+	query := `
+		SELECT partner_id, buyer_uid
+		FROM user_id_map
+		WHERE ssp_user_id = $1
+	`
+
+	rows, err := ps.db.QueryContext(ctx, query, sspUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buyerUIDs := make(map[string]string)
+	for rows.Next() {
+		var partnerID, buyerUID string
+		if err := rows.Scan(&partnerID, &buyerUID); err != nil {
+			return nil, err
+		}
+		buyerUIDs[partnerID] = buyerUID
+	}
+
+	return buyerUIDs, rows.Err()
+}
+
+// Analytics retention operations
+
+// SetAnalyticsRetention stores the retention period for an analytics table
+func (ps *PostgresStore) SetAnalyticsRetention(ctx context.Context, table string, days int) error {
+	query := `
+		INSERT INTO analytics_retention_config (table_name, retention_days, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (table_name)
+		DO UPDATE SET retention_days = EXCLUDED.retention_days, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := ps.db.ExecContext(ctx, query, table, days)
+	return err
+}
+
+// ListAnalyticsRetention returns the stored retention periods for analytics tables
+func (ps *PostgresStore) ListAnalyticsRetention(ctx context.Context) ([]*AnalyticsRetention, error) {
+	query := `
+		SELECT table_name, retention_days, updated_at
+		FROM analytics_retention_config
+		ORDER BY table_name
+	`
+
+	rows, err := ps.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := []*AnalyticsRetention{}
+	for rows.Next() {
+		r := &AnalyticsRetention{}
+		if err := rows.Scan(&r.Table, &r.RetentionDays, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, r)
+	}
+
+	return settings, rows.Err()
+}
+
+// Analytics export operations
+
+// CreateAnalyticsExport records a requested analytics export
+func (ps *PostgresStore) CreateAnalyticsExport(ctx context.Context, export *AnalyticsExport) error {
+	query := `
+		INSERT INTO analytics_exports (id, table_name, export_date, bucket, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := ps.db.ExecContext(ctx, query, export.ID, export.Table, export.Date, export.Bucket, export.Status, export.CreatedAt)
+	return err
+}
+
+// SetAnalyticsExportStatus updates an export's status. Completed and failed
+// exports are stamped with their completion time.
+func (ps *PostgresStore) SetAnalyticsExportStatus(ctx context.Context, id, status, errMsg string) error {
+	query := `
+		UPDATE analytics_exports
+		SET status = $2, error = NULLIF($3, ''), completed_at = CASE WHEN $2 IN ('completed', 'failed') THEN NOW() END
+		WHERE id = $1
+	`
+
+	_, err := ps.db.ExecContext(ctx, query, id, status, errMsg)
+	return err
+}
+
+// GetAnalyticsExport retrieves an analytics export by ID
+func (ps *PostgresStore) GetAnalyticsExport(ctx context.Context, id string) (*AnalyticsExport, error) {
+	query := `
+		SELECT id, table_name, export_date, bucket, status, error, created_at, completed_at
+		FROM analytics_exports
+		WHERE id = $1
+	`
+
+	export := &AnalyticsExport{}
+	var date time.Time
+	var errMsg sql.NullString
+	var completedAt sql.NullTime
+	err := ps.db.QueryRowContext(ctx, query, id).Scan(
+		&export.ID,
+		&export.Table,
+		&date,
+		&export.Bucket,
+		&export.Status,
+		&errMsg,
+		&export.CreatedAt,
+		&completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("analytics export not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	export.Date = date.Format(ExportDateLayout)
+	export.Error = errMsg.String
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+
+	return export, nil
+}
+
+// SSP config operations
+
+// ErrConfigNotFound is returned when no SSP config value is stored for a key
+var ErrConfigNotFound = errors.New("config not found")
+
+// GetConfig returns a stored SSP config value
+func (ps *PostgresStore) GetConfig(ctx context.Context, key string) (string, error) {
+	var value string
+	err := ps.db.QueryRowContext(ctx, "SELECT value FROM ssp_config WHERE key = $1", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%w: %s", ErrConfigNotFound, key)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// SetConfig stores an SSP config value, replacing any previous one
+func (ps *PostgresStore) SetConfig(ctx context.Context, key, value string) error {
+	query := `
+		INSERT INTO ssp_config (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := ps.db.ExecContext(ctx, query, key, value)
+	return err
+}
+
 // Close closes the database connection
 func (ps *PostgresStore) Close() error {
 	return ps.db.Close()
@@ -0,0 +1,106 @@
+package ssp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQualifyColumns(t *testing.T) {
+	got := qualifyColumns("pl", "id, site_id, name")
+	if got != "pl.id, pl.site_id, pl.name" {
+		t.Errorf("Expected pl.id, pl.site_id, pl.name, got %s", got)
+	}
+
+	// Every placement column must be qualified to avoid ambiguity with sites
+	for _, col := range strings.Split(qualifyColumns("pl", placementColumns), ", ") {
+		if !strings.HasPrefix(col, "pl.") || strings.Contains(col, " ") {
+			t.Errorf("Unexpected qualified column %q", col)
+		}
+	}
+}
+
+// testPostgresStore connects to the database named by TEST_DATABASE_URL,
+// skipping the test when it is unset
+func testPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	store, err := NewPostgresStore(connString)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestUpdatePublisherRevShareHistory(t *testing.T) {
+	store := testPostgresStore(t)
+	ctx := context.Background()
+
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	pub := &Publisher{
+		ID:        "test-" + uuid.New().String(),
+		Name:      "Rev Share Test",
+		Email:     "revshare@example.com",
+		Domain:    "example.com",
+		Active:    true,
+		Status:    PublisherStatusActive,
+		RevShare:  0.7,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if err := store.CreatePublisher(ctx, pub); err != nil {
+		t.Fatalf("CreatePublisher: %v", err)
+	}
+	t.Cleanup(func() { store.DeletePublisher(context.Background(), pub.ID) })
+
+	// An update that keeps the rate records no history
+	pub.Name = "Renamed"
+	pub.UpdatedAt = created.Add(10 * time.Minute)
+	if err := store.UpdatePublisher(ctx, pub, "admin"); err != nil {
+		t.Fatalf("UpdatePublisher: %v", err)
+	}
+	history, err := store.GetRevShareHistory(ctx, pub.ID)
+	if err != nil {
+		t.Fatalf("GetRevShareHistory: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("Expected no history for an unchanged rate, got %d periods", len(history))
+	}
+
+	changed := created.Add(20 * time.Minute)
+	pub.RevShare = 0.75
+	pub.UpdatedAt = changed
+	if err := store.UpdatePublisher(ctx, pub, "admin"); err != nil {
+		t.Fatalf("UpdatePublisher: %v", err)
+	}
+
+	got, err := store.GetPublisher(ctx, pub.ID)
+	if err != nil {
+		t.Fatalf("GetPublisher: %v", err)
+	}
+	if got.Name != "Renamed" || got.RevShare != 0.75 {
+		t.Errorf("Expected updated name and rev share, got %q and %v", got.Name, got.RevShare)
+	}
+
+	history, err = store.GetRevShareHistory(ctx, pub.ID)
+	if err != nil {
+		t.Fatalf("GetRevShareHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 periods, got %d", len(history))
+	}
+	if history[0].RevShare != 0.7 || history[0].EffectiveTo == nil || !history[0].EffectiveTo.Equal(changed) {
+		t.Errorf("Expected the signup rate closed at %v, got %+v", changed, history[0])
+	}
+	if history[1].RevShare != 0.75 || history[1].EffectiveTo != nil || history[1].ChangedBy != "admin" {
+		t.Errorf("Expected an open period for the new rate, got %+v", history[1])
+	}
+}
@@ -2,10 +2,13 @@ package ssp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -59,14 +62,112 @@ type Tracking struct {
 	Quartile3  []string `json:"quartile_3"`
 }
 
+// Macros substituted in Publica tracking URLs by replaceMacros
+const (
+	MacroBidID       = "[BIDID]"
+	MacroCacheBuster = "[CACHEBUSTER]"
+	MacroPrice       = "[PRICE]"
+	MacroTimestamp   = "[TIMESTAMP]"
+	MacroSignature   = "[SIG]" // Impression pixel signature; see ImpressionSigner
+)
+
 // PublicaHandler handles Publica-specific endpoints
 type PublicaHandler struct {
-	ssp *SSP
+	ssp           *SSP
+	mu            sync.RWMutex // Guards baseURL, which SetBaseURL changes at runtime
+	baseURL       string       // Public URL of this SSP, prefixed to tracking URLs
+	ContentFilter *ContentFilter
+	ContentPolicy *PublicaContentPolicy // Optional; nil serves all content
+	BidCache      *BidCache             // Optional; holds served bids for impression pixel verification
+	Signer        *ImpressionSigner     // Optional; signs impression pixels
+}
+
+// NewPublicaHandler creates a new Publica handler whose tracking URLs point at baseURL
+func NewPublicaHandler(ssp *SSP, baseURL string) *PublicaHandler {
+	return &PublicaHandler{
+		ssp:           ssp,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		ContentFilter: &ContentFilter{AdultFloorPremium: DefaultAdultFloorPremium},
+	}
+}
+
+// SetBaseURL changes the URL prefixed to tracking URLs from now on
+func (h *PublicaHandler) SetBaseURL(baseURL string) {
+	h.mu.Lock()
+	h.baseURL = strings.TrimRight(baseURL, "/")
+	h.mu.Unlock()
+}
+
+func (h *PublicaHandler) currentBaseURL() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.baseURL
 }
 
-// NewPublicaHandler creates a new Publica handler
-func NewPublicaHandler(ssp *SSP) *PublicaHandler {
-	return &PublicaHandler{ssp: ssp}
+// replaceMacros substitutes each macro in vars with its query-escaped value.
+// Macros without a value, such as player-side macros, are left in place.
+func replaceMacros(trackingURL string, vars map[string]string) string {
+	oldnew := make([]string, 0, 2*len(vars))
+	for macro, value := range vars {
+		oldnew = append(oldnew, macro, url.QueryEscape(value))
+	}
+	return strings.NewReplacer(oldnew...).Replace(trackingURL)
+}
+
+// landingURL returns the creative's click-through URL from bid.ext.clickthrough,
+// or "" when the bid has no http(s) click URL. adomain names the advertiser,
+// not a landing page, so it is never used here.
+func landingURL(bid openrtb2.Bid) string {
+	var ext struct {
+		ClickThrough string `json:"clickthrough"`
+	}
+	if len(bid.Ext) == 0 || json.Unmarshal(bid.Ext, &ext) != nil {
+		return ""
+	}
+	u, err := url.Parse(ext.ClickThrough)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return ext.ClickThrough
+}
+
+// cacheBid holds a served bid in the bid cache until its impression pixel
+// fires. It returns the served ID the bid's tracking URLs carry.
+func (h *PublicaHandler) cacheBid(bid openrtb2.Bid, publisherID, siteID string) string {
+	servedID := uuid.New().String()
+	if h.BidCache == nil {
+		return servedID
+	}
+	h.BidCache.PutEntry(&BidCacheEntry{
+		ServedID: servedID,
+		Bid: &Bid{
+			ID:      bid.ID,
+			ImpID:   bid.ImpID,
+			Price:   bid.Price,
+			NURL:    bid.NURL,
+			ADM:     bid.AdM,
+			ADomain: bid.ADomain,
+			CRID:    bid.CrID,
+			DealID:  bid.DealID,
+			Exp:     int(bid.Exp),
+		},
+		SiteID:       siteID,
+		PublisherID:  publisherID,
+		ClearedPrice: bid.Price,
+	})
+	return servedID
+}
+
+// trackingMacros returns the macro values for a bid's tracking URLs; [BIDID]
+// is the bid's served ID
+func (h *PublicaHandler) trackingMacros(bid openrtb2.Bid, servedID, cacheBuster string, now time.Time) map[string]string {
+	return map[string]string{
+		MacroBidID:       servedID,
+		MacroCacheBuster: cacheBuster,
+		MacroPrice:       strconv.FormatFloat(bid.Price, 'f', -1, 64),
+		MacroTimestamp:   strconv.FormatInt(now.UnixMilli(), 10),
+		MacroSignature:   h.Signer.Sign(servedID, now.UnixMilli()),
+	}
 }
 
 // HandleSSAI handles Server-Side Ad Insertion requests from Publica
@@ -77,6 +178,12 @@ func (h *PublicaHandler) HandleSSAI(c *gin.Context) {
 		return
 	}
 
+	// Content above the policy's maximum rating is not monetized
+	if h.ContentPolicy != nil && !h.ContentPolicy.AllowsRating(req.ContentRating) {
+		c.Data(http.StatusOK, "application/xml", []byte(emptyVAST()))
+		return
+	}
+
 	// Convert Publica request to OpenRTB bid request
 	bidRequest := h.convertToOpenRTB(&req)
 
@@ -85,7 +192,7 @@ func (h *PublicaHandler) HandleSSAI(c *gin.Context) {
 	defer cancel()
 
 	// Get bids from partners (including BidsCube if it's a P1 publisher)
-	bidResponse, err := h.ssp.processRequest(ctx, bidRequest)
+	bidResponse, err := h.ssp.processRequest(ctx, bidRequest, time.Duration(bidRequest.TMax)*time.Millisecond)
 	if err != nil {
 		c.JSON(http.StatusNoContent, gin.H{"message": "No ads available"})
 		return
@@ -155,7 +262,7 @@ func (h *PublicaHandler) HandleVAST(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 	defer cancel()
 
-	bidResponse, err := h.ssp.processRequest(ctx, bidRequest)
+	bidResponse, err := h.ssp.processRequest(ctx, bidRequest, time.Duration(bidRequest.TMax)*time.Millisecond)
 	if err != nil || len(bidResponse.SeatBid) == 0 {
 		// Return empty VAST
 		c.Data(http.StatusOK, "application/xml", []byte(emptyVAST()))
@@ -163,7 +270,9 @@ func (h *PublicaHandler) HandleVAST(c *gin.Context) {
 	}
 
 	// Generate VAST from winning bid
-	vast := h.generateVAST(bidResponse.SeatBid[0].Bid[0], contentID)
+	bid := bidResponse.SeatBid[0].Bid[0]
+	servedID := h.cacheBid(bid, pubID, siteID)
+	vast := h.generateVAST(bid, servedID, contentID)
 	c.Data(http.StatusOK, "application/xml", []byte(vast))
 }
 
@@ -223,13 +332,21 @@ func (h *PublicaHandler) convertToOpenRTB(req *PublicaSSAIRequest) *openrtb2.Bid
 		}
 	}
 
+	// Brand safety for the content the ad runs alongside
+	if h.ContentFilter != nil {
+		h.ContentFilter.Apply(bidRequest, req.ContentRating, req.ContentGenre)
+	}
+	if h.ContentPolicy != nil {
+		h.ContentPolicy.Apply(bidRequest, req.ContentGenre)
+	}
+
 	return bidRequest
 }
 
 // convertToPublicaResponse converts OpenRTB response to Publica format
 func (h *PublicaHandler) convertToPublicaResponse(resp *openrtb2.BidResponse, req *PublicaSSAIRequest) *PublicaSSAIResponse {
-	baseURL := "https://ssp.ad.nexus" // This should come from config
-	cacheBuster := strconv.FormatInt(time.Now().Unix(), 10)
+	now := time.Now()
+	cacheBuster := strconv.FormatInt(now.Unix(), 10)
 
 	publicaResp := &PublicaSSAIResponse{
 		AdBreakID:   uuid.New().String(),
@@ -248,11 +365,13 @@ func (h *PublicaHandler) convertToPublicaResponse(resp *openrtb2.BidResponse, re
 	// Process winning bids
 	for _, seatBid := range resp.SeatBid {
 		for _, bid := range seatBid.Bid {
+			servedID := h.cacheBid(bid, req.PublisherID, req.SiteID)
+			macros := h.trackingMacros(bid, servedID, cacheBuster, now)
 			ad := Ad{
 				ID:         bid.ID,
 				Duration:   30, // Default duration, should be parsed from VAST
 				MediaURL:   bid.AdM,
-				ClickURL:   fmt.Sprintf("%s/publica/click?bid=%s&cb=%s", baseURL, bid.ID, cacheBuster),
+				ClickURL:   landingURL(bid),
 				Title:      "Video Ad",
 				Advertiser: seatBid.Seat,
 				CPM:        bid.Price,
@@ -262,9 +381,11 @@ func (h *PublicaHandler) convertToPublicaResponse(resp *openrtb2.BidResponse, re
 
 			// Add tracking URLs
 			publicaResp.TrackingURLs.Impression = append(publicaResp.TrackingURLs.Impression,
-				fmt.Sprintf("%s/publica/pixel/impression?bid=%s&cb=%s", baseURL, bid.ID, cacheBuster))
+				replaceMacros(h.currentBaseURL()+"/publica/pixel/impression?bid=[BIDID]&price=[PRICE]&ts=[TIMESTAMP]&sig=[SIG]&cb=[CACHEBUSTER]", macros))
+			publicaResp.TrackingURLs.Click = append(publicaResp.TrackingURLs.Click,
+				replaceMacros(h.currentBaseURL()+"/publica/click?bid=[BIDID]&cb=[CACHEBUSTER]", macros))
 			publicaResp.TrackingURLs.Complete = append(publicaResp.TrackingURLs.Complete,
-				fmt.Sprintf("%s/publica/pixel/complete?bid=%s&cb=%s", baseURL, bid.ID, cacheBuster))
+				replaceMacros(h.currentBaseURL()+"/publica/pixel/complete?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]", macros))
 		}
 	}
 
@@ -281,26 +402,50 @@ func (h *PublicaHandler) convertToPublicaResponse(resp *openrtb2.BidResponse, re
 	if req.DealID != "" {
 		params += "&deal=" + req.DealID
 	}
-	publicaResp.VASTURL = fmt.Sprintf("%s/publica/vast?%s", baseURL, params)
+	publicaResp.VASTURL = fmt.Sprintf("%s/publica/vast?%s", h.currentBaseURL(), params)
 
 	return publicaResp
 }
 
 // generateVAST generates a VAST response from a bid
-func (h *PublicaHandler) generateVAST(bid openrtb2.Bid, contentID string) string {
+func (h *PublicaHandler) generateVAST(bid openrtb2.Bid, servedID, contentID string) string {
 	// If the bid already contains VAST, return it
 	if strings.Contains(bid.AdM, "<VAST") {
 		return bid.AdM
 	}
 
+	now := time.Now()
+	macros := h.trackingMacros(bid, servedID, strconv.FormatInt(now.Unix(), 10), now)
+	tracking := func(path string) string {
+		return cdataEscape(replaceMacros(h.currentBaseURL()+path, macros))
+	}
+
+	// The impression pixel is the only tracker counting an impression; the
+	// playback trackers record video events
+	impressions := fmt.Sprintf(`
+      <Impression><![CDATA[%s]]></Impression>`,
+		tracking("/publica/pixel/impression?bid=[BIDID]&price=[PRICE]&ts=[TIMESTAMP]&sig=[SIG]&cb=[CACHEBUSTER]"))
+	if bid.NURL != "" {
+		impressions += fmt.Sprintf(`
+      <Impression><![CDATA[%s]]></Impression>`, cdataEscape(bid.NURL))
+	}
+
+	// Clicks go to the advertiser; the SSP only tracks them
+	videoClicks := ""
+	if landing := landingURL(bid); landing != "" {
+		videoClicks = fmt.Sprintf(`
+              <ClickThrough><![CDATA[%s]]></ClickThrough>`, cdataEscape(landing))
+	}
+	videoClicks += fmt.Sprintf(`
+              <ClickTracking><![CDATA[%s]]></ClickTracking>`, tracking("/publica/click?bid=[BIDID]&cb=[CACHEBUSTER]"))
+
 	// Otherwise generate VAST wrapper
 	vast := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <VAST version="3.0">
   <Ad id="%s">
     <InLine>
       <AdSystem>AdNexus SSP</AdSystem>
-      <AdTitle>Video Ad</AdTitle>
-      <Impression><![CDATA[%s]]></Impression>
+      <AdTitle>Video Ad</AdTitle>%s
       <Creatives>
         <Creative>
           <Linear>
@@ -310,22 +455,26 @@ func (h *PublicaHandler) generateVAST(bid openrtb2.Bid, contentID string) string
                 <![CDATA[%s]]>
               </MediaFile>
             </MediaFiles>
-            <VideoClicks>
-              <ClickThrough><![CDATA[https://ssp.ad.nexus/click?bid=%s]]></ClickThrough>
+            <VideoClicks>%s
             </VideoClicks>
             <TrackingEvents>
-              <Tracking event="start"><![CDATA[https://ssp.ad.nexus/pixel/start?bid=%s]]></Tracking>
-              <Tracking event="firstQuartile"><![CDATA[https://ssp.ad.nexus/pixel/q1?bid=%s]]></Tracking>
-              <Tracking event="midpoint"><![CDATA[https://ssp.ad.nexus/pixel/q2?bid=%s]]></Tracking>
-              <Tracking event="thirdQuartile"><![CDATA[https://ssp.ad.nexus/pixel/q3?bid=%s]]></Tracking>
-              <Tracking event="complete"><![CDATA[https://ssp.ad.nexus/pixel/complete?bid=%s]]></Tracking>
+              <Tracking event="start"><![CDATA[%s]]></Tracking>
+              <Tracking event="firstQuartile"><![CDATA[%s]]></Tracking>
+              <Tracking event="midpoint"><![CDATA[%s]]></Tracking>
+              <Tracking event="thirdQuartile"><![CDATA[%s]]></Tracking>
+              <Tracking event="complete"><![CDATA[%s]]></Tracking>
             </TrackingEvents>
           </Linear>
         </Creative>
       </Creatives>
     </InLine>
   </Ad>
-</VAST>`, bid.ID, bid.NURL, bid.AdM, bid.ID, bid.ID, bid.ID, bid.ID, bid.ID, bid.ID)
+</VAST>`, xmlAttrEscape(bid.ID), impressions, cdataEscape(bid.AdM), videoClicks,
+		tracking("/publica/pixel/start?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]"),
+		tracking("/publica/pixel/q1?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]"),
+		tracking("/publica/pixel/q2?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]"),
+		tracking("/publica/pixel/q3?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]"),
+		tracking("/publica/pixel/complete?bid=[BIDID]&ts=[TIMESTAMP]&cb=[CACHEBUSTER]"))
 
 	return vast
 }
@@ -0,0 +1,112 @@
+package ssp
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+func TestReplaceMacros(t *testing.T) {
+	got := replaceMacros("https://ssp.example.com/pixel?bid=[BIDID]&price=[PRICE]&err=[ERRORCODE]", map[string]string{
+		MacroBidID: "bid 1&x",
+		MacroPrice: "2.5",
+	})
+
+	expected := "https://ssp.example.com/pixel?bid=bid+1%26x&price=2.5&err=[ERRORCODE]"
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestPublicaTrackingURLsUseBaseURL(t *testing.T) {
+	h := NewPublicaHandler(nil, "https://ssp.example.com/")
+	bid := openrtb2.Bid{ID: "bid-1", Price: 3.25, AdM: "https://cdn.example.com/video.mp4"}
+
+	resp := h.convertToPublicaResponse(&openrtb2.BidResponse{
+		SeatBid: []openrtb2.SeatBid{{Seat: "dsp-1", Bid: []openrtb2.Bid{bid}}},
+	}, &PublicaSSAIRequest{PublisherID: "pub-1"})
+
+	urls := append([]string{resp.VASTURL}, resp.TrackingURLs.Impression...)
+	urls = append(urls, resp.TrackingURLs.Click...)
+	urls = append(urls, resp.TrackingURLs.Complete...)
+	for _, u := range urls {
+		if !strings.HasPrefix(u, "https://ssp.example.com/publica/") {
+			t.Errorf("Expected tracking URL on the configured base URL, got %s", u)
+		}
+		if strings.Contains(u, "[") {
+			t.Errorf("Expected all macros replaced, got %s", u)
+		}
+	}
+	if strings.Contains(resp.TrackingURLs.Impression[0], "bid=bid-1&") || !strings.Contains(resp.TrackingURLs.Impression[0], "&price=3.25&") {
+		t.Errorf("Expected served ID and price in impression URL, got %s", resp.TrackingURLs.Impression[0])
+	}
+
+	vast := h.generateVAST(bid, "bid-1", "content-1")
+	if strings.Contains(vast, "ssp.ad.nexus") || strings.Contains(vast, "[BIDID]") {
+		t.Errorf("Expected VAST tracking URLs on the configured base URL:\n%s", vast)
+	}
+	if !strings.Contains(vast, "https://ssp.example.com/publica/pixel/q1?bid=bid-1&") {
+		t.Errorf("Expected quartile tracking URL in VAST:\n%s", vast)
+	}
+}
+
+func TestPublicaVASTTrackers(t *testing.T) {
+	h := NewPublicaHandler(nil, "https://ssp.example.com")
+	bid := openrtb2.Bid{
+		ID:      "bid-1",
+		Price:   3.25,
+		AdM:     "https://cdn.example.com/video.mp4",
+		NURL:    "https://dsp.example.com/win",
+		ADomain: []string{"advertiser.example.com"},
+		Ext:     json.RawMessage(`{"clickthrough":"https://advertiser.example.com/landing?utm=ssp"}`),
+	}
+
+	vast := h.generateVAST(bid, "bid-1", "content-1")
+	if !strings.Contains(vast, "<ClickThrough><![CDATA[https://advertiser.example.com/landing?utm=ssp]]></ClickThrough>") {
+		t.Errorf("Expected click-through to the creative's landing page:\n%s", vast)
+	}
+	if !strings.Contains(vast, "<ClickTracking><![CDATA[https://ssp.example.com/publica/click?bid=bid-1&") {
+		t.Errorf("Expected the SSP click tracker in ClickTracking:\n%s", vast)
+	}
+	if strings.Count(vast, "/publica/pixel/impression?") != 1 || !strings.Contains(vast, "<Impression><![CDATA[https://dsp.example.com/win]]></Impression>") {
+		t.Errorf("Expected the SSP impression pixel and the bid's win notice as impressions:\n%s", vast)
+	}
+
+	bid.ID = `bid"><Evil>`
+	if vast := h.generateVAST(bid, "bid-1", "content-1"); !strings.Contains(vast, `<Ad id="bid&quot;&gt;&lt;Evil&gt;">`) {
+		t.Errorf("Expected the bid ID escaped in the Ad id attribute:\n%s", vast)
+	}
+
+	// An advertiser domain alone is not a landing page to send clicks to
+	bid.Ext = nil
+	if vast := h.generateVAST(bid, "bid-1", "content-1"); strings.Contains(vast, "<ClickThrough>") {
+		t.Errorf("Expected no click-through without a landing URL:\n%s", vast)
+	}
+}
+
+func TestPublicaServedBidsAreCached(t *testing.T) {
+	h := NewPublicaHandler(nil, "https://ssp.example.com")
+	h.BidCache = NewBidCache()
+
+	resp := h.convertToPublicaResponse(&openrtb2.BidResponse{
+		SeatBid: []openrtb2.SeatBid{{Seat: "dsp-1", Bid: []openrtb2.Bid{{ID: "bid-1", Price: 3.25, Exp: 60}}}},
+	}, &PublicaSSAIRequest{PublisherID: "pub-1", SiteID: "site-1"})
+
+	pixel, err := url.Parse(resp.TrackingURLs.Impression[0])
+	if err != nil {
+		t.Fatalf("Invalid impression URL: %v", err)
+	}
+	entry, ok := h.BidCache.Get(pixel.Query().Get("bid"))
+	if !ok {
+		t.Fatal("Expected served bid to be cached for impression verification")
+	}
+	if entry.Bid.Price != 3.25 || entry.Bid.Exp != 60 {
+		t.Errorf("Expected cached bid to keep price and exp, got %+v", entry.Bid)
+	}
+	if entry.PublisherID != "pub-1" || entry.SiteID != "site-1" || entry.ClearedPrice != 3.25 {
+		t.Errorf("Expected publisher, site and price logged with the impression, got %+v", entry)
+	}
+}
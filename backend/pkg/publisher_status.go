@@ -0,0 +1,92 @@
+package ssp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Publisher onboarding statuses. Only active publishers receive bids.
+const (
+	PublisherStatusPending   = "pending"
+	PublisherStatusActive    = "active"
+	PublisherStatusSuspended = "suspended"
+	PublisherStatusRejected  = "rejected"
+)
+
+// publisherStatusTransitions lists the statuses each status may move to
+var publisherStatusTransitions = map[string][]string{
+	PublisherStatusPending:   {PublisherStatusActive, PublisherStatusRejected},
+	PublisherStatusActive:    {PublisherStatusSuspended},
+	PublisherStatusSuspended: {PublisherStatusActive, PublisherStatusRejected},
+	PublisherStatusRejected:  {PublisherStatusActive},
+}
+
+// ValidPublisherStatusTransition reports whether a publisher may move from one status to another
+func ValidPublisherStatusTransition(from, to string) bool {
+	for _, status := range publisherStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PublisherStatusChange is the webhook payload sent when a publisher's status changes
+type PublisherStatusChange struct {
+	PublisherID    string    `json:"publisherId"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email"`
+	PreviousStatus string    `json:"previousStatus"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	ChangedAt      time.Time `json:"changedAt"`
+}
+
+// PublisherStatusNotifier posts publisher status changes to a webhook, which
+// is responsible for emailing the publisher
+type PublisherStatusNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewPublisherStatusNotifier creates a notifier. An empty URL disables notifications.
+func NewPublisherStatusNotifier(webhookURL string) *PublisherStatusNotifier {
+	return &PublisherStatusNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Notify sends a status change notification
+func (n *PublisherStatusNotifier) Notify(ctx context.Context, change *PublisherStatusChange) error {
+	if n.webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status change: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("status webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
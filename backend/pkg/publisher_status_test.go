@@ -0,0 +1,60 @@
+package ssp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidPublisherStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected bool
+	}{
+		{PublisherStatusPending, PublisherStatusActive, true},
+		{PublisherStatusPending, PublisherStatusRejected, true},
+		{PublisherStatusPending, PublisherStatusSuspended, false},
+		{PublisherStatusActive, PublisherStatusSuspended, true},
+		{PublisherStatusActive, PublisherStatusActive, false},
+		{PublisherStatusSuspended, PublisherStatusActive, true},
+		{PublisherStatusRejected, PublisherStatusSuspended, false},
+		{"unknown", PublisherStatusActive, false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidPublisherStatusTransition(tt.from, tt.to); got != tt.expected {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.expected, got)
+		}
+	}
+}
+
+func TestPublisherStatusNotifier(t *testing.T) {
+	var received PublisherStatusChange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	change := &PublisherStatusChange{
+		PublisherID:    "pub-1",
+		PreviousStatus: PublisherStatusPending,
+		Status:         PublisherStatusActive,
+		ChangedAt:      time.Now(),
+	}
+	if err := NewPublisherStatusNotifier(server.URL).Notify(context.Background(), change); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if received.PublisherID != "pub-1" || received.Status != PublisherStatusActive {
+		t.Errorf("unexpected webhook payload: %+v", received)
+	}
+
+	if err := NewPublisherStatusNotifier("").Notify(context.Background(), change); err != nil {
+		t.Errorf("expected disabled notifier to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package ssp
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefererDomain returns the normalized host of a Referer URL: lowercased,
+// without port or a leading "www.". It returns "" when the referer has no host.
+func RefererDomain(referer string) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil {
+		return ""
+	}
+	return normalizeDomain(u.Hostname())
+}
+
+// DomainMatches reports whether host is siteDomain or one of its subdomains
+func DomainMatches(siteDomain, host string) bool {
+	siteDomain = normalizeDomain(siteDomain)
+	host = normalizeDomain(host)
+	if siteDomain == "" || host == "" {
+		return false
+	}
+	return host == siteDomain || strings.HasSuffix(host, "."+siteDomain)
+}
+
+// normalizeDomain lowercases a domain and strips a leading "www." and trailing dot
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimSuffix(domain, ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// Site domain cache defaults
+const (
+	DefaultSiteDomainCacheTTL  = 5 * time.Minute
+	DefaultSiteDomainCacheSize = 100000
+)
+
+// SiteDomainLoader loads the active site registered for a domain
+type SiteDomainLoader func(ctx context.Context, domain string) (*Site, error)
+
+// SiteDomainCache caches the site registered for each referer domain, including
+// domains with no site, so referer checks keyed by a client-supplied header do
+// not query Postgres each time. It holds at most size domains.
+type SiteDomainCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	size    int
+	load    SiteDomainLoader
+	entries map[string]siteDomainEntry
+}
+
+type siteDomainEntry struct {
+	site      *Site // nil when no site is registered for the domain
+	expiresAt time.Time
+}
+
+// NewSiteDomainCache creates a site domain cache backed by load
+func NewSiteDomainCache(ttl time.Duration, size int, load SiteDomainLoader) *SiteDomainCache {
+	return &SiteDomainCache{
+		ttl:     ttl,
+		size:    size,
+		load:    load,
+		entries: make(map[string]siteDomainEntry),
+	}
+}
+
+// Get returns the site registered for domain, or nil when there is none or it
+// cannot be loaded. Lookup failures are cached like missing sites, as the
+// result only enriches fraud logs.
+func (c *SiteDomainCache) Get(ctx context.Context, domain string) *Site {
+	c.mu.RLock()
+	entry, ok := c.entries[domain]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.site
+	}
+
+	site, err := c.load(ctx, domain)
+	if err != nil {
+		site = nil
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= c.size {
+		c.evictExpired()
+	}
+	if len(c.entries) < c.size {
+		c.entries[domain] = siteDomainEntry{
+			site:      site,
+			expiresAt: time.Now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+
+	return site
+}
+
+// evictExpired drops expired entries. The caller must hold c.mu.
+func (c *SiteDomainCache) evictExpired() {
+	now := time.Now()
+	for domain, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, domain)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package ssp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefererDomain(t *testing.T) {
+	tests := map[string]string{
+		"https://www.Example.com/article?id=1": "example.com",
+		"http://news.example.com:8080/":        "news.example.com",
+		"":                                     "",
+		"not a url":                            "",
+	}
+
+	for referer, want := range tests {
+		if got := RefererDomain(referer); got != want {
+			t.Errorf("RefererDomain(%q) = %q, want %q", referer, got, want)
+		}
+	}
+}
+
+func TestDomainMatches(t *testing.T) {
+	tests := []struct {
+		siteDomain string
+		host       string
+		want       bool
+	}{
+		{"example.com", "example.com", true},
+		{"www.example.com", "example.com", true},
+		{"example.com", "news.example.com", true},
+		{"Example.com", "EXAMPLE.COM", true},
+		{"example.com", "badexample.com", false},
+		{"example.com", "example.com.evil.net", false},
+		{"", "example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := DomainMatches(tt.siteDomain, tt.host); got != tt.want {
+			t.Errorf("DomainMatches(%q, %q) = %v, want %v", tt.siteDomain, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSiteDomainCache(t *testing.T) {
+	loads := 0
+	cache := NewSiteDomainCache(time.Minute, 2, func(ctx context.Context, domain string) (*Site, error) {
+		loads++
+		if domain == "publisher.example" {
+			return &Site{ID: "site-1", Domain: domain}, nil
+		}
+		return nil, errors.New("site not found for domain: " + domain)
+	})
+
+	for i := 0; i < 2; i++ {
+		if site := cache.Get(context.Background(), "publisher.example"); site == nil || site.ID != "site-1" {
+			t.Fatalf("Unexpected site %+v", site)
+		}
+		if site := cache.Get(context.Background(), "unknown.example"); site != nil {
+			t.Fatalf("Expected no site for an unregistered domain, got %+v", site)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("Expected found and missing domains to be cached, got %d loads", loads)
+	}
+
+	// Full: a third domain is loaded but not cached
+	cache.Get(context.Background(), "other.example")
+	cache.Get(context.Background(), "other.example")
+	if loads != 4 || len(cache.entries) != 2 {
+		t.Errorf("Expected the cache to stay at its size, got %d entries after %d loads", len(cache.entries), loads)
+	}
+}
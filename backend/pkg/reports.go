@@ -0,0 +1,48 @@
+package ssp
+
+import "time"
+
+// DefaultPublisherRevShare is the publisher revenue share assumed when none is known
+const DefaultPublisherRevShare = 0.70
+
+// MarginReport summarizes SSP margin over a date range
+type MarginReport struct {
+	Start                time.Time `json:"start"`
+	End                  time.Time `json:"end"`
+	TotalGross           float64   `json:"totalGross"`           // Total cleared revenue
+	TotalPublisherPayout float64   `json:"totalPublisherPayout"` // Revenue owed to publishers
+	SSPMargin            float64   `json:"sspMargin"`            // Revenue retained by the SSP
+	SSPMarginPct         float64   `json:"sspMarginPct"`         // SSPMargin as a percentage of TotalGross
+}
+
+// ComputeMarginReport splits gross revenue per publisher into publisher payout and
+// SSP margin using each publisher's rev share. Publishers missing from the list
+// (e.g. deleted since the revenue was logged) fall back to DefaultPublisherRevShare.
+func ComputeMarginReport(revenueByPublisher map[string]float64, publishers []*Publisher, start, end time.Time) *MarginReport {
+	revShares := make(map[string]float64, len(publishers))
+	for _, pub := range publishers {
+		revShares[pub.ID] = pub.RevShare
+	}
+
+	report := &MarginReport{
+		Start: start,
+		End:   end,
+	}
+
+	for publisherID, gross := range revenueByPublisher {
+		revShare, ok := revShares[publisherID]
+		if !ok {
+			revShare = DefaultPublisherRevShare
+		}
+
+		report.TotalGross += gross
+		report.TotalPublisherPayout += gross * revShare
+	}
+
+	report.SSPMargin = report.TotalGross - report.TotalPublisherPayout
+	if report.TotalGross > 0 {
+		report.SSPMarginPct = report.SSPMargin / report.TotalGross * 100
+	}
+
+	return report
+}
@@ -0,0 +1,52 @@
+package ssp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeMarginReport(t *testing.T) {
+	publishers := []*Publisher{
+		{ID: "pub-1", RevShare: 0.70},
+		{ID: "pub-2", RevShare: 0.80},
+	}
+
+	revenue := map[string]float64{
+		"pub-1":   100.0,
+		"pub-2":   50.0,
+		"deleted": 10.0, // Unknown publisher uses the default rev share
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -7)
+
+	report := ComputeMarginReport(revenue, publishers, start, end)
+
+	if report.TotalGross != 160.0 {
+		t.Errorf("Expected total gross 160.0, got %f", report.TotalGross)
+	}
+
+	expectedPayout := 100.0*0.70 + 50.0*0.80 + 10.0*DefaultPublisherRevShare
+	if math.Abs(report.TotalPublisherPayout-expectedPayout) > 1e-9 {
+		t.Errorf("Expected publisher payout %f, got %f", expectedPayout, report.TotalPublisherPayout)
+	}
+
+	expectedMargin := 160.0 - expectedPayout
+	if math.Abs(report.SSPMargin-expectedMargin) > 1e-9 {
+		t.Errorf("Expected SSP margin %f, got %f", expectedMargin, report.SSPMargin)
+	}
+
+	expectedPct := expectedMargin / 160.0 * 100
+	if math.Abs(report.SSPMarginPct-expectedPct) > 1e-9 {
+		t.Errorf("Expected SSP margin pct %f, got %f", expectedPct, report.SSPMarginPct)
+	}
+}
+
+func TestComputeMarginReportNoRevenue(t *testing.T) {
+	report := ComputeMarginReport(map[string]float64{}, nil, time.Now(), time.Now())
+
+	if report.TotalGross != 0 || report.SSPMargin != 0 || report.SSPMarginPct != 0 {
+		t.Errorf("Expected empty report, got %+v", report)
+	}
+}
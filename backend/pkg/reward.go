@@ -0,0 +1,48 @@
+package ssp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RewardCallback is the payload posted to a placement's RewardCallbackURL
+// when a rewarded video has been watched to completion
+type RewardCallback struct {
+	PlacementID string    `json:"placementId"`
+	BidID       string    `json:"bidId"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// SendRewardCallback notifies the publisher's server that the user earned a reward
+func SendRewardCallback(ctx context.Context, client *http.Client, placement *Placement, bidID string) error {
+	body, err := json.Marshal(&RewardCallback{
+		PlacementID: placement.ID,
+		BidID:       bidID,
+		CompletedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal reward callback: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, placement.RewardCallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create reward callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reward callback failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("reward callback returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package ssp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimeSlot is a weekly window during which a placement serves ads.
+// Hours are in the slot's timezone; EndHour is exclusive and may be 24.
+// A slot with StartHour > EndHour runs overnight into the next day.
+type TimeSlot struct {
+	DayOfWeek int    `json:"dayOfWeek"` // 0=Sunday ... 6=Saturday
+	StartHour int    `json:"startHour"` // 0-23
+	EndHour   int    `json:"endHour"`   // 1-24
+	Timezone  string `json:"timezone"`  // IANA name, e.g. "America/New_York"; empty means UTC
+
+	loc *time.Location // Timezone, loaded when the slot is decoded
+}
+
+// UnmarshalJSON decodes a slot and loads its timezone once, so evaluating the
+// schedule on every ad request does not
+func (s *TimeSlot) UnmarshalJSON(data []byte) error {
+	type slotAlias TimeSlot
+	if err := json.Unmarshal(data, (*slotAlias)(s)); err != nil {
+		return err
+	}
+
+	// An unknown timezone is reported by ValidateSchedule
+	s.loc = nil
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		s.loc = loc
+	}
+	return nil
+}
+
+// location returns the slot's timezone, loading it for slots not decoded from JSON
+func (s *TimeSlot) location() (*time.Location, error) {
+	if s.loc != nil {
+		return s.loc, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// ValidateSchedule checks time slots for valid days, hours and timezones
+func ValidateSchedule(schedule []TimeSlot) error {
+	for i, slot := range schedule {
+		if slot.DayOfWeek < 0 || slot.DayOfWeek > 6 {
+			return fmt.Errorf("slot %d: dayOfWeek must be between 0 and 6", i)
+		}
+		if slot.StartHour < 0 || slot.StartHour > 23 {
+			return fmt.Errorf("slot %d: startHour must be between 0 and 23", i)
+		}
+		if slot.EndHour < 1 || slot.EndHour > 24 || slot.EndHour == slot.StartHour {
+			return fmt.Errorf("slot %d: endHour must be between 1 and 24 and differ from startHour", i)
+		}
+		if _, err := time.LoadLocation(slot.Timezone); err != nil {
+			return fmt.Errorf("slot %d: invalid timezone %q", i, slot.Timezone)
+		}
+	}
+	return nil
+}
+
+// EvaluateSchedule reports whether now falls within any slot of the schedule.
+// Each slot is evaluated in its own timezone, so slots follow local wall-clock
+// time across DST transitions. Slots with an unknown timezone never match.
+func EvaluateSchedule(schedule []TimeSlot, now time.Time) bool {
+	for _, slot := range schedule {
+		loc, err := slot.location()
+		if err != nil {
+			continue
+		}
+
+		local := now.In(loc)
+		day := int(local.Weekday())
+		hour := local.Hour()
+
+		if slot.StartHour < slot.EndHour {
+			if day == slot.DayOfWeek && hour >= slot.StartHour && hour < slot.EndHour {
+				return true
+			}
+			continue
+		}
+
+		// Overnight slot: from StartHour on DayOfWeek until EndHour the next day
+		if day == slot.DayOfWeek && hour >= slot.StartHour {
+			return true
+		}
+		if day == (slot.DayOfWeek+1)%7 && hour < slot.EndHour {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package ssp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestEvaluateSchedule(t *testing.T) {
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("bad time %s: %v", s, err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name     string
+		schedule []TimeSlot
+		now      time.Time
+		expected bool
+	}{
+		{"inside UTC slot", []TimeSlot{{DayOfWeek: 1, StartHour: 9, EndHour: 17}}, utc("2026-03-02T12:00:00Z"), true},
+		{"end hour exclusive", []TimeSlot{{DayOfWeek: 1, StartHour: 9, EndHour: 17}}, utc("2026-03-02T17:00:00Z"), false},
+		{"wrong day", []TimeSlot{{DayOfWeek: 2, StartHour: 9, EndHour: 17}}, utc("2026-03-02T12:00:00Z"), false},
+		{"empty schedule", nil, utc("2026-03-02T12:00:00Z"), false},
+		{"local timezone", []TimeSlot{{DayOfWeek: 1, StartHour: 9, EndHour: 17, Timezone: "America/New_York"}}, utc("2026-03-02T14:30:00Z"), true},
+		{"local timezone before open", []TimeSlot{{DayOfWeek: 1, StartHour: 9, EndHour: 17, Timezone: "America/New_York"}}, utc("2026-03-02T13:30:00Z"), false},
+		{"overnight slot next morning", []TimeSlot{{DayOfWeek: 5, StartHour: 22, EndHour: 2}}, utc("2026-03-07T01:00:00Z"), true},
+		{"overnight slot after end", []TimeSlot{{DayOfWeek: 5, StartHour: 22, EndHour: 2}}, utc("2026-03-07T02:00:00Z"), false},
+		{"unknown timezone", []TimeSlot{{DayOfWeek: 1, StartHour: 0, EndHour: 24, Timezone: "Mars/Olympus"}}, utc("2026-03-02T12:00:00Z"), false},
+
+		// US DST starts Sunday 2026-03-08 02:00 EST -> 03:00 EDT
+		{"spring forward before", []TimeSlot{{DayOfWeek: 0, StartHour: 3, EndHour: 4, Timezone: "America/New_York"}}, utc("2026-03-01T07:30:00Z"), false},
+		{"spring forward after", []TimeSlot{{DayOfWeek: 0, StartHour: 3, EndHour: 4, Timezone: "America/New_York"}}, utc("2026-03-08T07:30:00Z"), true},
+		{"spring forward skipped hour", []TimeSlot{{DayOfWeek: 0, StartHour: 2, EndHour: 3, Timezone: "America/New_York"}}, utc("2026-03-08T07:00:00Z"), false},
+
+		// US DST ends Sunday 2026-11-01 02:00 EDT -> 01:00 EST, so 01:xx occurs twice
+		{"fall back first 1am", []TimeSlot{{DayOfWeek: 0, StartHour: 1, EndHour: 2, Timezone: "America/New_York"}}, utc("2026-11-01T05:30:00Z"), true},
+		{"fall back second 1am", []TimeSlot{{DayOfWeek: 0, StartHour: 1, EndHour: 2, Timezone: "America/New_York"}}, utc("2026-11-01T06:30:00Z"), true},
+		{"fall back 2am", []TimeSlot{{DayOfWeek: 0, StartHour: 1, EndHour: 2, Timezone: "America/New_York"}}, utc("2026-11-01T07:30:00Z"), false},
+	}
+
+	for _, tt := range tests {
+		if got := EvaluateSchedule(tt.schedule, tt.now); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	valid := []TimeSlot{{DayOfWeek: 6, StartHour: 0, EndHour: 24, Timezone: "Europe/Berlin"}}
+	if err := ValidateSchedule(valid); err != nil {
+		t.Errorf("expected valid schedule, got %v", err)
+	}
+
+	invalid := [][]TimeSlot{
+		{{DayOfWeek: 7, StartHour: 0, EndHour: 1}},
+		{{DayOfWeek: 0, StartHour: 24, EndHour: 1}},
+		{{DayOfWeek: 0, StartHour: 5, EndHour: 5}},
+		{{DayOfWeek: 0, StartHour: 0, EndHour: 1, Timezone: "Mars/Olympus"}},
+	}
+	for i, schedule := range invalid {
+		if err := ValidateSchedule(schedule); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestTimeSlotLoadsTimezoneOnDecode(t *testing.T) {
+	var schedule []TimeSlot
+	if err := json.Unmarshal([]byte(`[{"dayOfWeek":1,"startHour":9,"endHour":17,"timezone":"America/New_York"}]`), &schedule); err != nil {
+		t.Fatalf("Failed to decode schedule: %v", err)
+	}
+	if schedule[0].loc == nil || schedule[0].loc.String() != "America/New_York" {
+		t.Fatalf("Expected the timezone to be loaded when decoded, got %v", schedule[0].loc)
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2026-03-02T14:30:00Z") // 09:30 in New York
+	if !EvaluateSchedule(schedule, now) {
+		t.Error("Expected decoded slot to be evaluated in its timezone")
+	}
+}
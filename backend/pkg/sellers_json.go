@@ -1,10 +1,17 @@
 package ssp
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"strings"
+	"sync"
 	"time"
 )
 
+// SellersJSONMaxAge is how long clients may cache sellers.json (Cache-Control max-age)
+const SellersJSONMaxAge = time.Hour
+
 // SellersJSON represents the IAB sellers.json specification
 // https://iabtechlab.com/sellers-json/
 type SellersJSON struct {
@@ -33,6 +40,7 @@ type Identifier struct {
 
 // SellersJSONGenerator generates sellers.json from publisher data
 type SellersJSONGenerator struct {
+	mu             sync.RWMutex // Guards ContactEmail, which SetContactEmail changes at runtime
 	ContactEmail   string
 	ContactAddress string
 	Version        string
@@ -57,6 +65,13 @@ func (g *SellersJSONGenerator) AddIdentifier(name, value string) {
 	})
 }
 
+// SetContactEmail changes the contact email of sellers.json generated from now on
+func (g *SellersJSONGenerator) SetContactEmail(contactEmail string) {
+	g.mu.Lock()
+	g.ContactEmail = contactEmail
+	g.mu.Unlock()
+}
+
 // GenerateFromPublishers generates sellers.json from a list of publishers
 func (g *SellersJSONGenerator) GenerateFromPublishers(publishers []Publisher) (*SellersJSON, error) {
 	sellers := make([]Seller, 0, len(publishers))
@@ -82,8 +97,12 @@ func (g *SellersJSONGenerator) GenerateFromPublishers(publishers []Publisher) (*
 		sellers = append(sellers, seller)
 	}
 
+	g.mu.RLock()
+	contactEmail := g.ContactEmail
+	g.mu.RUnlock()
+
 	sellersJSON := &SellersJSON{
-		ContactEmail:   g.ContactEmail,
+		ContactEmail:   contactEmail,
 		ContactAddress: g.ContactAddress,
 		Version:        g.Version,
 		Identifiers:    g.Identifiers,
@@ -104,9 +123,11 @@ func ValidateSellersJSON(data []byte) error {
 	return json.Unmarshal(data, &sellersJSON)
 }
 
-// SellersJSONCache provides caching for sellers.json
+// SellersJSONCache provides caching for sellers.json along with its ETag
 type SellersJSONCache struct {
+	mu        sync.RWMutex
 	data      []byte
+	etag      string
 	updatedAt time.Time
 	ttl       time.Duration
 }
@@ -118,27 +139,46 @@ func NewSellersJSONCache(ttl time.Duration) *SellersJSONCache {
 	}
 }
 
-// Set updates the cached sellers.json data
-func (c *SellersJSONCache) Set(data []byte) {
+// Set updates the cached sellers.json data and returns its ETag
+func (c *SellersJSONCache) Set(data []byte) string {
+	etag := contentETag(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.data = data
+	c.etag = etag
 	c.updatedAt = time.Now()
+	return etag
 }
 
 // Get returns cached sellers.json if not expired
 func (c *SellersJSONCache) Get() ([]byte, bool) {
+	data, _, ok := c.GetWithETag()
+	return data, ok
+}
+
+// GetWithETag returns cached sellers.json and its ETag if not expired
+func (c *SellersJSONCache) GetWithETag() ([]byte, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if c.data == nil {
-		return nil, false
+		return nil, "", false
 	}
 
 	if time.Since(c.updatedAt) > c.ttl {
-		return nil, false // Expired
+		return nil, "", false // Expired
 	}
 
-	return c.data, true
+	return c.data, c.etag, true
 }
 
 // IsExpired checks if cache is expired
 func (c *SellersJSONCache) IsExpired() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if c.data == nil {
 		return true
 	}
@@ -147,6 +187,29 @@ func (c *SellersJSONCache) IsExpired() bool {
 
 // Clear clears the cache
 func (c *SellersJSONCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.data = nil
+	c.etag = ""
 	c.updatedAt = time.Time{}
 }
+
+// contentETag returns a strong ETag: the quoted MD5 of the content
+func contentETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header matches etag. Weak
+// validators (W/) compare equal to their strong form, as RFC 9110 requires for
+// If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
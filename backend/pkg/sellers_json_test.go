@@ -2,6 +2,7 @@ package ssp
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -303,3 +304,48 @@ func TestIsPassthrough(t *testing.T) {
 		t.Errorf("Expected is_passthrough 0, got %d", publisher.IsPassthrough)
 	}
 }
+
+func TestSellersJSONCacheETag(t *testing.T) {
+	cache := NewSellersJSONCache(time.Second)
+
+	etag := cache.Set([]byte(`{"sellers":[]}`))
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || len(etag) != 34 {
+		t.Errorf("Expected a quoted MD5 ETag, got %s", etag)
+	}
+
+	_, cached, ok := cache.GetWithETag()
+	if !ok || cached != etag {
+		t.Errorf("Expected cached ETag %s, got %s", etag, cached)
+	}
+
+	if other := cache.Set([]byte(`{"sellers":[{}]}`)); other == etag {
+		t.Error("Expected ETag to change with content")
+	}
+
+	cache.Clear()
+	if _, cached, ok := cache.GetWithETag(); ok || cached != "" {
+		t.Error("Expected no ETag after Clear")
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc123"`
+
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"abc123"`, true},
+		{`W/"abc123"`, true},
+		{`"other", "abc123"`, true},
+		{`*`, true},
+		{`"other"`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		if got := ETagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("ETagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
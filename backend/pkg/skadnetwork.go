@@ -0,0 +1,45 @@
+package ssp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SKAdNetworkVersion is the SKAdNetwork version advertised to DSPs
+const SKAdNetworkVersion = "2.0"
+
+// iosBundlePattern matches App Store IDs, which OpenRTB uses as the bundle
+// for iOS apps (e.g. "1234567890" or "id1234567890"). Android bundles are
+// reverse-DNS package names.
+var iosBundlePattern = regexp.MustCompile(`^(id)?[0-9]+$`)
+
+// SKAdNetworkExt lists the SKAdNetwork IDs supported for attribution
+type SKAdNetworkExt struct {
+	Version    string   `json:"version"`
+	SKAdNetIDs []string `json:"skadnetids"`
+}
+
+// IsIOSBundle reports whether an app bundle identifies an iOS App Store app
+func IsIOSBundle(bundle string) bool {
+	return iosBundlePattern.MatchString(strings.ToLower(strings.TrimSpace(bundle)))
+}
+
+// ApplySKAdNetwork adds the supported SKAdNetwork IDs to iOS app bid requests
+// so DSPs can return attribution signatures. They go in app.ext.skadnetwork
+// alongside any app extensions the request already carries; an app.ext that
+// is not an object is left as is.
+func (b *BidRequestBuilder) ApplySKAdNetwork(bidReq *BidRequest) {
+	if len(b.SKAdNetworkIDs) == 0 || bidReq.App == nil || !IsIOSBundle(bidReq.App.Bundle) {
+		return
+	}
+
+	bidReq.App.SKAdNetworkIDs = append([]string{}, b.SKAdNetworkIDs...)
+	ext, err := mergeExt(bidReq.App.Ext, "skadnetwork", &SKAdNetworkExt{
+		Version:    SKAdNetworkVersion,
+		SKAdNetIDs: bidReq.App.SKAdNetworkIDs,
+	})
+	if err != nil {
+		return
+	}
+	bidReq.App.Ext = ext
+}
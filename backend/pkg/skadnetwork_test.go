@@ -0,0 +1,51 @@
+package ssp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIsIOSBundle(t *testing.T) {
+	tests := map[string]bool{
+		"1234567890":       true,
+		"id1234567890":     true,
+		"com.example.game": false,
+		"":                 false,
+	}
+
+	for bundle, expected := range tests {
+		if got := IsIOSBundle(bundle); got != expected {
+			t.Errorf("IsIOSBundle(%q) = %v, want %v", bundle, got, expected)
+		}
+	}
+}
+
+func TestApplySKAdNetwork(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+	builder.SKAdNetworkIDs = []string{"cstr6suwn9.skadnetwork", "4fzdc2evr5.skadnetwork"}
+
+	bidReq := &BidRequest{App: &App{Bundle: "id1234567890"}}
+	builder.ApplySKAdNetwork(bidReq)
+
+	data, err := json.Marshal(bidReq.App)
+	if err != nil {
+		t.Fatalf("Failed to marshal app: %v", err)
+	}
+	if !strings.Contains(string(data), `"skadnetids":["cstr6suwn9.skadnetwork","4fzdc2evr5.skadnetwork"]`) {
+		t.Errorf("Expected skadnetwork IDs in app.ext, got %s", data)
+	}
+
+	withExt := &BidRequest{App: &App{Bundle: "id1234567890", Ext: map[string]interface{}{"storeurl": "https://apps.example.com/app"}}}
+	builder.ApplySKAdNetwork(withExt)
+	ext, _ := withExt.App.Ext.(map[string]interface{})
+	if ext["storeurl"] != "https://apps.example.com/app" || ext["skadnetwork"] == nil {
+		t.Errorf("Expected skadnetwork merged into the existing app.ext, got %v", withExt.App.Ext)
+	}
+
+	androidReq := &BidRequest{App: &App{Bundle: "com.example.game"}}
+	builder.ApplySKAdNetwork(androidReq)
+	if androidReq.App.Ext != nil {
+		t.Errorf("Expected no app.ext for Android app, got %v", androidReq.App.Ext)
+	}
+}
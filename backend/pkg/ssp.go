@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/prebid/openrtb/v20/openrtb2"
 )
@@ -17,7 +18,11 @@ type SSP struct {
 	partnerManager *PartnerManager
 	auctionEngine  *AuctionEngine
 	bidder         *Bidder
+	analyticsStore *AnalyticsStore // Optional; nil disables partner no-fill logging
 	logger         *slog.Logger
+
+	BidsCubeMetrics     *BidsCubeMetrics // Optional; nil disables BidsCube latency metrics
+	BidsCubeDSARequired bool             // Require EU DSA transparency info from BidsCube bids
 }
 
 // NewSSP creates a new SSP instance
@@ -25,37 +30,49 @@ func NewSSP(
 	partnerManager *PartnerManager,
 	auctionEngine *AuctionEngine,
 	bidder *Bidder,
+	analyticsStore *AnalyticsStore,
 	logger *slog.Logger,
 ) *SSP {
 	return &SSP{
 		partnerManager: partnerManager,
 		auctionEngine:  auctionEngine,
 		bidder:         bidder,
+		analyticsStore: analyticsStore,
 		logger:         logger,
 	}
 }
 
-// processRequest handles an OpenRTB bid request by sending it to partners and running an auction
-func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidRequest) (*openrtb2.BidResponse, error) {
-	// Get active partners
-	partners := s.partnerManager.GetActivePartners()
+// processRequest handles an OpenRTB bid request by sending it to partners and running an auction.
+// deadline bounds the whole request (normally the request tmax); responses
+// arriving after it are ignored. A zero deadline relies on ctx alone.
+func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidRequest, deadline time.Duration) (*openrtb2.BidResponse, error) {
+	// Get active partners in priority order
+	partners := s.partnerManager.GetPartnersOrdered()
 	if len(partners) == 0 {
 		return nil, fmt.Errorf("no active partners available")
 	}
 
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+
 	// Send bid requests to all partners in parallel
 	type partnerResult struct {
-		partner  *SupplyPartner
-		response *openrtb2.BidResponse
-		err      error
+		index        int // Position in partners
+		partner      *SupplyPartner
+		response     *openrtb2.BidResponse
+		err          error
+		noFillReason string
 	}
 
 	resultCh := make(chan partnerResult, len(partners))
 	var wg sync.WaitGroup
 
-	for _, partner := range partners {
+	for i, partner := range partners {
 		wg.Add(1)
-		go func(p *SupplyPartner) {
+		go func(index int, p *SupplyPartner) {
 			defer wg.Done()
 
 			// Set timeout for this partner
@@ -70,6 +87,11 @@ func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidReques
 			case "adnexus":
 				// Create BidsCube client and send request
 				adnexusPartner := NewBidsCubePartner(p.Endpoint, p.APIKey, p.RevShare)
+				if s.BidsCubeMetrics != nil {
+					adnexusPartner.WithMetrics(s.BidsCubeMetrics.Latency, s.BidsCubeMetrics.ResponseTime)
+				}
+				adnexusPartner.DSARequired = s.BidsCubeDSARequired
+				adnexusPartner.Logger = s.logger
 				response, err = adnexusPartner.SendBidRequest(partnerCtx, bidRequest)
 			case "dsp":
 				// Direct OpenRTB request to DSP
@@ -79,12 +101,15 @@ func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidReques
 				response, err = s.sendOpenRTBRequest(partnerCtx, p, bidRequest)
 			}
 
+			hasBids := response != nil && len(response.SeatBid) > 0
 			resultCh <- partnerResult{
-				partner:  p,
-				response: response,
-				err:      err,
+				index:        index,
+				partner:      p,
+				response:     response,
+				err:          err,
+				noFillReason: PartnerNoFillReason(err, hasBids),
 			}
-		}(partner)
+		}(i, partner)
 	}
 
 	// Wait for all partners to respond or timeout
@@ -93,11 +118,43 @@ func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidReques
 		close(resultCh)
 	}()
 
-	// Collect responses
+	// Collect responses until all partners finish or the global deadline passes
+	results := make([]*partnerResult, len(partners))
+
+collect:
+	for {
+		select {
+		case result, ok := <-resultCh:
+			if !ok {
+				break collect
+			}
+			results[result.index] = &result
+		case <-ctx.Done():
+			s.logger.Debug("Global deadline reached before all partners responded", "request_id", bidRequest.ID)
+			break collect
+		}
+	}
+
+	// Handle results in partner priority order rather than arrival order, so
+	// logs and analytics don't depend on goroutine scheduling
 	var bidResponses []*openrtb2.BidResponse
-	partnerResponses := make(map[string]*openrtb2.BidResponse)
+	var noFills []*PartnerNoFillLog
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+
+		if result.noFillReason != "" {
+			noFills = append(noFills, &PartnerNoFillLog{
+				RequestID:   bidRequest.ID,
+				PartnerID:   result.partner.ID,
+				PartnerName: result.partner.Name,
+				Reason:      result.noFillReason,
+				Timestamp:   time.Now(),
+			})
+		}
 
-	for result := range resultCh {
 		if result.err != nil {
 			s.logger.Warn("Partner bid request failed",
 				"partner", result.partner.Name,
@@ -107,9 +164,9 @@ func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidReques
 
 		if result.response != nil && len(result.response.SeatBid) > 0 {
 			bidResponses = append(bidResponses, result.response)
-			partnerResponses[result.partner.ID] = result.response
 		}
 	}
+	s.logPartnerNoFills(noFills)
 
 	// If no valid responses, return nil
 	if len(bidResponses) == 0 {
@@ -125,6 +182,23 @@ func (s *SSP) processRequest(ctx context.Context, bidRequest *openrtb2.BidReques
 	return winningResponse, nil
 }
 
+// logPartnerNoFills records partner no-fills asynchronously, in the given order
+func (s *SSP) logPartnerNoFills(logs []*PartnerNoFillLog) {
+	if s.analyticsStore == nil || len(logs) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, log := range logs {
+			if err := s.analyticsStore.LogPartnerNoFill(ctx, log); err != nil {
+				s.logger.Error("Failed to log partner no-fill", "error", err)
+			}
+		}
+	}()
+}
+
 // sendOpenRTBRequest sends an OpenRTB request to a partner endpoint
 func (s *SSP) sendOpenRTBRequest(ctx context.Context, partner *SupplyPartner, bidRequest *openrtb2.BidRequest) (*openrtb2.BidResponse, error) {
 	// Marshal bid request
@@ -142,10 +216,8 @@ func (s *SSP) sendOpenRTBRequest(ctx context.Context, partner *SupplyPartner, bi
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-OpenRTB-Version", "2.5")
 
-	// Add API key if provided
-	if partner.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+partner.APIKey)
-	}
+	// Authenticate with the partner's configured method
+	applyPartnerAuth(req, partner, requestBody)
 
 	// Send request
 	client := &http.Client{
@@ -170,7 +242,7 @@ func (s *SSP) sendOpenRTBRequest(ctx context.Context, partner *SupplyPartner, bi
 	// Parse response
 	var bidResponse openrtb2.BidResponse
 	if err := json.NewDecoder(resp.Body).Decode(&bidResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBidResponse, err)
 	}
 
 	return &bidResponse, nil
@@ -0,0 +1,42 @@
+package ssp
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+)
+
+// SSP config keys stored in the ssp_config table. Stored values take
+// precedence over the matching environment variables at startup.
+const (
+	ConfigSSPID        = "ssp_id"        // Overrides SSP_ID
+	ConfigSSPEndpoint  = "ssp_endpoint"  // Overrides SSP_ENDPOINT
+	ConfigCDNURL       = "cdn_url"       // Overrides CDN_URL
+	ConfigContactEmail = "contact_email" // Overrides SELLERS_JSON_CONTACT_EMAIL
+)
+
+// ValidateSSPConfig checks that key is a known config key and value is
+// usable for it
+func ValidateSSPConfig(key, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s must not be empty", key)
+	}
+
+	switch key {
+	case ConfigSSPID:
+		return nil
+	case ConfigSSPEndpoint, ConfigCDNURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute http(s) URL", key)
+		}
+		return nil
+	case ConfigContactEmail:
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%s must be an email address", key)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown config key: %s", key)
+}
@@ -0,0 +1,30 @@
+package ssp
+
+import "testing"
+
+func TestValidateSSPConfig(t *testing.T) {
+	valid := map[string]string{
+		ConfigSSPID:        "network-b-ssp",
+		ConfigSSPEndpoint:  "https://ssp.network-b.example",
+		ConfigCDNURL:       "https://cdn.network-b.example",
+		ConfigContactEmail: "ops@network-b.example",
+	}
+	for key, value := range valid {
+		if err := ValidateSSPConfig(key, value); err != nil {
+			t.Errorf("%s=%q: unexpected error %v", key, value, err)
+		}
+	}
+
+	invalid := [][2]string{
+		{ConfigSSPID, ""},
+		{ConfigSSPEndpoint, "ssp.network-b.example"},
+		{ConfigCDNURL, "ftp://cdn.network-b.example"},
+		{ConfigContactEmail, "not-an-email"},
+		{"admin_api_key", "secret"},
+	}
+	for _, kv := range invalid {
+		if err := ValidateSSPConfig(kv[0], kv[1]); err == nil {
+			t.Errorf("%s=%q: expected error", kv[0], kv[1])
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ssp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prebid/openrtb/v20/openrtb2"
+)
+
+func TestProcessRequestGlobalDeadline(t *testing.T) {
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(openrtb2.BidResponse{
+			ID:      "resp-fast",
+			SeatBid: []openrtb2.SeatBid{{Bid: []openrtb2.Bid{{ID: "bid-fast", ImpID: "1", Price: 1.00}}}},
+		})
+	}))
+	defer fast.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+
+	pm := NewPartnerManager()
+	pm.AddPartner(&SupplyPartner{ID: "fast", Name: "Fast", Type: "dsp", Endpoint: fast.URL, Timeout: 2 * time.Second, Active: true})
+	pm.AddPartner(&SupplyPartner{ID: "slow", Name: "Slow", Type: "dsp", Endpoint: slow.URL, Timeout: 2 * time.Second, Active: true})
+
+	s := NewSSP(pm, NewAuctionEngine(0.01), nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Now()
+	resp, err := s.processRequest(context.Background(), &openrtb2.BidRequest{ID: "req-1", TMax: 100}, 100*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("Expected fast partner response, got error: %v", err)
+	}
+	if resp.ID != "resp-fast" {
+		t.Errorf("Expected resp-fast, got %s", resp.ID)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected processRequest to return at the global deadline, took %v", elapsed)
+	}
+}
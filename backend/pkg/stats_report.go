@@ -0,0 +1,111 @@
+package ssp
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// PublisherStatsReport is a publisher's stats over a date range broken down
+// by site and placement
+type PublisherStatsReport struct {
+	Publisher *SupplyStats       `json:"publisher"`
+	Sites     []*SiteStatsReport `json:"sites"`
+}
+
+// SiteStatsReport is one site's stats and the stats of each of its placements
+type SiteStatsReport struct {
+	Site       *SupplyStats   `json:"site"`
+	Placements []*SupplyStats `json:"placements"`
+}
+
+// StatsReportCSVHeader is the CSV header written by PublisherStatsReport.WriteCSV
+var StatsReportCSVHeader = []string{
+	"level", "publisher_id", "site_id", "placement_id",
+	"requests", "impressions", "fills", "revenue", "avg_cpm",
+}
+
+// NewPublisherStatsReport joins publisher, site and placement totals onto the
+// publisher's sites and placements. Sites and placements without traffic are
+// reported with zero stats; totals for ones no longer owned are dropped.
+func NewPublisherStatsReport(publisherID string, totals *SupplyStats, siteTotals, placementTotals []*SupplyStats, sites []*Site, placements []*Placement) *PublisherStatsReport {
+	if totals == nil {
+		totals = &SupplyStats{}
+	}
+	totals.PublisherID = publisherID
+
+	siteStats := make(map[string]*SupplyStats, len(siteTotals))
+	for _, stats := range siteTotals {
+		siteStats[stats.SiteID] = stats
+	}
+	placementStats := make(map[string]*SupplyStats, len(placementTotals))
+	for _, stats := range placementTotals {
+		placementStats[stats.PlacementID] = stats
+	}
+
+	report := &PublisherStatsReport{Publisher: totals, Sites: make([]*SiteStatsReport, 0, len(sites))}
+	bySite := make(map[string]*SiteStatsReport, len(sites))
+	for _, site := range sites {
+		stats, ok := siteStats[site.ID]
+		if !ok {
+			stats = &SupplyStats{PublisherID: publisherID, SiteID: site.ID}
+		}
+		sr := &SiteStatsReport{Site: stats, Placements: []*SupplyStats{}}
+		bySite[site.ID] = sr
+		report.Sites = append(report.Sites, sr)
+	}
+
+	for _, placement := range placements {
+		sr, ok := bySite[placement.SiteID]
+		if !ok {
+			continue
+		}
+		stats, ok := placementStats[placement.ID]
+		if !ok {
+			stats = &SupplyStats{PublisherID: publisherID, SiteID: placement.SiteID, PlacementID: placement.ID}
+		}
+		sr.Placements = append(sr.Placements, stats)
+	}
+
+	return report
+}
+
+// WriteCSV writes the report as CSV: the publisher row, then each site row
+// followed by its placement rows
+func (r *PublisherStatsReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(StatsReportCSVHeader); err != nil {
+		return err
+	}
+
+	write := func(level string, stats *SupplyStats) error {
+		return cw.Write([]string{
+			level,
+			stats.PublisherID,
+			stats.SiteID,
+			stats.PlacementID,
+			strconv.FormatInt(stats.Requests, 10),
+			strconv.FormatInt(stats.Impressions, 10),
+			strconv.FormatInt(stats.Fills, 10),
+			formatFloat(stats.Revenue),
+			formatFloat(stats.AvgCPM),
+		})
+	}
+
+	if err := write(statsLevelPublisher, r.Publisher); err != nil {
+		return err
+	}
+	for _, site := range r.Sites {
+		if err := write(statsLevelSite, site.Site); err != nil {
+			return err
+		}
+		for _, placement := range site.Placements {
+			if err := write(statsLevelPlacement, placement); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,72 @@
+package ssp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewPublisherStatsReport(t *testing.T) {
+	sites := []*Site{{ID: "site-1"}, {ID: "site-2"}}
+	placements := []*Placement{
+		{ID: "placement-1", SiteID: "site-1"},
+		{ID: "placement-2", SiteID: "site-1"},
+		{ID: "placement-3", SiteID: "site-2"},
+	}
+	totals := &SupplyStats{PublisherID: "pub-1", Requests: 100, Impressions: 40, Revenue: 80}
+	siteTotals := []*SupplyStats{
+		{PublisherID: "pub-1", SiteID: "site-1", Requests: 100, Impressions: 40, Revenue: 80},
+		{PublisherID: "pub-1", SiteID: "site-deleted", Requests: 5},
+	}
+	placementTotals := []*SupplyStats{
+		{PublisherID: "pub-1", SiteID: "site-1", PlacementID: "placement-2", Requests: 100, Impressions: 40, Revenue: 80},
+	}
+
+	report := NewPublisherStatsReport("pub-1", totals, siteTotals, placementTotals, sites, placements)
+
+	if report.Publisher.Requests != 100 {
+		t.Errorf("Expected publisher totals, got %+v", report.Publisher)
+	}
+	if len(report.Sites) != 2 {
+		t.Fatalf("Expected 2 owned sites, got %d", len(report.Sites))
+	}
+
+	site1 := report.Sites[0]
+	if site1.Site.SiteID != "site-1" || site1.Site.Revenue != 80 {
+		t.Errorf("Expected site-1 stats, got %+v", site1.Site)
+	}
+	if len(site1.Placements) != 2 || site1.Placements[0].Requests != 0 || site1.Placements[1].Requests != 100 {
+		t.Errorf("Expected placement-1 with zero stats and placement-2 with traffic, got %+v %+v", site1.Placements[0], site1.Placements[1])
+	}
+
+	site2 := report.Sites[1]
+	if site2.Site.SiteID != "site-2" || site2.Site.Requests != 0 {
+		t.Errorf("Expected site-2 with zero stats, got %+v", site2.Site)
+	}
+	if len(site2.Placements) != 1 || site2.Placements[0].PlacementID != "placement-3" {
+		t.Errorf("Expected placement-3 on site-2, got %+v", site2.Placements)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"level,publisher_id,site_id,placement_id,requests,impressions,fills,revenue,avg_cpm",
+		"publisher,pub-1,,,100,40,0,80,0",
+		"site,pub-1,site-1,,100,40,0,80,0",
+		"placement,pub-1,site-1,placement-1,0,0,0,0,0",
+		"placement,pub-1,site-1,placement-2,100,40,0,80,0",
+		"site,pub-1,site-2,,0,0,0,0,0",
+		"placement,pub-1,site-2,placement-3,0,0,0,0,0",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d CSV lines, got %d:\n%s", len(expected), len(lines), buf.String())
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
+// ErrCircularSupplyChain is returned when the same ASI+SID node appears more than once in a chain
+var ErrCircularSupplyChain = errors.New("circular supply chain: duplicate ASI+SID node")
+
 // SupplyChain represents the OpenRTB SupplyChain object (ads.cert 1.0)
 // https://github.com/InteractiveAdvertisingBureau/openrtb/blob/master/supplychainobject.md
 type SupplyChain struct {
@@ -115,6 +119,21 @@ func (b *SupplyChainBuilder) BuildIncomplete(publisherID string) (*SupplyChain,
 	return schain, nil
 }
 
+// ToPChainString serializes a supply chain as a payment chain string for
+// Source.PChain: one "<asi>:<sid>:<hp>" entry per node, comma separated
+func (b *SupplyChainBuilder) ToPChainString(schain *SupplyChain) string {
+	if schain == nil {
+		return ""
+	}
+
+	entries := make([]string, len(schain.Nodes))
+	for i, node := range schain.Nodes {
+		entries[i] = fmt.Sprintf("%s:%s:%d", node.ASI, node.SID, node.HP)
+	}
+
+	return strings.Join(entries, ",")
+}
+
 // ValidateSupplyChain validates a supply chain object
 func ValidateSupplyChain(schain *SupplyChain) error {
 	if schain == nil {
@@ -129,11 +148,19 @@ func ValidateSupplyChain(schain *SupplyChain) error {
 		return errors.New("supply chain version is required")
 	}
 
-	// Validate each node
+	// Validate each node and reject resellers appearing more than once
+	type nodeKey struct{ asi, sid string }
+	seen := make(map[nodeKey]bool, len(schain.Nodes))
 	for i, node := range schain.Nodes {
 		if err := validateNode(&node); err != nil {
 			return fmt.Errorf("node %d: %w", i, err)
 		}
+
+		key := nodeKey{asi: node.ASI, sid: node.SID}
+		if seen[key] {
+			return fmt.Errorf("node %d (%s/%s): %w", i, node.ASI, node.SID, ErrCircularSupplyChain)
+		}
+		seen[key] = true
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package ssp
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -122,6 +123,29 @@ func TestBuildForIntermediary(t *testing.T) {
 	}
 }
 
+func TestToPChainString(t *testing.T) {
+	builder := NewSupplyChainBuilder("ad.nexus", "ssp-001", "AdNexus", "ad.nexus")
+
+	intermediaries := []SupplyChainNode{
+		{ASI: "intermediary1.com", SID: "int-001", HP: 1},
+		{ASI: "intermediary2.com", SID: "int-002", HP: 1},
+	}
+
+	schain, err := builder.BuildForIntermediary("pub-001", "publisher.com", intermediaries)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "intermediary1.com:int-001:1,intermediary2.com:int-002:1,ad.nexus:pub-001:0"
+	if got := builder.ToPChainString(schain); got != expected {
+		t.Errorf("Expected pchain %q, got %q", expected, got)
+	}
+
+	if got := builder.ToPChainString(nil); got != "" {
+		t.Errorf("Expected empty pchain for nil chain, got %q", got)
+	}
+}
+
 func TestBuildIncomplete(t *testing.T) {
 	builder := NewSupplyChainBuilder("ad.nexus", "ssp-001", "AdNexus", "ad.nexus")
 
@@ -233,6 +257,29 @@ func TestValidateSupplyChain(t *testing.T) {
 	}
 }
 
+func TestValidateSupplyChainCircular(t *testing.T) {
+	schain := &SupplyChain{
+		Complete: 1,
+		Ver:      "1.0",
+		Nodes: []SupplyChainNode{
+			{ASI: "ssp.com", SID: "pub-001", HP: 1},
+			{ASI: "reseller.com", SID: "res-001", HP: 1},
+			{ASI: "reseller.com", SID: "res-001", HP: 1}, // Injected twice
+		},
+	}
+
+	err := ValidateSupplyChain(schain)
+	if !errors.Is(err, ErrCircularSupplyChain) {
+		t.Fatalf("Expected ErrCircularSupplyChain, got %v", err)
+	}
+
+	// Same ASI with a different SID is a distinct seller
+	schain.Nodes[2].SID = "res-002"
+	if err := ValidateSupplyChain(schain); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
 func TestSupplyChainToJSON(t *testing.T) {
 	schain := &SupplyChain{
 		Complete: 1,
@@ -0,0 +1,174 @@
+package ssp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supply chain node trust scores, from the seller's sellers.json entry
+const (
+	TrustScorePublisher    = 1.0 // Listed as PUBLISHER (direct inventory)
+	TrustScoreIntermediary = 0.5 // Listed as INTERMEDIARY or BOTH
+	TrustScoreUnknown      = 0.0 // Not listed
+)
+
+// DefaultSellersJSONTrustTTL is how long a fetched sellers.json is trusted before refetching
+const DefaultSellersJSONTrustTTL = 6 * time.Hour
+
+// SellersJSONFailureTTL is how long a failed sellers.json fetch is remembered
+// before the ASI is fetched again
+const SellersJSONFailureTTL = 5 * time.Minute
+
+// sellersJSONMaxBytes caps the size of a fetched sellers.json
+const sellersJSONMaxBytes = 10 << 20
+
+// TrustRegistry scores how trustworthy a supply chain node is, from 0.0 to 1.0
+type TrustRegistry interface {
+	GetTrustScore(asi, sid string) (float64, error)
+}
+
+// SellersJSONTrustRegistry scores nodes by the seller type listed for the SID in
+// the ASI's sellers.json, fetched from https://<asi>/sellers.json and cached per
+// ASI. Only known ASIs are fetched, so request data never chooses the hosts
+// contacted and the cache holds at most one entry per known ASI.
+type SellersJSONTrustRegistry struct {
+	client    *http.Client
+	ttl       time.Duration
+	knownASIs map[string]bool
+
+	mu      sync.Mutex
+	sellers map[string]*cachedSellers
+}
+
+// cachedSellers maps seller IDs to seller types for one ASI, or records why
+// its sellers.json could not be fetched
+type cachedSellers struct {
+	types     map[string]string
+	err       error
+	fetchedAt time.Time
+}
+
+// NewSellersJSONTrustRegistry creates a registry that fetches the sellers.json
+// of the known ASIs with client and caches each one for ttl
+func NewSellersJSONTrustRegistry(client *http.Client, ttl time.Duration, knownASIs []string) *SellersJSONTrustRegistry {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if ttl <= 0 {
+		ttl = DefaultSellersJSONTrustTTL
+	}
+	known := make(map[string]bool, len(knownASIs))
+	for _, asi := range knownASIs {
+		if asi = strings.ToLower(strings.TrimSpace(asi)); asi != "" {
+			known[asi] = true
+		}
+	}
+	return &SellersJSONTrustRegistry{
+		client:    client,
+		ttl:       ttl,
+		knownASIs: known,
+		sellers:   make(map[string]*cachedSellers),
+	}
+}
+
+// GetTrustScore returns the node's score from the ASI's sellers.json. Nodes of
+// unknown ASIs score TrustScoreUnknown. A fetch failure is returned as an error
+// rather than scored, so the caller decides.
+func (r *SellersJSONTrustRegistry) GetTrustScore(asi, sid string) (float64, error) {
+	asi = strings.ToLower(asi)
+	if !r.knownASIs[asi] {
+		return TrustScoreUnknown, nil
+	}
+
+	types, err := r.sellerTypes(asi)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToUpper(types[sid]) {
+	case "PUBLISHER":
+		return TrustScorePublisher, nil
+	case "INTERMEDIARY", "BOTH":
+		return TrustScoreIntermediary, nil
+	default:
+		return TrustScoreUnknown, nil
+	}
+}
+
+// sellerTypes returns the cached seller types for an ASI, fetching them when
+// missing or stale. Failed fetches are cached for SellersJSONFailureTTL.
+func (r *SellersJSONTrustRegistry) sellerTypes(asi string) (map[string]string, error) {
+	r.mu.Lock()
+	cached, ok := r.sellers[asi]
+	r.mu.Unlock()
+	if ok {
+		ttl := r.ttl
+		if cached.err != nil {
+			ttl = SellersJSONFailureTTL
+		}
+		if time.Since(cached.fetchedAt) < ttl {
+			return cached.types, cached.err
+		}
+	}
+
+	types, err := r.fetch(asi)
+
+	r.mu.Lock()
+	r.sellers[asi] = &cachedSellers{types: types, err: err, fetchedAt: time.Now()}
+	r.mu.Unlock()
+
+	return types, err
+}
+
+// fetch downloads and parses an ASI's sellers.json
+func (r *SellersJSONTrustRegistry) fetch(asi string) (map[string]string, error) {
+	resp, err := r.client.Get("https://" + asi + "/sellers.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sellers.json for %s: %w", asi, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sellers.json for %s returned status %d", asi, resp.StatusCode)
+	}
+
+	var doc SellersJSON
+	if err := json.NewDecoder(io.LimitReader(resp.Body, sellersJSONMaxBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid sellers.json for %s: %w", asi, err)
+	}
+
+	types := make(map[string]string, len(doc.Sellers))
+	for _, seller := range doc.Sellers {
+		types[seller.SellerID] = seller.SellerType
+	}
+	return types, nil
+}
+
+// ComputeChainTrustScore returns the product of the trust scores of every node
+// in the chain, so one unknown node makes the whole chain untrusted. Nodes for
+// our own ASI are trusted without a lookup.
+func (b *SupplyChainBuilder) ComputeChainTrustScore(schain *SupplyChain, registry TrustRegistry) (float64, error) {
+	if err := ValidateSupplyChain(schain); err != nil {
+		return 0, err
+	}
+
+	score := 1.0
+	for i, node := range schain.Nodes {
+		if strings.EqualFold(node.ASI, b.ourASI) {
+			continue
+		}
+
+		nodeScore, err := registry.GetTrustScore(node.ASI, node.SID)
+		if err != nil {
+			return 0, fmt.Errorf("node %d (%s/%s): %w", i, node.ASI, node.SID, err)
+		}
+		score *= nodeScore
+	}
+
+	return score, nil
+}
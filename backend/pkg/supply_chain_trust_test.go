@@ -0,0 +1,108 @@
+package ssp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSellersJSONTrustRegistry(t *testing.T) {
+	fetches := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.URL.Path != "/sellers.json" {
+			t.Errorf("Expected /sellers.json, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"version":"1.0","sellers":[
+			{"seller_id":"pub-1","seller_type":"PUBLISHER"},
+			{"seller_id":"reseller-1","seller_type":"INTERMEDIARY"}
+		]}`))
+	}))
+	defer server.Close()
+
+	asi := strings.TrimPrefix(server.URL, "https://")
+	registry := NewSellersJSONTrustRegistry(server.Client(), time.Minute, []string{asi})
+
+	expected := map[string]float64{"pub-1": 1.0, "reseller-1": 0.5, "unknown": 0.0}
+	for sid, want := range expected {
+		score, err := registry.GetTrustScore(asi, sid)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", sid, err)
+		}
+		if score != want {
+			t.Errorf("Expected score %.1f for %s, got %.1f", want, sid, score)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected sellers.json to be fetched once while cached, got %d", fetches)
+	}
+}
+
+func TestSellersJSONTrustRegistryKnownASIs(t *testing.T) {
+	fetches := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	asi := strings.TrimPrefix(server.URL, "https://")
+	registry := NewSellersJSONTrustRegistry(server.Client(), time.Minute, []string{asi})
+
+	// ASIs outside the known set are never fetched
+	score, err := registry.GetTrustScore("127.0.0.1:1", "pub-1")
+	if err != nil || score != TrustScoreUnknown {
+		t.Errorf("Expected unknown ASI to score %.1f without error, got %.1f, %v", TrustScoreUnknown, score, err)
+	}
+	if len(registry.sellers) != 0 {
+		t.Errorf("Expected no cache entry for an unknown ASI, got %d", len(registry.sellers))
+	}
+
+	// A failed fetch is remembered rather than retried on every request
+	for i := 0; i < 3; i++ {
+		if _, err := registry.GetTrustScore(asi, "pub-1"); err == nil {
+			t.Error("Expected fetch failure to be returned")
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected the failed fetch to be cached, got %d fetches", fetches)
+	}
+}
+
+// staticTrustRegistry scores nodes from a map keyed by "asi/sid"
+type staticTrustRegistry map[string]float64
+
+func (r staticTrustRegistry) GetTrustScore(asi, sid string) (float64, error) {
+	score, ok := r[asi+"/"+sid]
+	if !ok {
+		return 0, errors.New("sellers.json unavailable")
+	}
+	return score, nil
+}
+
+func TestComputeChainTrustScore(t *testing.T) {
+	builder := NewSupplyChainBuilder("ad.nexus", "ssp-1", "AdNexus", "ad.nexus")
+	registry := staticTrustRegistry{"exchange.com/pub-1": 1.0, "reseller.com/r-1": 0.5}
+
+	schain := &SupplyChain{Complete: 1, Ver: "1.0", Nodes: []SupplyChainNode{
+		{ASI: "exchange.com", SID: "pub-1", HP: 1},
+		{ASI: "reseller.com", SID: "r-1", HP: 1},
+		{ASI: "ad.nexus", SID: "pub-9", HP: 1}, // Our own node is not looked up
+	}}
+
+	score, err := builder.ComputeChainTrustScore(schain, registry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if score != 0.5 {
+		t.Errorf("Expected chain score 0.5, got %f", score)
+	}
+
+	schain.Nodes = append(schain.Nodes, SupplyChainNode{ASI: "unknown.com", SID: "x", HP: 1})
+	if _, err := builder.ComputeChainTrustScore(schain, registry); err == nil {
+		t.Error("Expected error when a node cannot be scored")
+	}
+}
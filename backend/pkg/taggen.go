@@ -1,12 +1,50 @@
 package ssp
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
+// ErrInvalidAMPTag is returned when an AMP RTC tag cannot be generated for a placement
+var ErrInvalidAMPTag = errors.New("invalid AMP RTC tag")
+
+// ErrTagAdTypeMismatch is returned when a tag is generated for a placement
+// whose ad type the tag cannot serve
+var ErrTagAdTypeMismatch = errors.New("tag type does not match placement ad type")
+
+// Tag types generated by TagGenerator
+const (
+	TagTypeDisplay       = "display"
+	TagTypeVAST          = "vast"
+	TagTypeHeaderBidding = "header-bidding"
+	TagTypeInterstitial  = "interstitial"
+	TagTypeAMP           = "amp"
+)
+
+// tagAdTypes lists the placement ad types each tag type can serve
+var tagAdTypes = map[string][]string{
+	TagTypeDisplay:       {"banner", "native", AdTypeDOOH},
+	TagTypeVAST:          {"video", AdTypeRewardedVideo},
+	TagTypeHeaderBidding: {"banner"},
+	TagTypeInterstitial:  {"banner"},
+	TagTypeAMP:           {"banner"},
+}
+
+// ampRTCTimeoutMillis is the RTC callout timeout; AMP caps it at 1000ms
+const ampRTCTimeoutMillis = 1000
+
+// prebidBucketMaxCPM is the top of the Prebid.js custom price bucket in header bidding tags
+const prebidBucketMaxCPM = 20.0
+
 // TagGenerator generates ad tags for publishers
 type TagGenerator struct {
+	mu          sync.RWMutex // Guards sspEndpoint and cdnURL, which can change at runtime
 	sspEndpoint string
 	cdnURL      string
 }
@@ -19,10 +57,68 @@ func NewTagGenerator(sspEndpoint, cdnURL string) *TagGenerator {
 	}
 }
 
+// SetSSPEndpoint changes the SSP URL used by tags generated from now on
+func (tg *TagGenerator) SetSSPEndpoint(sspEndpoint string) {
+	tg.mu.Lock()
+	tg.sspEndpoint = sspEndpoint
+	tg.mu.Unlock()
+}
+
+// SetCDNURL changes the CDN URL used by tags generated from now on
+func (tg *TagGenerator) SetCDNURL(cdnURL string) {
+	tg.mu.Lock()
+	tg.cdnURL = cdnURL
+	tg.mu.Unlock()
+}
+
+func (tg *TagGenerator) endpoint() string {
+	tg.mu.RLock()
+	defer tg.mu.RUnlock()
+	return tg.sspEndpoint
+}
+
+func (tg *TagGenerator) cdn() string {
+	tg.mu.RLock()
+	defer tg.mu.RUnlock()
+	return tg.cdnURL
+}
+
+// FloorCPMChanged reports whether a placement update changes the floor
+// embedded in its display and header bidding tags, meaning publishers must
+// regenerate and redeploy those tags
+func (tg *TagGenerator) FloorCPMChanged(old, updated *Placement) bool {
+	if old == nil || updated == nil {
+		return old != updated
+	}
+	return old.MinBidFloor != updated.MinBidFloor
+}
+
+// ValidatePlacementForTag checks that a placement's ad type can be served by
+// a tag type, e.g. that header bidding tags are only generated for banners
+func (tg *TagGenerator) ValidatePlacementForTag(placement *Placement, tagType string) error {
+	adTypes, ok := tagAdTypes[tagType]
+	if !ok {
+		return fmt.Errorf("unknown tag type: %s", tagType)
+	}
+
+	for _, adType := range adTypes {
+		if placement.AdType == adType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: placement %s is %s, %s tags require %s",
+		ErrTagAdTypeMismatch, placement.ID, placement.AdType, tagType, strings.Join(adTypes, " or "))
+}
+
 // GenerateDisplayTag generates a display ad tag
 func (tg *TagGenerator) GenerateDisplayTag(placement *Placement) (string, error) {
+	if err := tg.ValidatePlacementForTag(placement, TagTypeDisplay); err != nil {
+		return "", err
+	}
+
 	tmpl := `<!-- AdNexus SSP Display Ad Tag -->
-<div id="adnexus-{{.PlacementID}}" style="width:{{.Width}}px;height:{{.Height}}px;"></div>
+<div id="adnexus-{{.PlacementID}}" data-floor-cpm="{{.FloorCPM}}" style="width:{{.Width}}px;height:{{.Height}}px;"></div>
 <script>
 (function() {
   var adnexus = window.adnexus || {};
@@ -53,14 +149,16 @@ func (tg *TagGenerator) GenerateDisplayTag(placement *Placement) (string, error)
 		PlacementID string
 		Width       int
 		Height      int
+		FloorCPM    float64
 		SSPEndpoint string
 		CDNURL      string
 	}{
 		PlacementID: placement.ID,
 		Width:       placement.Width,
 		Height:      placement.Height,
-		SSPEndpoint: tg.sspEndpoint,
-		CDNURL:      tg.cdnURL,
+		FloorCPM:    placement.MinBidFloor,
+		SSPEndpoint: tg.endpoint(),
+		CDNURL:      tg.cdn(),
 	}
 
 	var buf []byte
@@ -72,8 +170,59 @@ func (tg *TagGenerator) GenerateDisplayTag(placement *Placement) (string, error)
 	return string(w.buf), nil
 }
 
-// GenerateVASTTag generates a VAST video ad tag
+// GenerateDisplayTagWithNoscript generates a display ad tag followed by a
+// noscript fallback image for browsers with JavaScript disabled. The image is
+// the placement's FallbackImageURL, or a CDN placeholder sized to the placement.
+func (tg *TagGenerator) GenerateDisplayTagWithNoscript(placement *Placement) (string, error) {
+	tag, err := tg.GenerateDisplayTag(placement)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl := `
+<noscript>
+<img src="{{.ImageURL}}" width="{{.Width}}" height="{{.Height}}" alt="Advertisement" style="border:0;">
+</noscript>`
+
+	t, err := template.New("noscript").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	imageURL := placement.FallbackImageURL
+	if imageURL == "" {
+		imageURL = fmt.Sprintf("%s/fallback/%dx%d.png", tg.cdn(), placement.Width, placement.Height)
+	}
+
+	data := struct {
+		ImageURL string
+		Width    int
+		Height   int
+	}{
+		ImageURL: imageURL,
+		Width:    placement.Width,
+		Height:   placement.Height,
+	}
+
+	w := &writeBuffer{buf: []byte(tag)}
+	if err := t.Execute(w, data); err != nil {
+		return "", err
+	}
+
+	return string(w.buf), nil
+}
+
+// GenerateVASTTag generates a VAST video ad tag. Outstream placements get a
+// tag that embeds its own player instead of relying on one on the page.
 func (tg *TagGenerator) GenerateVASTTag(placement *Placement) (string, error) {
+	if err := tg.ValidatePlacementForTag(placement, TagTypeVAST); err != nil {
+		return "", err
+	}
+
+	if placement.IsOutstream() {
+		return tg.generateOutstreamTag(placement)
+	}
+
 	tmpl := `<!-- AdNexus SSP VAST Video Ad Tag -->
 <div id="adnexus-video-{{.PlacementID}}"></div>
 <script>
@@ -113,8 +262,69 @@ func (tg *TagGenerator) GenerateVASTTag(placement *Placement) (string, error) {
 		PlacementID: placement.ID,
 		Width:       placement.Width,
 		Height:      placement.Height,
-		SSPEndpoint: tg.sspEndpoint,
-		CDNURL:      tg.cdnURL,
+		SSPEndpoint: tg.endpoint(),
+		CDNURL:      tg.cdn(),
+	}
+
+	var buf []byte
+	w := &writeBuffer{buf: buf}
+	if err := t.Execute(w, data); err != nil {
+		return "", err
+	}
+
+	return string(w.buf), nil
+}
+
+// generateOutstreamTag generates an outstream video tag that renders the
+// AdNexus player into its own container
+func (tg *TagGenerator) generateOutstreamTag(placement *Placement) (string, error) {
+	tmpl := `<!-- AdNexus SSP Outstream Video Ad Tag -->
+<div id="adnexus-outstream-{{.PlacementID}}" style="width:{{.Width}}px;max-width:100%;"></div>
+<script>
+(function() {
+  var adnexus = window.adnexus || {};
+  adnexus.outstreamPlacements = adnexus.outstreamPlacements || [];
+  adnexus.outstreamPlacements.push({
+    placementId: '{{.PlacementID}}',
+    placementType: '{{.PlacementType}}',
+    width: {{.Width}},
+    height: {{.Height}},
+    vastUrl: '{{.SSPEndpoint}}/vast/{{.PlacementID}}',
+    containerId: 'adnexus-outstream-{{.PlacementID}}',
+    autoplay: true,
+    muted: true,
+    collapseOnComplete: true
+  });
+
+  if (!window.adnexusOutstreamLoaded) {
+    var s = document.createElement('script');
+    s.async = true;
+    s.src = '{{.CDNURL}}/adnexus-outstream.js';
+    document.head.appendChild(s);
+    window.adnexusOutstreamLoaded = true;
+  }
+})();
+</script>`
+
+	t, err := template.New("outstream").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		PlacementID   string
+		PlacementType string
+		Width         int
+		Height        int
+		SSPEndpoint   string
+		CDNURL        string
+	}{
+		PlacementID:   placement.ID,
+		PlacementType: placement.PlacementType,
+		Width:         placement.Width,
+		Height:        placement.Height,
+		SSPEndpoint:   tg.endpoint(),
+		CDNURL:        tg.cdn(),
 	}
 
 	var buf []byte
@@ -128,6 +338,10 @@ func (tg *TagGenerator) GenerateVASTTag(placement *Placement) (string, error) {
 
 // GenerateHeaderBiddingTag generates a Prebid.js compatible header bidding tag
 func (tg *TagGenerator) GenerateHeaderBiddingTag(placement *Placement) (string, error) {
+	if err := tg.ValidatePlacementForTag(placement, TagTypeHeaderBidding); err != nil {
+		return "", err
+	}
+
 	tmpl := `<!-- AdNexus SSP Header Bidding Tag -->
 <script>
 var adnexusPrebid = adnexusPrebid || {};
@@ -150,6 +364,18 @@ adnexusPrebid.que.push(function() {
     }]
   }];
 
+  // Bucket prices from the placement floor so no line item sits below it
+  adnexusPrebid.setConfig({
+    priceGranularity: {
+      buckets: [{
+        precision: 2,
+        min: {{.FloorCPM}},
+        max: {{.BucketMaxCPM}},
+        increment: 0.01
+      }]
+    }
+  });
+
   adnexusPrebid.addAdUnits(adUnits);
   adnexusPrebid.requestBids({
     bidsBackHandler: function() {
@@ -159,7 +385,7 @@ adnexusPrebid.que.push(function() {
 });
 </script>
 
-<div id='adnexus-hb-{{.PlacementID}}' style='width:{{.Width}}px;height:{{.Height}}px;'>
+<div id='adnexus-hb-{{.PlacementID}}' data-floor-cpm='{{.FloorCPM}}' style='width:{{.Width}}px;height:{{.Height}}px;'>
   <script>
     googletag.cmd.push(function() {
       googletag.display('adnexus-hb-{{.PlacementID}}');
@@ -185,18 +411,275 @@ adnexusPrebid.que.push(function() {
 		sizes = fmt.Sprintf("[%d, %d]", placement.Width, placement.Height)
 	}
 
+	data := struct {
+		PlacementID  string
+		Width        int
+		Height       int
+		Sizes        string
+		FloorCPM     float64
+		BucketMaxCPM float64
+		SSPEndpoint  string
+	}{
+		PlacementID:  placement.ID,
+		Width:        placement.Width,
+		Height:       placement.Height,
+		Sizes:        sizes,
+		FloorCPM:     placement.MinBidFloor,
+		BucketMaxCPM: max(prebidBucketMaxCPM, 2*placement.MinBidFloor),
+		SSPEndpoint:  tg.endpoint(),
+	}
+
+	var buf []byte
+	w := &writeBuffer{buf: buf}
+	if err := t.Execute(w, data); err != nil {
+		return "", err
+	}
+
+	return string(w.buf), nil
+}
+
+// GenerateInterstitialTag generates a full-page overlay ad tag with a close button
+func (tg *TagGenerator) GenerateInterstitialTag(placement *Placement) (string, error) {
+	if err := tg.ValidatePlacementForTag(placement, TagTypeInterstitial); err != nil {
+		return "", err
+	}
+
+	tmpl := `<!-- AdNexus SSP Interstitial Ad Tag -->
+<style>
+  #adnexus-interstitial-{{.PlacementID}} {
+    position: fixed;
+    top: 0;
+    left: 0;
+    width: 100vw;
+    height: 100vh;
+    z-index: 2147483647;
+    background: rgba(0, 0, 0, 0.85);
+    display: flex;
+    align-items: center;
+    justify-content: center;
+  }
+  #adnexus-interstitial-{{.PlacementID}} .adnexus-close {
+    position: absolute;
+    top: 12px;
+    right: 12px;
+    width: 32px;
+    height: 32px;
+    border: 0;
+    border-radius: 50%;
+    background: #fff;
+    color: #000;
+    font-size: 20px;
+    line-height: 32px;
+    cursor: pointer;
+  }
+</style>
+<div id="adnexus-interstitial-{{.PlacementID}}">
+  <button type="button" class="adnexus-close" aria-label="Close ad">&times;</button>
+  <div id="adnexus-{{.PlacementID}}" style="width:{{.Width}}px;height:{{.Height}}px;max-width:100vw;max-height:100vh;"></div>
+</div>
+<script>
+(function() {
+  var overlay = document.getElementById('adnexus-interstitial-{{.PlacementID}}');
+  overlay.querySelector('.adnexus-close').addEventListener('click', function() {
+    overlay.parentNode.removeChild(overlay);
+  });
+
+  var adnexus = window.adnexus || {};
+  adnexus.placements = adnexus.placements || [];
+  adnexus.placements.push({
+    placementId: '{{.PlacementID}}',
+    width: {{.Width}},
+    height: {{.Height}},
+    interstitial: true,
+    endpoint: '{{.SSPEndpoint}}/ad/request'
+  });
+
+  if (!window.adnexusLoaded) {
+    var s = document.createElement('script');
+    s.async = true;
+    s.src = '{{.CDNURL}}/adnexus-ssp.js';
+    document.head.appendChild(s);
+    window.adnexusLoaded = true;
+  }
+})();
+</script>`
+
+	t, err := template.New("interstitial").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
 	data := struct {
 		PlacementID string
 		Width       int
 		Height      int
-		Sizes       string
 		SSPEndpoint string
+		CDNURL      string
+	}{
+		PlacementID: placement.ID,
+		Width:       placement.Width,
+		Height:      placement.Height,
+		SSPEndpoint: tg.endpoint(),
+		CDNURL:      tg.cdn(),
+	}
+
+	var buf []byte
+	w := &writeBuffer{buf: buf}
+	if err := t.Execute(w, data); err != nil {
+		return "", err
+	}
+
+	return string(w.buf), nil
+}
+
+// GeneratePreviewPage generates a standalone HTML page that renders the
+// placement's ad tag inside an iframe sized to the placement. Video placements
+// use the VAST video tag; everything else uses the display tag.
+func (tg *TagGenerator) GeneratePreviewPage(placement *Placement) (string, error) {
+	var tag string
+	var err error
+	width, height := placement.Width, placement.Height
+	switch placement.AdType {
+	case "video", AdTypeRewardedVideo:
+		tag, err = tg.GenerateVASTTag(placement)
+		if width == 0 || height == 0 {
+			width, height = 640, 360
+		}
+	default:
+		tag, err = tg.GenerateDisplayTag(placement)
+		if width == 0 || height == 0 {
+			width, height = 300, 250
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	frameTmpl := `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>html, body { margin: 0; padding: 0; }</style>
+</head>
+<body>
+{{.Tag}}
+</body>
+</html>`
+
+	pageTmpl := `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>AdNexus Placement Preview: {{.Name}}</title>
+<style>
+  body { font-family: sans-serif; margin: 24px; background: #f5f5f5; }
+  .adnexus-preview-frame { border: 1px dashed #999; background: #fff; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>Placement {{.PlacementID}} &middot; {{.AdType}} &middot; {{.Width}}x{{.Height}}</p>
+<iframe class="adnexus-preview-frame" title="Ad preview" width="{{.Width}}" height="{{.Height}}"
+  sandbox="allow-scripts allow-popups" srcdoc="{{.Frame}}"></iframe>
+</body>
+</html>`
+
+	ft, err := template.New("previewframe").Parse(frameTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	fw := &writeBuffer{}
+	if err := ft.Execute(fw, struct{ Tag template.HTML }{Tag: template.HTML(tag)}); err != nil {
+		return "", err
+	}
+
+	pt, err := template.New("preview").Parse(pageTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		PlacementID string
+		Name        string
+		AdType      string
+		Width       int
+		Height      int
+		Frame       string
+	}{
+		PlacementID: placement.ID,
+		Name:        placement.Name,
+		AdType:      placement.AdType,
+		Width:       width,
+		Height:      height,
+		Frame:       string(fw.buf),
+	}
+
+	var buf []byte
+	w := &writeBuffer{buf: buf}
+	if err := pt.Execute(w, data); err != nil {
+		return "", err
+	}
+
+	return string(w.buf), nil
+}
+
+// GenerateAMPRTCTag generates an <amp-ad> tag whose real-time config calls the
+// SSP's AMP RTC endpoint. AMP only sends RTC callouts as GET requests, so an
+// rtcURL must accept GET. An empty rtcURL uses the SSP endpoint.
+func (tg *TagGenerator) GenerateAMPRTCTag(placement *Placement, rtcURL string) (string, error) {
+	if err := tg.ValidatePlacementForTag(placement, TagTypeAMP); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidAMPTag, err)
+	}
+
+	if rtcURL == "" {
+		rtcURL = tg.endpoint() + "/amp/rtc"
+	}
+
+	u, err := url.Parse(rtcURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("%w: RTC URL must be an absolute https URL", ErrInvalidAMPTag)
+	}
+
+	query := u.Query()
+	query.Set("placement_id", placement.ID)
+	u.RawQuery = query.Encode()
+
+	rtcConfig, err := json.Marshal(struct {
+		URLs          []string `json:"urls"`
+		TimeoutMillis int      `json:"timeoutMillis"`
+	}{
+		URLs:          []string{u.String()},
+		TimeoutMillis: ampRTCTimeoutMillis,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tmpl := `<!-- AdNexus SSP AMP RTC Tag -->
+<amp-ad width="{{.Width}}" height="{{.Height}}"
+  type="doubleclick"
+  data-slot="/adnexus/{{.PlacementID}}"
+  rtc-config='{{.RTCConfig}}'>
+</amp-ad>`
+
+	t, err := template.New("amprtc").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		PlacementID string
+		Width       int
+		Height      int
+		RTCConfig   string
 	}{
 		PlacementID: placement.ID,
 		Width:       placement.Width,
 		Height:      placement.Height,
-		Sizes:       sizes,
-		SSPEndpoint: tg.sspEndpoint,
+		RTCConfig:   string(rtcConfig),
 	}
 
 	var buf []byte
@@ -208,19 +691,45 @@ adnexusPrebid.que.push(function() {
 	return string(w.buf), nil
 }
 
-// GenerateVASTXML generates a VAST XML response
-func (tg *TagGenerator) GenerateVASTXML(bid *Bid, placement *Placement) string {
+// ImpressionURL returns the impression pixel URL of a served bid, carrying
+// the current time as ts and its signature
+func (tg *TagGenerator) ImpressionURL(servedID string, signer *ImpressionSigner, now time.Time) string {
+	ts := now.UnixMilli()
+	return fmt.Sprintf("%s/impression/%s?ts=%d&sig=%s", tg.endpoint(), url.PathEscape(servedID), ts, signer.Sign(servedID, ts))
+}
+
+// GenerateVASTXML generates a VAST XML response. servedID identifies the bid
+// in the SSP's tracking URLs; see BidCacheEntry.
+func (tg *TagGenerator) GenerateVASTXML(bid *Bid, placement *Placement, servedID string) string {
+	companions := companionAdsXML(selectCompanions(bid, placement))
+
+	// The player substitutes the IAB error code for the [ERRORCODE] macro
+	errorURL := fmt.Sprintf("%s/vast/error/%s?placement_id=%s&errorcode=[ERRORCODE]",
+		tg.endpoint(), url.PathEscape(servedID), url.QueryEscape(placement.ID))
+
+	// Rewarded video reports completion back to the SSP so the reward callback can fire
+	tracking := ""
+	if placement.IsRewarded() {
+		completeURL := fmt.Sprintf("%s/impression/%s?event=complete&placement_id=%s",
+			tg.endpoint(), url.PathEscape(servedID), url.QueryEscape(placement.ID))
+		tracking = fmt.Sprintf(`
+            <TrackingEvents>
+              <Tracking event="complete"><![CDATA[%s]]></Tracking>
+            </TrackingEvents>`, completeURL)
+	}
+
 	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <VAST version="3.0">
   <Ad id="%s">
     <InLine>
       <AdSystem>AdNexus SSP</AdSystem>
       <AdTitle>Advertisement</AdTitle>
+      <Error><![CDATA[%s]]></Error>
       <Impression><![CDATA[%s]]></Impression>
       <Creatives>
         <Creative>
           <Linear>
-            <Duration>00:00:30</Duration>
+            <Duration>00:00:30</Duration>%s
             <MediaFiles>
               <MediaFile delivery="progressive" type="video/mp4" width="%d" height="%d">
                 <![CDATA[%s]]>
@@ -230,13 +739,16 @@ func (tg *TagGenerator) GenerateVASTXML(bid *Bid, placement *Placement) string {
               <ClickThrough><![CDATA[%s]]></ClickThrough>
             </VideoClicks>
           </Linear>
-        </Creative>
+        </Creative>%s
       </Creatives>
     </InLine>
   </Ad>
-</VAST>`, bid.ID, bid.NURL, placement.Width, placement.Height, bid.IURL, bid.ADM)
+</VAST>`, xmlAttrEscape(bid.ID), errorURL, cdataEscape(bid.NURL), tracking, placement.Width, placement.Height, cdataEscape(bid.IURL), cdataEscape(bid.ADM), companions)
 }
 
+// EmptyVAST is the VAST document returned when no ad is available
+const EmptyVAST = `<?xml version="1.0" encoding="UTF-8"?><VAST version="3.0"></VAST>`
+
 // writeBuffer implements io.Writer for template execution
 type writeBuffer struct {
 	buf []byte
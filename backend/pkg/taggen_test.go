@@ -0,0 +1,261 @@
+package ssp
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAMPRTCTag(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	tag, err := tg.GenerateAMPRTCTag(placement, "")
+	if err != nil {
+		t.Fatalf("Failed to generate AMP RTC tag: %v", err)
+	}
+
+	if !strings.Contains(tag, `type="doubleclick"`) {
+		t.Errorf("Expected doubleclick amp-ad, got:\n%s", tag)
+	}
+
+	expected := `rtc-config='{"urls":["https://ssp.example.com/amp/rtc?placement_id=placement-1"],"timeoutMillis":1000}'`
+	if !strings.Contains(html.UnescapeString(tag), expected) {
+		t.Errorf("Expected %s in tag, got:\n%s", expected, tag)
+	}
+}
+
+func TestGenerateAMPRTCTagValidation(t *testing.T) {
+	tg := NewTagGenerator("http://localhost:8081", "https://cdn.example.com")
+
+	tests := []struct {
+		name      string
+		placement *Placement
+		rtcURL    string
+	}{
+		{"insecure default endpoint", &Placement{ID: "p1", AdType: "banner"}, ""},
+		{"insecure rtc url", &Placement{ID: "p1", AdType: "banner"}, "http://ssp.example.com/amp/rtc"},
+		{"relative rtc url", &Placement{ID: "p1", AdType: "banner"}, "/amp/rtc"},
+		{"video placement", &Placement{ID: "p1", AdType: "video"}, "https://ssp.example.com/amp/rtc"},
+	}
+
+	for _, tt := range tests {
+		if _, err := tg.GenerateAMPRTCTag(tt.placement, tt.rtcURL); !errors.Is(err, ErrInvalidAMPTag) {
+			t.Errorf("%s: expected ErrInvalidAMPTag, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidatePlacementForTag(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+
+	tests := []struct {
+		adType  string
+		tagType string
+		valid   bool
+	}{
+		{"banner", TagTypeDisplay, true},
+		{"native", TagTypeDisplay, true},
+		{"video", TagTypeDisplay, false},
+		{"video", TagTypeVAST, true},
+		{AdTypeRewardedVideo, TagTypeVAST, true},
+		{"banner", TagTypeVAST, false},
+		{"banner", TagTypeHeaderBidding, true},
+		{"video", TagTypeHeaderBidding, false},
+		{"native", TagTypeInterstitial, false},
+	}
+
+	for _, tt := range tests {
+		err := tg.ValidatePlacementForTag(&Placement{ID: "p1", AdType: tt.adType}, tt.tagType)
+		if tt.valid && err != nil {
+			t.Errorf("%s %s: unexpected error %v", tt.adType, tt.tagType, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrTagAdTypeMismatch) {
+			t.Errorf("%s %s: expected ErrTagAdTypeMismatch, got %v", tt.adType, tt.tagType, err)
+		}
+	}
+
+	if err := tg.ValidatePlacementForTag(&Placement{ID: "p1", AdType: "banner"}, "popunder"); err == nil {
+		t.Error("Expected error for unknown tag type")
+	}
+
+	if _, err := tg.GenerateHeaderBiddingTag(&Placement{ID: "p1", AdType: "video"}); !errors.Is(err, ErrTagAdTypeMismatch) {
+		t.Errorf("Expected header bidding tag for video placement to fail, got %v", err)
+	}
+}
+
+func TestGenerateInterstitialTag(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 320, Height: 480, Interstitial: true}
+
+	tag, err := tg.GenerateInterstitialTag(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate interstitial tag: %v", err)
+	}
+
+	for _, expected := range []string{
+		`id="adnexus-interstitial-placement-1"`,
+		"width: 100vw;",
+		"height: 100vh;",
+		`class="adnexus-close"`,
+		"interstitial: true",
+	} {
+		if !strings.Contains(tag, expected) {
+			t.Errorf("Expected %q in tag, got:\n%s", expected, tag)
+		}
+	}
+}
+
+func TestGeneratePreviewPage(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+
+	page, err := tg.GeneratePreviewPage(&Placement{ID: "placement-1", Name: "Leaderboard", AdType: "banner", Width: 728, Height: 90})
+	if err != nil {
+		t.Fatalf("Failed to generate preview page: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<meta name="viewport"`,
+		`<iframe class="adnexus-preview-frame"`,
+		`width="728" height="90"`,
+		"adnexus-ssp.js",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %q in preview page, got:\n%s", expected, page)
+		}
+	}
+
+	page, err = tg.GeneratePreviewPage(&Placement{ID: "placement-2", AdType: "video"})
+	if err != nil {
+		t.Fatalf("Failed to generate video preview page: %v", err)
+	}
+
+	if !strings.Contains(page, `width="640" height="360"`) || !strings.Contains(page, "adnexus-video.js") {
+		t.Errorf("Expected default-sized video player preview, got:\n%s", page)
+	}
+}
+
+func TestGenerateDisplayTagWithNoscript(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	tag, err := tg.GenerateDisplayTagWithNoscript(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate noscript tag: %v", err)
+	}
+
+	for _, expected := range []string{
+		`id="adnexus-placement-1"`,
+		"<noscript>",
+		`<img src="https://cdn.example.com/fallback/300x250.png" width="300" height="250"`,
+	} {
+		if !strings.Contains(tag, expected) {
+			t.Errorf("Expected %q in tag, got:\n%s", expected, tag)
+		}
+	}
+
+	placement.FallbackImageURL = "https://images.example.com/house-ad.png"
+	tag, err = tg.GenerateDisplayTagWithNoscript(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate noscript tag: %v", err)
+	}
+
+	if !strings.Contains(tag, `<img src="https://images.example.com/house-ad.png"`) {
+		t.Errorf("Expected placement fallback image, got:\n%s", tag)
+	}
+}
+
+func TestTagsIncludeFloorCPM(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250, MinBidFloor: 1.5}
+
+	display, err := tg.GenerateDisplayTag(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate display tag: %v", err)
+	}
+	if !strings.Contains(display, `data-floor-cpm="1.5"`) {
+		t.Errorf("Expected floor attribute in display tag, got:\n%s", display)
+	}
+
+	hb, err := tg.GenerateHeaderBiddingTag(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate header bidding tag: %v", err)
+	}
+	for _, expected := range []string{
+		`data-floor-cpm='1.5'`,
+		"priceGranularity",
+		"min:  1.5 ,",
+		"max:  20 ,",
+	} {
+		if !strings.Contains(hb, expected) {
+			t.Errorf("Expected %q in header bidding tag, got:\n%s", expected, hb)
+		}
+	}
+}
+
+func TestFloorCPMChanged(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	old := &Placement{ID: "placement-1", MinBidFloor: 1.0, Name: "Old"}
+
+	if tg.FloorCPMChanged(old, &Placement{ID: "placement-1", MinBidFloor: 1.0, Name: "Renamed"}) {
+		t.Error("Expected no change when only the name changes")
+	}
+	if !tg.FloorCPMChanged(old, &Placement{ID: "placement-1", MinBidFloor: 1.25}) {
+		t.Error("Expected a floor change to require new tags")
+	}
+}
+
+func TestGenerateVASTXMLErrorURL(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	bid := &Bid{ID: "bid 1", ADM: "https://cdn.example.com/video.mp4"}
+	placement := &Placement{ID: "placement-1", AdType: "video", Width: 640, Height: 360}
+
+	vast := tg.GenerateVASTXML(bid, placement, bid.ID)
+
+	expected := "<Error><![CDATA[https://ssp.example.com/vast/error/bid%201?placement_id=placement-1&errorcode=[ERRORCODE]]]></Error>"
+	if !strings.Contains(vast, expected) {
+		t.Errorf("Expected %s in VAST, got:\n%s", expected, vast)
+	}
+}
+
+func TestGenerateVASTXMLEscapesBidID(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	bid := &Bid{ID: `bid"><Evil>`, ADM: "https://advertiser.example.com", IURL: "https://cdn.example.com/video.mp4"}
+	placement := &Placement{ID: "placement-1", AdType: "video", Width: 640, Height: 360}
+
+	vast := tg.GenerateVASTXML(bid, placement, bid.ID)
+	if !strings.Contains(vast, `<Ad id="bid&quot;&gt;&lt;Evil&gt;">`) || strings.Contains(vast, "<Evil>") {
+		t.Errorf("Expected the bid ID escaped in the Ad id attribute, got:\n%s", vast)
+	}
+}
+
+func TestTagGeneratorSetEndpoints(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	tg.SetSSPEndpoint("https://ssp2.example.com")
+	tg.SetCDNURL("https://cdn2.example.com")
+
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	tag, err := tg.GenerateDisplayTag(placement)
+	if err != nil {
+		t.Fatalf("Failed to generate display tag: %v", err)
+	}
+	if !strings.Contains(tag, "ssp2.example.com/ad/request") || !strings.Contains(tag, "cdn2.example.com/adnexus-ssp.js") {
+		t.Errorf("Expected the updated SSP and CDN URLs in tag, got:\n%s", tag)
+	}
+}
+
+func TestGenerateVASTXMLValidates(t *testing.T) {
+	tg := NewTagGenerator("https://ssp.example.com", "https://cdn.example.com")
+	placement := &Placement{ID: "placement-1", AdType: "video", Width: 640, Height: 360}
+
+	served := &Bid{ID: "bid-1", ADM: "https://advertiser.example.com", IURL: "https://cdn.example.com/video.mp4"}
+	if warnings := (VASTValidator{}).Validate(tg.GenerateVASTXML(served, placement, served.ID), placement.AdType); HasValidationErrors(warnings) {
+		t.Errorf("Expected generated VAST to validate, got %v", warnings)
+	}
+
+	noMedia := &Bid{ID: "bid-2", ADM: "https://advertiser.example.com"}
+	if warnings := (VASTValidator{}).Validate(tg.GenerateVASTXML(noMedia, placement, noMedia.ID), placement.AdType); !HasValidationErrors(warnings) {
+		t.Error("Expected VAST without a media file to fail validation")
+	}
+}
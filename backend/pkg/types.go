@@ -1,49 +1,196 @@
 package ssp
 
 import (
+	"fmt"
 	"time"
 )
 
 // Publisher represents a publisher entity
 type Publisher struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Email       string    `json:"email"`
-	Domain      string    `json:"domain"`
-	Active      bool      `json:"active"`
-	RevShare    float64   `json:"revShare"` // Publisher revenue share (0.0-1.0)
-	PaymentInfo string    `json:"paymentInfo,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	Email                string            `json:"email"`
+	Domain               string            `json:"domain"`
+	Active               bool              `json:"active"`
+	Status               string            `json:"status"` // pending, active, suspended, rejected
+	StatusReason         string            `json:"statusReason,omitempty"`
+	RevShare             float64           `json:"revShare"` // Publisher revenue share (0.0-1.0)
+	PaymentInfo          string            `json:"paymentInfo,omitempty"`
+	Notes                string            `json:"notes,omitempty"`                // Internal account notes, at most MaxPublisherNotesLength characters
+	Metadata             map[string]string `json:"metadata,omitempty"`             // Free-form account metadata
+	MaxPlacementsPerSite int               `json:"maxPlacementsPerSite,omitempty"` // Placement quota per site; 0 uses DefaultMaxPlacementsPerSite
+	BlockedCategories    []string          `json:"blockedCategories,omitempty"`    // IAB categories blocked on all of the publisher's sites
+	CreatedAt            time.Time         `json:"createdAt"`
+	UpdatedAt            time.Time         `json:"updatedAt"`
+}
+
+// RevShareHistory is a period during which a publisher's revenue share applied
+type RevShareHistory struct {
+	PublisherID   string     `json:"publisherId"`
+	RevShare      float64    `json:"revShare"`
+	EffectiveFrom time.Time  `json:"effectiveFrom"`
+	EffectiveTo   *time.Time `json:"effectiveTo,omitempty"` // nil for the current rate
+	ChangedBy     string     `json:"changedBy,omitempty"`   // empty for the rate set at signup
+}
+
+// MaxPublisherNotesLength is the maximum length of Publisher.Notes in characters
+const MaxPublisherNotesLength = 2048
+
+// DefaultMaxPlacementsPerSite is the per-site placement quota for publishers without their own
+const DefaultMaxPlacementsPerSite = 50
+
+// PlacementLimit returns the maximum number of placements allowed on each of the publisher's sites
+func (p *Publisher) PlacementLimit() int {
+	if p.MaxPlacementsPerSite > 0 {
+		return p.MaxPlacementsPerSite
+	}
+	return DefaultMaxPlacementsPerSite
 }
 
 // Site represents a publisher site
 type Site struct {
-	ID          string    `json:"id"`
-	PublisherID string    `json:"publisherId"`
-	Name        string    `json:"name"`
-	Domain      string    `json:"domain"`
-	Page        string    `json:"page,omitempty"`
-	Cat         []string  `json:"cat,omitempty"` // IAB categories
-	Active      bool      `json:"active"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID                string        `json:"id"`
+	PublisherID       string        `json:"publisherId"`
+	Name              string        `json:"name"`
+	Domain            string        `json:"domain"`
+	Page              string        `json:"page,omitempty"`
+	Cat               []string      `json:"cat,omitempty"`               // IAB categories
+	ContentRating     string        `json:"contentRating,omitempty"`     // G, PG, PG13, R or X
+	Language          string        `json:"language,omitempty"`          // ISO 639-1 content language, e.g. "en"
+	ImpressionCap     *FrequencyCap `json:"impressionCap,omitempty"`     // Per-user impression limit
+	BlockedCategories []string      `json:"blockedCategories,omitempty"` // IAB categories blocked on all of the site's placements
+	Active            bool          `json:"active"`
+	CreatedAt         time.Time     `json:"createdAt"`
+	UpdatedAt         time.Time     `json:"updatedAt"`
+}
+
+// FrequencyCap limits how many impressions a user sees within a rolling window
+type FrequencyCap struct {
+	MaxImpressions int `json:"maxImpressions"`
+	WindowHours    int `json:"windowHours"`
+}
+
+// ValidFrequencyCap reports whether a frequency cap is unset or has a positive limit and window
+func ValidFrequencyCap(fc *FrequencyCap) bool {
+	return fc == nil || (fc.MaxImpressions > 0 && fc.WindowHours > 0)
+}
+
+// Site content ratings
+const (
+	ContentRatingG    = "G"
+	ContentRatingPG   = "PG"
+	ContentRatingPG13 = "PG13"
+	ContentRatingR    = "R"
+	ContentRatingX    = "X"
+)
+
+// ValidContentRating reports whether rating is a known content rating or empty (unrated)
+func ValidContentRating(rating string) bool {
+	switch rating {
+	case "", ContentRatingG, ContentRatingPG, ContentRatingPG13, ContentRatingR, ContentRatingX:
+		return true
+	}
+	return false
 }
 
 // Placement represents an ad placement on a site
 type Placement struct {
-	ID          string         `json:"id"`
-	SiteID      string         `json:"siteId"`
-	Name        string         `json:"name"`
-	AdType      string         `json:"adType"` // banner, video, native
-	Width       int            `json:"width,omitempty"`
-	Height      int            `json:"height,omitempty"`
-	MinBidFloor float64        `json:"minBidFloor"`
-	Active      bool           `json:"active"`
-	Formats     []Format       `json:"formats,omitempty"` // For multi-size placements
-	Video       *VideoSettings `json:"video,omitempty"`   // Video-specific settings
-	CreatedAt   time.Time      `json:"createdAt"`
-	UpdatedAt   time.Time      `json:"updatedAt"`
+	ID                string          `json:"id"`
+	SiteID            string          `json:"siteId"`
+	Name              string          `json:"name"`
+	AdType            string          `json:"adType"` // banner, video, rewarded_video, native, dooh
+	Width             int             `json:"width,omitempty"`
+	Height            int             `json:"height,omitempty"`
+	MinBidFloor       float64         `json:"minBidFloor"`
+	Active            bool            `json:"active"`
+	Formats           []Format        `json:"formats,omitempty"`           // For multi-size placements
+	Video             *VideoSettings  `json:"video,omitempty"`             // Video-specific settings
+	TimeoutMs         int             `json:"timeoutMs,omitempty"`         // Partner bid timeout; 0 uses the bidder default
+	Deals             []Deal          `json:"deals,omitempty"`             // PMP and programmatic guaranteed deals
+	PlacementType     string          `json:"placementType,omitempty"`     // Video: in-stream, in-banner, in-article, in-feed
+	RewardCallbackURL string          `json:"rewardCallbackUrl,omitempty"` // Rewarded video: notified server-side on completion
+	AuctionType       int             `json:"auctionType,omitempty"`       // OpenRTB at sent to DSPs: 1=first price, 2=second price; 0 uses 2
+	ScheduleEnabled   bool            `json:"scheduleEnabled,omitempty"`   // Only serve within Schedule
+	Schedule          []TimeSlot      `json:"schedule,omitempty"`          // Weekly serving windows
+	MinFillRate       float64         `json:"minFillRate,omitempty"`       // Target fill rate (0.05 = 5%); below it the auction may relax the floor
+	DOOH              *DOOHSettings   `json:"dooh,omitempty"`              // Digital out-of-home venue metadata
+	Interstitial      bool            `json:"interstitial,omitempty"`      // Full-screen placement, sent to DSPs as imp.instl
+	BlockedDomains    []string        `json:"blockedDomains,omitempty"`    // Advertiser domains rejected in the auction, sent to DSPs as badv
+	MinWidth          int             `json:"minWidth,omitempty"`          // Smallest banner format width offered and served
+	MinHeight         int             `json:"minHeight,omitempty"`         // Smallest banner format height offered and served
+	FallbackImageURL  string          `json:"fallbackImageUrl,omitempty"`  // Display tag noscript image; defaults to a CDN placeholder
+	BlockedCategories []string        `json:"blockedCategories,omitempty"` // IAB categories blocked on this placement
+	ScreenMetadata    *ScreenMetadata `json:"screenMetadata,omitempty"`    // DOOH screen location and audience, sent as imp.ext.dooh
+	App               *App            `json:"app,omitempty"`               // Mobile app inventory, sent to DSPs as app in place of site
+	CreatedAt         time.Time       `json:"createdAt"`
+	UpdatedAt         time.Time       `json:"updatedAt"`
+}
+
+// Video placement types
+const (
+	PlacementTypeInStream  = "in-stream"  // Played before, during or after video content
+	PlacementTypeInBanner  = "in-banner"  // Outstream: inside a display banner slot
+	PlacementTypeInArticle = "in-article" // Outstream: between paragraphs of editorial content
+	PlacementTypeInFeed    = "in-feed"    // Outstream: within a content feed
+)
+
+// videoPlacementTypes maps placement types to OpenRTB video placement values (list 5.9)
+var videoPlacementTypes = map[string]int{
+	PlacementTypeInStream:  1,
+	PlacementTypeInBanner:  2,
+	PlacementTypeInArticle: 3,
+	PlacementTypeInFeed:    4,
+}
+
+// ValidPlacementType reports whether placementType is a known video placement type or empty
+func ValidPlacementType(placementType string) bool {
+	if placementType == "" {
+		return true
+	}
+	_, ok := videoPlacementTypes[placementType]
+	return ok
+}
+
+// AdTypeRewardedVideo is a non-skippable video that grants the user a reward on completion
+const AdTypeRewardedVideo = "rewarded_video"
+
+// AdTypeDOOH is a digital out-of-home screen, such as a billboard or transit display
+const AdTypeDOOH = "dooh"
+
+// validAdTypes lists the ad types BuildBidRequest supports
+var validAdTypes = map[string]bool{
+	"banner":            true,
+	"video":             true,
+	AdTypeRewardedVideo: true,
+	"native":            true,
+	AdTypeDOOH:          true,
+}
+
+// ValidAdType reports whether adType is a supported placement ad type
+func ValidAdType(adType string) bool {
+	return validAdTypes[adType]
+}
+
+// IsRewarded reports whether the placement serves rewarded video
+func (p *Placement) IsRewarded() bool {
+	return p.AdType == AdTypeRewardedVideo
+}
+
+// MeetsMinSize reports whether a creative size satisfies the placement's
+// MinWidth and MinHeight. A zero dimension is unknown and is not rejected.
+func (p *Placement) MeetsMinSize(w, h int) bool {
+	if w > 0 && w < p.MinWidth {
+		return false
+	}
+	if h > 0 && h < p.MinHeight {
+		return false
+	}
+	return true
+}
+
+// IsOutstream reports whether the placement plays video outside of a publisher video player
+func (p *Placement) IsOutstream() bool {
+	return p.AdType == "video" && p.PlacementType != "" && p.PlacementType != PlacementTypeInStream
 }
 
 // Format represents an ad format size
@@ -52,16 +199,48 @@ type Format struct {
 	H int `json:"h"`
 }
 
+// Placement format size bounds (up to 4K)
+const (
+	MaxFormatWidth  = 3840
+	MaxFormatHeight = 2160
+)
+
+// ValidatePlacementFormats rejects format sizes that no creative could fill
+func ValidatePlacementFormats(formats []Format) error {
+	for i, f := range formats {
+		if f.W < 1 || f.H < 1 || f.W > MaxFormatWidth || f.H > MaxFormatHeight {
+			return fmt.Errorf("format %d: size %dx%d must be between 1x1 and %dx%d", i, f.W, f.H, MaxFormatWidth, MaxFormatHeight)
+		}
+	}
+	return nil
+}
+
 // VideoSettings represents video placement settings
 type VideoSettings struct {
-	Mimes          []string `json:"mimes"`
-	MinDuration    int      `json:"minduration,omitempty"`
-	MaxDuration    int      `json:"maxduration"`
-	Protocols      []int    `json:"protocols"`
-	Linearity      int      `json:"linearity,omitempty"`  // 1=linear, 2=non-linear
-	StartDelay     int      `json:"startdelay,omitempty"` // -1=mid-roll, 0=pre-roll, >0=seconds
-	PlaybackMethod []int    `json:"playbackmethod,omitempty"`
-	API            []int    `json:"api,omitempty"` // Supported API frameworks
+	Mimes          []string          `json:"mimes"`
+	MinDuration    int               `json:"minduration,omitempty"`
+	MaxDuration    int               `json:"maxduration"`
+	Protocols      []int             `json:"protocols"`
+	Linearity      int               `json:"linearity,omitempty"`  // 1=linear, 2=non-linear
+	StartDelay     int               `json:"startdelay,omitempty"` // -1=mid-roll, 0=pre-roll, >0=seconds
+	PlaybackMethod []int             `json:"playbackmethod,omitempty"`
+	API            []int             `json:"api,omitempty"`          // Supported API frameworks
+	CompanionAds   []CompanionAdSpec `json:"companionads,omitempty"` // Companion slots shown alongside the player
+}
+
+// DOOHSettings describes the venue of a digital out-of-home placement
+type DOOHSettings struct {
+	VenueType    []int `json:"venuetype,omitempty"`    // Venue type IDs from VenueTypeTax
+	VenueTypeTax int   `json:"venuetypetax,omitempty"` // Venue taxonomy (1=AdCom DOOH venue types, 2=OpenOOH)
+}
+
+// ScreenMetadata describes the physical screen of a DOOH placement for venue
+// and geo targeting by demand partners
+type ScreenMetadata struct {
+	VenueID       string `json:"venueid,omitempty"`
+	VenueType     int    `json:"venuetype,omitempty"`     // Venue type ID from the placement's DOOH VenueTypeTax
+	DMACode       string `json:"dma,omitempty"`           // Nielsen DMA code of the screen location
+	AudienceCount int    `json:"audiencecount,omitempty"` // Expected viewers per ad play
 }
 
 // OpenRTB 2.5 structures
@@ -72,6 +251,7 @@ type BidRequest struct {
 	Imp     []Impression `json:"imp"`
 	Site    *SiteInfo    `json:"site,omitempty"`
 	App     *App         `json:"app,omitempty"`
+	DOOH    *DOOHInfo    `json:"dooh,omitempty"` // OpenRTB 2.6; replaces site/app for out-of-home screens
 	Device  *Device      `json:"device,omitempty"`
 	User    *User        `json:"user,omitempty"`
 	Test    int          `json:"test,omitempty"`
@@ -144,7 +324,7 @@ type Video struct {
 	StartDelay     int         `json:"startdelay,omitempty"`
 	Placement      int         `json:"placement,omitempty"` // Video placement type
 	Linearity      int         `json:"linearity,omitempty"`
-	Skip           int         `json:"skip,omitempty"`      // 1=skippable
+	Skip           *int        `json:"skip,omitempty"`      // 1=skippable, 0=not skippable
 	SkipMin        int         `json:"skipmin,omitempty"`   // Seconds before skip button
 	SkipAfter      int         `json:"skipafter,omitempty"` // Seconds video must play
 	Sequence       int         `json:"sequence,omitempty"`
@@ -231,6 +411,18 @@ type App struct {
 	Content       *Content    `json:"content,omitempty"`
 	Keywords      string      `json:"keywords,omitempty"`
 	Ext           interface{} `json:"ext,omitempty"`
+
+	SKAdNetworkIDs []string `json:"-"` // Sent to DSPs as ext.skadnetwork for iOS apps
+}
+
+// DOOHInfo represents a digital out-of-home screen (OpenRTB 2.6)
+type DOOHInfo struct {
+	ID           string      `json:"id,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	VenueType    []int       `json:"venuetype,omitempty"`
+	VenueTypeTax int         `json:"venuetypetax,omitempty"`
+	Publisher    *Publisher2 `json:"publisher,omitempty"`
+	Domain       string      `json:"domain,omitempty"`
 }
 
 // Publisher2 represents publisher information in bid request (named to avoid conflict)
@@ -347,6 +539,23 @@ type User struct {
 	Ext        interface{} `json:"ext,omitempty"`
 }
 
+// UserExt is the User.Ext object carrying extended (third-party) identifiers
+type UserExt struct {
+	EIDs []EID `json:"eids,omitempty"`
+}
+
+// EID is an extended identifier from a single ID provider
+type EID struct {
+	Source string `json:"source"` // ID provider domain, e.g. "uidapi.com"
+	UIDs   []UID  `json:"uids"`
+}
+
+// UID is a single user identifier within an EID
+type UID struct {
+	ID    string `json:"id"`
+	AType int    `json:"atype,omitempty"` // Agent type: 1=device, 3=person-based
+}
+
 // Data represents additional data segments
 type Data struct {
 	ID      string      `json:"id,omitempty"`
@@ -370,6 +579,9 @@ type PMP struct {
 	Ext            interface{} `json:"ext,omitempty"`
 }
 
+// DealTypeGuaranteed marks a programmatic guaranteed (PG) deal: fixed price, bypasses the auction
+const DealTypeGuaranteed = "guaranteed"
+
 // Deal represents a private deal
 type Deal struct {
 	ID          string      `json:"id"`
@@ -379,6 +591,11 @@ type Deal struct {
 	WSeat       []string    `json:"wseat,omitempty"`    // Whitelist of buyer seats
 	WADomain    []string    `json:"wadomain,omitempty"` // Whitelist of advertiser domains
 	Ext         interface{} `json:"ext,omitempty"`
+	DealType    string      `json:"dealType,omitempty"` // SSP-side only, e.g. DealTypeGuaranteed; not sent to bidders
+
+	// Guaranteed deal pacing; SSP-side only, not sent to bidders
+	DailyBudget    int64   `json:"dailyBudget,omitempty"`    // Impressions per UTC day; 0 is unpaced
+	DeliveryPacing float64 `json:"deliveryPacing,omitempty"` // Multiplier on the even hourly share of DailyBudget; 0 means 1.0
 }
 
 // Source represents the source of the bid request
@@ -443,6 +660,8 @@ type Bid struct {
 	HRatio         int         `json:"hratio,omitempty"` // Relative height for native
 	Exp            int         `json:"exp,omitempty"`    // Expiry time
 	Ext            interface{} `json:"ext,omitempty"`
+
+	CompanionAds []CompanionAdSpec `json:"-"` // Parsed from ext.companionads
 }
 
 // SupplyStats represents supply-side statistics
@@ -455,9 +674,80 @@ type SupplyStats struct {
 	Revenue     float64 `json:"revenue"`
 	Fills       int64   `json:"fills"`
 	AvgCPM      float64 `json:"avgCpm"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"` // Clicks per impression (0.0-1.0)
 	Date        string  `json:"date"`
 }
 
+// NetworkStats represents network-wide statistics aggregated across all publishers
+type NetworkStats struct {
+	SupplyStats
+	RPM            float64 `json:"rpm"`                      // Revenue per 1000 ad requests
+	FillRate       float64 `json:"fillRate"`                 // Impressions per ad request (0.0-1.0)
+	PublisherCount int64   `json:"publisherCount,omitempty"` // Publishers with ad requests in the bucket
+}
+
+// NetworkAverages represents per-publisher performance averaged across all
+// publishers, for benchmarking. It carries no publisher identifiers.
+type NetworkAverages struct {
+	RPM      float64 `json:"rpm"`      // Revenue per 1000 ad requests
+	FillRate float64 `json:"fillRate"` // Impressions per ad request (0.0-1.0)
+	CTR      float64 `json:"ctr"`      // Clicks per impression (0.0-1.0)
+	AvgCPM   float64 `json:"avgCpm"`
+}
+
+// PlacementPerf represents a placement's performance for publisher dashboards
+type PlacementPerf struct {
+	PlacementID string  `json:"placementId"`
+	Requests    int64   `json:"requests"`
+	Impressions int64   `json:"impressions"`
+	Revenue     float64 `json:"revenue"`
+	RPM         float64 `json:"rpm"`      // Revenue per 1000 bid requests
+	FillRate    float64 `json:"fillRate"` // Impressions per bid request (0.0-1.0)
+}
+
+// PartnerStats represents a demand partner's bidding on one publisher
+type PartnerStats struct {
+	PartnerID   string  `json:"partnerId"`
+	PublisherID string  `json:"publisherId"`
+	Bids        int64   `json:"bids"`
+	Wins        int64   `json:"wins"`
+	WinRate     float64 `json:"winRate"` // Wins per bid (0.0-1.0)
+	AvgBid      float64 `json:"avgBid"`  // Average bid price (CPM)
+	Spend       float64 `json:"spend"`   // Sum of cleared prices of won bids
+}
+
+// PartnerContribution represents a demand partner's share of a publisher's revenue
+type PartnerContribution struct {
+	PartnerID   string  `json:"partnerId"`
+	PartnerName string  `json:"partnerName"`
+	Revenue     float64 `json:"revenue"`
+	Impressions int64   `json:"impressions"` // Won bids
+	SharePct    float64 `json:"sharePct"`    // Percentage of the publisher's revenue (0-100)
+}
+
+// HourlyImpression represents a publisher's impressions and revenue for one UTC hour
+type HourlyImpression struct {
+	Hour        int     `json:"hour"` // 0-23
+	Impressions int64   `json:"impressions"`
+	Revenue     float64 `json:"revenue"`
+}
+
+// HourlyTrafficStats represents ad request volume in one UTC hour of the week
+type HourlyTrafficStats struct {
+	DayOfWeek   int     `json:"dayOfWeek"` // 1=Monday ... 7=Sunday
+	Hour        int     `json:"hour"`      // 0-23
+	AvgRequests float64 `json:"avgRequests"`
+	P95Requests float64 `json:"p95Requests"` // 95th percentile of requests in this hour across the lookback
+}
+
+// DailyImpression represents a publisher's impressions and gross revenue for one UTC day
+type DailyImpression struct {
+	Date        time.Time `json:"date"`
+	Impressions int64     `json:"impressions"`
+	Revenue     float64   `json:"revenue"`
+}
+
 // AdTag represents generated ad tag code
 type AdTag struct {
 	PlacementID string    `json:"placementId"`
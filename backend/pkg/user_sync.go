@@ -0,0 +1,160 @@
+package ssp
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+)
+
+// User sync settings
+const (
+	UserIDCookie       = "adnx_uid"           // First-party cookie holding the SSP user ID
+	UserIDCookieMaxAge = 365 * 24 * time.Hour // Lifetime of the SSP user ID cookie
+	MaxBuyerUIDLength  = 256                  // Longest DSP user ID accepted by user sync
+)
+
+// Buyer UID cache defaults. A sync on another replica shows up here once the
+// cached entry expires.
+const (
+	DefaultBuyerUIDCacheTTL  = 5 * time.Minute
+	DefaultBuyerUIDCacheSize = 100000
+)
+
+// WithBuyerUID returns bidReq with User.BuyerUID set for one partner. Bid
+// requests are shared across partners, so the request and user are copied;
+// bidReq is returned unchanged when buyerUID is empty.
+func WithBuyerUID(bidReq *BidRequest, buyerUID string) *BidRequest {
+	if buyerUID == "" {
+		return bidReq
+	}
+
+	user := User{}
+	if bidReq.User != nil {
+		user = *bidReq.User
+	}
+	user.BuyerUID = buyerUID
+
+	req := *bidReq
+	req.User = &user
+	return &req
+}
+
+// SyncOriginAllowed reports whether a user sync posted from origin may be
+// stored for the partner. Browsers send Origin on every cross-site POST, so
+// requiring a configured origin stops other sites forging syncs for a user.
+func (p *SupplyPartner) SyncOriginAllowed(origin string) bool {
+	origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range p.SyncOrigins {
+		if strings.EqualFold(origin, strings.TrimSuffix(allowed, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// tcfPurposeOneBit is the offset of purpose 1 ("store and/or access
+// information on a device") in the PurposesConsent field of a TCF v2 core string
+const tcfPurposeOneBit = 152
+
+// SyncConsented reports whether a user may be synced given the IAB privacy
+// signals: a US Privacy opt-out blocks sync, and where GDPR applies the TCF v2
+// consent string must grant purpose 1, as sync stores a cookie and a user ID
+func SyncConsented(gdprApplies bool, gdprConsent, usPrivacy string) bool {
+	if len(usPrivacy) == 4 && usPrivacy[0] == '1' && usPrivacy[2] == 'Y' {
+		return false
+	}
+	if !gdprApplies {
+		return true
+	}
+
+	// The core string is the first dot-separated segment
+	core, _, _ := strings.Cut(gdprConsent, ".")
+	bits, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(core, "="))
+	if err != nil || len(bits) <= tcfPurposeOneBit/8 {
+		return false
+	}
+	if version := bits[0] >> 2; version != 2 {
+		return false
+	}
+	return bits[tcfPurposeOneBit/8]&(0x80>>(tcfPurposeOneBit%8)) != 0
+}
+
+// BuyerUIDLoader loads an SSP user's synced partner user IDs keyed by partner ID
+type BuyerUIDLoader func(ctx context.Context, sspUserID string) (map[string]string, error)
+
+// BuyerUIDCache caches synced partner user IDs per SSP user so ad requests
+// do not query Postgres each time. It holds at most size users.
+type BuyerUIDCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	size    int
+	load    BuyerUIDLoader
+	entries map[string]buyerUIDEntry
+}
+
+type buyerUIDEntry struct {
+	buyerUIDs map[string]string
+	expiresAt time.Time
+}
+
+// NewBuyerUIDCache creates a buyer UID cache backed by load
+func NewBuyerUIDCache(ttl time.Duration, size int, load BuyerUIDLoader) *BuyerUIDCache {
+	return &BuyerUIDCache{
+		ttl:     ttl,
+		size:    size,
+		load:    load,
+		entries: make(map[string]buyerUIDEntry),
+	}
+}
+
+// Get returns the buyer UIDs of an SSP user, loading them when missing or expired
+func (c *BuyerUIDCache) Get(ctx context.Context, sspUserID string) (map[string]string, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[sspUserID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.buyerUIDs, nil
+	}
+
+	buyerUIDs, err := c.load(ctx, sspUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= c.size {
+		c.evictExpired()
+	}
+	if len(c.entries) < c.size {
+		c.entries[sspUserID] = buyerUIDEntry{
+			buyerUIDs: buyerUIDs,
+			expiresAt: time.Now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+
+	return buyerUIDs, nil
+}
+
+// Invalidate drops the cached buyer UIDs of an SSP user
+func (c *BuyerUIDCache) Invalidate(sspUserID string) {
+	c.mu.Lock()
+	delete(c.entries, sspUserID)
+	c.mu.Unlock()
+}
+
+// evictExpired drops expired entries. The caller must hold c.mu.
+func (c *BuyerUIDCache) evictExpired() {
+	now := time.Now()
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ssp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWithBuyerUID(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+
+	publisher := &Publisher{ID: "pub-1"}
+	site := &Site{ID: "site-1"}
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+	adReq := &AdRequest{
+		PlacementID: "placement-1",
+		UserID:      "6f1c2b9e-8d4a-4c1e-9f3a-1b2c3d4e5f60",
+		BuyerUIDs:   map[string]string{"dsp-1": "buyer-123"},
+	}
+
+	bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, site, publisher)
+	if err != nil {
+		t.Fatalf("Failed to build bid request: %v", err)
+	}
+	if bidReq.User == nil || bidReq.User.ID != adReq.UserID {
+		t.Fatalf("Expected user.id %s, got %+v", adReq.UserID, bidReq.User)
+	}
+	if bidReq.User.BuyerUID != "" {
+		t.Errorf("Expected no buyeruid on the shared request, got %s", bidReq.User.BuyerUID)
+	}
+
+	synced := WithBuyerUID(bidReq, adReq.BuyerUIDs["dsp-1"])
+	if synced.User.BuyerUID != "buyer-123" || synced.User.ID != adReq.UserID {
+		t.Errorf("Expected buyeruid buyer-123 with user.id kept, got %+v", synced.User)
+	}
+	if bidReq.User.BuyerUID != "" {
+		t.Error("Expected the shared request to be left unchanged")
+	}
+
+	if unsynced := WithBuyerUID(bidReq, adReq.BuyerUIDs["dsp-2"]); unsynced != bidReq {
+		t.Error("Expected the shared request for a partner without a buyer UID")
+	}
+}
+
+// tcfConsent builds a TCF v2 core string granting purpose 1 when consented
+func tcfConsent(consented bool) string {
+	bits := make([]byte, 30)
+	bits[0] = 2 << 2 // Version 2
+	if consented {
+		bits[tcfPurposeOneBit/8] |= 0x80 >> (tcfPurposeOneBit % 8)
+	}
+	return base64.RawURLEncoding.EncodeToString(bits)
+}
+
+func TestBuildBidRequestUserIDPrivacy(t *testing.T) {
+	builder := NewBidRequestBuilder("test-ssp")
+	placement := &Placement{ID: "placement-1", AdType: "banner", Width: 300, Height: 250}
+
+	tests := []struct {
+		name     string
+		coppa    int
+		gdpr     int
+		consent  string
+		wantUser bool
+	}{
+		{"No regulation", 0, 0, "", true},
+		{"COPPA", 1, 0, "", false},
+		{"GDPR without consent", 0, 1, "", false},
+		{"GDPR with consent", 0, 1, tcfConsent(true), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adReq := &AdRequest{
+				PlacementID: "placement-1",
+				UserID:      "6f1c2b9e-8d4a-4c1e-9f3a-1b2c3d4e5f60",
+				UserIDs:     map[string]string{"uid2": "uid2-token"},
+				COPPA:       tt.coppa,
+				GDPRApplies: tt.gdpr,
+				GDPRConsent: tt.consent,
+			}
+
+			bidReq, err := builder.BuildBidRequest(context.Background(), adReq, placement, &Site{ID: "site-1"}, &Publisher{ID: "pub-1"})
+			if err != nil {
+				t.Fatalf("Failed to build bid request: %v", err)
+			}
+			if hasUser := bidReq.User != nil; hasUser != tt.wantUser {
+				t.Errorf("Expected user identifiers sent=%v, got %+v", tt.wantUser, bidReq.User)
+			}
+			if adReq.UserIDsAllowed() != tt.wantUser {
+				t.Errorf("Expected UserIDsAllowed=%v", tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestAdRequestSetPrivacyFrom(t *testing.T) {
+	var inbound BidRequest
+	body := `{"id":"r1","regs":{"coppa":1,"ext":{"gdpr":1,"us_privacy":"1YNN"}},"user":{"ext":{"consent":"` + tcfConsent(true) + `"}}}`
+	if err := json.Unmarshal([]byte(body), &inbound); err != nil {
+		t.Fatalf("Failed to parse bid request: %v", err)
+	}
+
+	adReq := &AdRequest{}
+	adReq.SetPrivacyFrom(&inbound)
+	if adReq.COPPA != 1 || adReq.GDPRApplies != 1 || adReq.USPrivacy != "1YNN" {
+		t.Errorf("Expected COPPA, GDPR and US Privacy from regs, got %+v", adReq)
+	}
+	if adReq.GDPRConsent != tcfConsent(true) {
+		t.Errorf("Expected consent from user.ext.consent, got %q", adReq.GDPRConsent)
+	}
+	if adReq.UserIDsAllowed() {
+		t.Error("Expected user identifiers to be withheld under COPPA")
+	}
+}
+
+func TestSyncConsented(t *testing.T) {
+	tests := []struct {
+		name        string
+		gdprApplies bool
+		gdprConsent string
+		usPrivacy   string
+		expected    bool
+	}{
+		{"no signals", false, "", "", true},
+		{"US Privacy opt-out", false, "", "1YYN", false},
+		{"US Privacy no opt-out", false, "", "1YNN", true},
+		{"GDPR without consent string", true, "", "", false},
+		{"GDPR with purpose 1", true, tcfConsent(true) + ".segment", "", true},
+		{"GDPR without purpose 1", true, tcfConsent(false), "", false},
+		{"GDPR with malformed consent", true, "not-a-tc-string!", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := SyncConsented(tt.gdprApplies, tt.gdprConsent, tt.usPrivacy); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestBuyerUIDCache(t *testing.T) {
+	loads := 0
+	cache := NewBuyerUIDCache(time.Minute, 1, func(ctx context.Context, sspUserID string) (map[string]string, error) {
+		loads++
+		return map[string]string{"dsp-1": "buyer-" + sspUserID}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if uids, err := cache.Get(context.Background(), "user-1"); err != nil || uids["dsp-1"] != "buyer-user-1" {
+			t.Fatalf("Unexpected buyer UIDs %v, %v", uids, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load for a cached user, got %d", loads)
+	}
+
+	cache.Invalidate("user-1")
+	cache.Get(context.Background(), "user-1")
+	if loads != 2 {
+		t.Errorf("Expected a reload after invalidation, got %d loads", loads)
+	}
+
+	// Full: user-2 is loaded but not cached
+	cache.Get(context.Background(), "user-2")
+	cache.Get(context.Background(), "user-2")
+	if loads != 4 || len(cache.entries) != 1 {
+		t.Errorf("Expected the cache to stay at its size, got %d entries after %d loads", len(cache.entries), loads)
+	}
+}
+
+func TestSupplyPartnerSyncOriginAllowed(t *testing.T) {
+	partner := &SupplyPartner{ID: "dsp-1", SyncOrigins: []string{"https://sync.dsp.example/"}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://sync.dsp.example", true},
+		{"HTTPS://SYNC.DSP.EXAMPLE", true},
+		{"https://evil.example", false},
+		{"http://sync.dsp.example", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := partner.SyncOriginAllowed(tt.origin); got != tt.want {
+			t.Errorf("SyncOriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if (&SupplyPartner{ID: "dsp-2"}).SyncOriginAllowed("https://sync.dsp.example") {
+		t.Error("Expected a partner without sync origins to reject every origin")
+	}
+}